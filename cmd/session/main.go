@@ -25,12 +25,18 @@ func main() {
 	dbmanEndpoints := cmnenv.CSV("DBMAN_ENDPOINTS", []string{cmnenv.String("DBMAN_ENDPOINT", "http://localhost:8082")})
 
 	sessionServer, err := sessionapp.NewServer(sessionapp.Config{
-		Port:           port,
-		RedisAddr:      cmnenv.String("REDIS_ADDR", "localhost:6379"),
-		JWTSecret:      cmnenv.String("JWT_SECRET", "change-me-in-production"),
-		JWTTTLMinutes:  cmnenv.Int("JWT_TTL_MINUTES", 1440),
-		DBManEndpoint:  dbmanEndpoints[0],
-		DBManEndpoints: dbmanEndpoints,
+		Port:             port,
+		RedisAddr:        cmnenv.String("REDIS_ADDR", "localhost:6379"),
+		JWTSecret:        cmnenv.String("JWT_SECRET", "change-me-in-production"),
+		JWTTTLMinutes:    cmnenv.Int("JWT_TTL_MINUTES", 1440),
+		DBManEndpoint:    dbmanEndpoints[0],
+		DBManEndpoints:   dbmanEndpoints,
+		WSQueueSize:      cmnenv.Int("SESSION_WS_QUEUE_SIZE", 256),
+		WSOverflowPolicy: cmnenv.String("SESSION_WS_OVERFLOW_POLICY", "disconnect"),
+		WSPingInterval:   time.Duration(cmnenv.Int("SESSION_WS_PING_INTERVAL_SEC", 25)) * time.Second,
+		WSPongWait:       time.Duration(cmnenv.Int("SESSION_WS_PONG_WAIT_SEC", 60)) * time.Second,
+		WSDrainWindow:    time.Duration(cmnenv.Int("SESSION_WS_DRAIN_WINDOW_MS", 5000)) * time.Millisecond,
+		WSReconnectAfter: time.Duration(cmnenv.Int("SESSION_WS_RECONNECT_AFTER_MS", 2000)) * time.Millisecond,
 	})
 	if err != nil {
 		log.Fatalf("initialize session server: %v", err)
@@ -0,0 +1,17 @@
+ALTER TABLE messages
+  ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS deleted_by TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS message_revisions (
+  revision_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  message_id TEXT NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
+  room_id TEXT NOT NULL,
+  editor_id TEXT NOT NULL,
+  body TEXT NOT NULL,
+  meta_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_revisions_message ON message_revisions(tenant_id, message_id, created_at DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE messages
+  ADD COLUMN IF NOT EXISTS parent_message_id TEXT REFERENCES messages(message_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_thread ON messages(tenant_id, parent_message_id, created_at, message_id)
+  WHERE parent_message_id IS NOT NULL;
@@ -0,0 +1,39 @@
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS last_message_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS room_seq BIGINT;
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS last_read_seq BIGINT NOT NULL DEFAULT 0;
+
+-- Backfill: number existing messages per room in send order.
+WITH ordered AS (
+  SELECT message_id,
+         ROW_NUMBER() OVER (PARTITION BY tenant_id, room_id ORDER BY created_at, message_id) AS seq
+  FROM messages
+)
+UPDATE messages m
+SET room_seq = o.seq
+FROM ordered o
+WHERE m.message_id = o.message_id
+  AND m.room_seq IS NULL;
+
+UPDATE chat_rooms cr
+SET last_message_seq = GREATEST(cr.last_message_seq, COALESCE((
+  SELECT MAX(m.room_seq)
+  FROM messages m
+  WHERE m.tenant_id = cr.tenant_id AND m.room_id = cr.chat_room_id
+), 0));
+
+UPDATE room_members rm
+SET last_read_seq = COALESCE((
+  SELECT MAX(m.room_seq)
+  FROM message_reads mr
+  JOIN messages m ON m.message_id = mr.message_id
+  WHERE mr.tenant_id = rm.tenant_id AND mr.room_id = rm.room_id AND mr.user_id = rm.user_id
+), 0)
+WHERE rm.last_read_seq = 0;
+
+ALTER TABLE messages ALTER COLUMN room_seq SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_room_seq ON messages(tenant_id, room_id, room_seq);
+
+DROP INDEX IF EXISTS idx_messages_thread;
+CREATE INDEX IF NOT EXISTS idx_messages_thread_seq ON messages(tenant_id, parent_message_id, room_seq)
+  WHERE parent_message_id IS NOT NULL;
@@ -0,0 +1,69 @@
+-- Append-only change feed for delta sync (GET /api/v1/sync).
+-- Rows are written by triggers so every write path is covered.
+CREATE TABLE IF NOT EXISTS sync_changes (
+  change_id BIGSERIAL PRIMARY KEY,
+  tenant_id TEXT NOT NULL,
+  room_id TEXT NOT NULL,
+  user_id TEXT,
+  kind TEXT NOT NULL,
+  entity_id TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_tenant_change ON sync_changes(tenant_id, change_id);
+CREATE INDEX IF NOT EXISTS idx_sync_changes_tenant_user_change ON sync_changes(tenant_id, user_id, change_id) WHERE user_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION sync_record_message_change() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO sync_changes(tenant_id, room_id, kind, entity_id) VALUES (NEW.tenant_id, NEW.room_id, 'message.created', NEW.message_id);
+  ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+    INSERT INTO sync_changes(tenant_id, room_id, kind, entity_id) VALUES (NEW.tenant_id, NEW.room_id, 'message.deleted', NEW.message_id);
+  ELSIF NEW.body IS DISTINCT FROM OLD.body OR NEW.edited_at IS DISTINCT FROM OLD.edited_at OR NEW.meta_json IS DISTINCT FROM OLD.meta_json THEN
+    INSERT INTO sync_changes(tenant_id, room_id, kind, entity_id) VALUES (NEW.tenant_id, NEW.room_id, 'message.updated', NEW.message_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sync_record_reaction_change() RETURNS TRIGGER AS $$
+DECLARE
+  r message_reactions%ROWTYPE;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+  INSERT INTO sync_changes(tenant_id, room_id, kind, entity_id) VALUES (r.tenant_id, r.room_id, 'message.reacted', r.message_id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sync_record_member_change() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO sync_changes(tenant_id, room_id, user_id, kind) VALUES (NEW.tenant_id, NEW.room_id, NEW.user_id, 'member.joined');
+  ELSIF TG_OP = 'DELETE' THEN
+    INSERT INTO sync_changes(tenant_id, room_id, user_id, kind) VALUES (OLD.tenant_id, OLD.room_id, OLD.user_id, 'member.left');
+  ELSIF NEW.last_read_seq IS DISTINCT FROM OLD.last_read_seq THEN
+    INSERT INTO sync_changes(tenant_id, room_id, user_id, kind) VALUES (NEW.tenant_id, NEW.room_id, NEW.user_id, 'read.updated');
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_sync_messages ON messages;
+CREATE TRIGGER trg_sync_messages
+  AFTER INSERT OR UPDATE ON messages
+  FOR EACH ROW EXECUTE FUNCTION sync_record_message_change();
+
+DROP TRIGGER IF EXISTS trg_sync_message_reactions ON message_reactions;
+CREATE TRIGGER trg_sync_message_reactions
+  AFTER INSERT OR DELETE ON message_reactions
+  FOR EACH ROW EXECUTE FUNCTION sync_record_reaction_change();
+
+DROP TRIGGER IF EXISTS trg_sync_room_members ON room_members;
+CREATE TRIGGER trg_sync_room_members
+  AFTER INSERT OR UPDATE OR DELETE ON room_members
+  FOR EACH ROW EXECUTE FUNCTION sync_record_member_change();
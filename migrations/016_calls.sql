@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS calls (
+  call_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  room_id TEXT NOT NULL REFERENCES chat_rooms(chat_room_id) ON DELETE CASCADE,
+  caller_id TEXT NOT NULL,
+  media TEXT NOT NULL DEFAULT 'audio',
+  status TEXT NOT NULL DEFAULT 'ringing',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMPTZ,
+  ended_at TIMESTAMPTZ,
+  duration_sec BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_calls_room_created ON calls(tenant_id, room_id, created_at DESC, call_id DESC);
+
+-- At most one ringing or active call per room.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calls_room_live ON calls(tenant_id, room_id) WHERE status IN ('ringing', 'active');
+
+CREATE TABLE IF NOT EXISTS call_participants (
+  call_id TEXT NOT NULL REFERENCES calls(call_id) ON DELETE CASCADE,
+  tenant_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  state TEXT NOT NULL DEFAULT 'invited',
+  invited_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  joined_at TIMESTAMPTZ,
+  left_at TIMESTAMPTZ,
+  PRIMARY KEY (call_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_call_participants_user ON call_participants(tenant_id, user_id, invited_at DESC);
@@ -0,0 +1,17 @@
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS stun_urls TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS turn_urls TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS turn_shared_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS turn_credential_ttl_sec INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS turn_credential_audit (
+  id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  user_id TEXT NOT NULL,
+  username TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_turn_credential_audit_user ON turn_credential_audit(tenant_id, user_id, created_at DESC);
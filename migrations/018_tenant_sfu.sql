@@ -0,0 +1,3 @@
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS sfu_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS sfu_api_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS sfu_api_secret TEXT NOT NULL DEFAULT '';
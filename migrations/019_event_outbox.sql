@@ -0,0 +1,16 @@
+-- Events written in the same transaction as the change they describe. The
+-- dbman relay publishes pending rows to chat.events and marks them delivered.
+CREATE TABLE IF NOT EXISTS event_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  event_type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox(tenant_id, next_attempt_at, id) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_delivered ON event_outbox(delivered_at) WHERE delivered_at IS NOT NULL;
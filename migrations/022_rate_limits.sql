@@ -0,0 +1,15 @@
+-- Tenant-configured rate limits. subject_id is a user or room id; '' is the
+-- default for every subject in the scope (always '' for scope 'tenant').
+CREATE TABLE IF NOT EXISTS rate_limit_policies (
+  policy_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  scope TEXT NOT NULL CHECK (scope IN ('tenant', 'room', 'user')),
+  subject_id TEXT NOT NULL DEFAULT '',
+  action TEXT NOT NULL CHECK (action IN ('message', 'api')),
+  per_minute INT NOT NULL CHECK (per_minute >= 0),
+  burst INT NOT NULL DEFAULT 0 CHECK (burst >= 0),
+  updated_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (tenant_id, scope, subject_id, action)
+);
@@ -0,0 +1,38 @@
+-- Tenant-configured moderation rules run over message and note bodies before
+-- they are stored. words/pattern apply to the words and regex kinds,
+-- max_links to max_links.
+CREATE TABLE IF NOT EXISTS moderation_rules (
+  rule_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  name TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('words', 'regex', 'national_id', 'card_number', 'max_links')),
+  words TEXT[] NOT NULL DEFAULT '{}',
+  pattern TEXT NOT NULL DEFAULT '',
+  max_links INT NOT NULL DEFAULT 0 CHECK (max_links >= 0),
+  action TEXT NOT NULL CHECK (action IN ('block', 'mask', 'flag')),
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  updated_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_rules_tenant ON moderation_rules(tenant_id, created_at);
+
+-- Review queue for content matched by flag rules. subject_id is the message
+-- or note id; body is the content as stored (after masking).
+CREATE TABLE IF NOT EXISTS moderation_flags (
+  flag_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  source TEXT NOT NULL CHECK (source IN ('message', 'note')),
+  room_id TEXT NOT NULL DEFAULT '',
+  subject_id TEXT NOT NULL,
+  sender_id TEXT NOT NULL,
+  body TEXT NOT NULL DEFAULT '',
+  rules TEXT[] NOT NULL DEFAULT '{}',
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'removed')),
+  reviewed_by TEXT NOT NULL DEFAULT '',
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_flags_queue ON moderation_flags(tenant_id, status, created_at DESC);
@@ -0,0 +1,37 @@
+-- Users banned from a room cannot be added back until unbanned.
+CREATE TABLE IF NOT EXISTS room_bans (
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  room_id TEXT NOT NULL REFERENCES chat_rooms(chat_room_id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  reason TEXT NOT NULL DEFAULT '',
+  banned_by TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tenant_id, room_id, user_id)
+);
+
+-- Member reports share the review queue with rule flags; reporter_id is set
+-- for reports and '' for rule flags.
+ALTER TABLE moderation_flags ADD COLUMN IF NOT EXISTS reporter_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE moderation_flags ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_moderation_flags_pending_report
+  ON moderation_flags(tenant_id, subject_id, reporter_id)
+  WHERE reporter_id <> '' AND status = 'pending';
+
+-- Every admin moderation action and member report, written in the same
+-- transaction as the action itself.
+CREATE TABLE IF NOT EXISTS moderation_audit (
+  audit_id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  room_id TEXT NOT NULL DEFAULT '',
+  actor_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  target_user_id TEXT NOT NULL DEFAULT '',
+  message_id TEXT NOT NULL DEFAULT '',
+  flag_id TEXT NOT NULL DEFAULT '',
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_audit_tenant ON moderation_audit(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_moderation_audit_room ON moderation_audit(tenant_id, room_id, created_at DESC);
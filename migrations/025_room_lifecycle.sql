@@ -0,0 +1,2 @@
+-- Archived rooms stay readable but reject new messages.
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
@@ -0,0 +1,25 @@
+-- Per-room roles. Existing rooms get their creator as owner; a room has at
+-- most one owner, changed only by transferring ownership.
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member';
+
+DO $$ BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_room_members_role') THEN
+    ALTER TABLE room_members ADD CONSTRAINT chk_room_members_role CHECK (role IN ('owner', 'admin', 'member', 'read_only'));
+  END IF;
+END $$;
+
+-- Migrations re-run on every deploy: only rooms that have no owner yet are
+-- backfilled, so a creator who later handed ownership over stays demoted.
+UPDATE room_members rm
+SET role = 'owner'
+FROM chat_rooms cr
+WHERE cr.tenant_id = rm.tenant_id
+  AND cr.chat_room_id = rm.room_id
+  AND cr.created_by = rm.user_id
+  AND rm.role = 'member'
+  AND NOT EXISTS (
+    SELECT 1 FROM room_members o
+    WHERE o.tenant_id = rm.tenant_id AND o.room_id = rm.room_id AND o.role = 'owner'
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_room_members_owner ON room_members(tenant_id, room_id) WHERE role = 'owner';
@@ -0,0 +1,11 @@
+-- Order the change feed by writing transaction so a sync cursor only moves
+-- past transactions that have finished. Rows written before this migration
+-- keep tx_id 0 and sort ahead of everything new.
+DO $$ BEGIN
+  IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'sync_changes' AND column_name = 'tx_id') THEN
+    ALTER TABLE sync_changes ADD COLUMN tx_id xid8 NOT NULL DEFAULT '0';
+    ALTER TABLE sync_changes ALTER COLUMN tx_id SET DEFAULT pg_current_xact_id();
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_tenant_tx ON sync_changes(tenant_id, tx_id, change_id);
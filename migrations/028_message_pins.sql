@@ -0,0 +1,12 @@
+-- Messages pinned to the top of their room, gated by the room's pin
+-- permission. A pin goes away with its message.
+CREATE TABLE IF NOT EXISTS message_pins (
+  tenant_id TEXT NOT NULL REFERENCES tenants(tenant_id) ON DELETE RESTRICT,
+  message_id TEXT NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
+  room_id TEXT NOT NULL,
+  pinned_by TEXT NOT NULL,
+  pinned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_pins_room ON message_pins(tenant_id, room_id, pinned_at DESC);
@@ -0,0 +1,5 @@
+-- Deleting a message now drops its edit history in the same transaction;
+-- clear the history left behind by messages deleted before that.
+DELETE FROM message_revisions r
+USING messages m
+WHERE m.message_id = r.message_id AND m.deleted_at IS NOT NULL;
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/service"
+)
+
+func callErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCallMedia):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCallNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCallInProgress), errors.Is(err, service.ErrCallInvalidState):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrNotCallParticipant):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrICEServersNotConfigured), errors.Is(err, service.ErrSFUNotConfigured):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) startCall(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Media string `json:"media"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+			return
+		}
+	}
+	call, err := h.calls.StartCall(c.Request.Context(), tenantID, c.Param("id"), userID, req.Media)
+	if err != nil {
+		c.JSON(callErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, call)
+}
+
+func (h *Handler) answerCall(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Accept *bool `json:"accept"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+			return
+		}
+	}
+	accept := req.Accept == nil || *req.Accept
+	call, err := h.calls.AnswerCall(c.Request.Context(), tenantID, c.Param("id"), c.Param("callId"), userID, accept)
+	if err != nil {
+		c.JSON(callErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, call)
+}
+
+func (h *Handler) hangupCall(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	call, err := h.calls.HangupCall(c.Request.Context(), tenantID, c.Param("id"), c.Param("callId"), userID)
+	if err != nil {
+		c.JSON(callErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, call)
+}
+
+func (h *Handler) getCall(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	call, err := h.calls.GetCall(c.Request.Context(), tenantID, c.Param("id"), c.Param("callId"))
+	if err != nil {
+		c.JSON(callErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, call)
+}
+
+func (h *Handler) listCalls(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "30"))
+	items, nextCursor, err := h.calls.ListCalls(c.Request.Context(), tenantID, c.Param("id"), limit, c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewPaginatedResponse(items, nextCursor))
+}
+
+func (h *Handler) issueICEServers(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	out, err := h.calls.IssueICEServers(c.Request.Context(), tenantID, userID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(callErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) issueSFUToken(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	out, err := h.calls.IssueSFUToken(c.Request.Context(), tenantID, c.Param("id"), c.Param("callId"), userID)
+	if err != nil {
+		c.JSON(callErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, out)
+}
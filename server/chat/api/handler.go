@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -14,38 +15,135 @@ import (
 	commonauth "msg_server/server/common/auth"
 	commonlog "msg_server/server/common/log"
 	"msg_server/server/common/middleware"
+	"msg_server/server/common/moderation"
 )
 
 type Handler struct {
-	chat *service.ChatService
-	ws   *service.RealtimeService
-	auth *commonauth.Service
+	chat       *service.ChatService
+	calls      *service.CallService
+	webhooks   *service.WebhookService
+	incoming   *service.IncomingWebhookService
+	limits     *service.RateLimitService
+	moderation *service.ModerationService
+	ws         *service.RealtimeService
+	auth       *commonauth.Service
 }
 
-func NewHandler(chat *service.ChatService, ws *service.RealtimeService, jwtSecret string, jwtTTLMinutes int) *Handler {
+func NewHandler(chat *service.ChatService, calls *service.CallService, webhooks *service.WebhookService, incoming *service.IncomingWebhookService, limits *service.RateLimitService, moderation *service.ModerationService, ws *service.RealtimeService, jwtSecret string, jwtTTLMinutes int) *Handler {
 	auth := commonauth.NewService(jwtSecret, jwtTTLMinutes)
-	return &Handler{chat: chat, ws: ws, auth: auth}
+	return &Handler{chat: chat, calls: calls, webhooks: webhooks, incoming: incoming, limits: limits, moderation: moderation, ws: ws, auth: auth}
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
-	r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, NewHealthResponse("ok")) })
+	r.GET("/health", func(c *gin.Context) {
+		if h.ws.Draining() {
+			c.JSON(http.StatusServiceUnavailable, NewHealthResponse("draining"))
+			return
+		}
+		c.JSON(http.StatusOK, NewHealthResponse("ok"))
+	})
+	// Readiness also fails while the shared LavinMQ connection is down, so the
+	// pod leaves rotation until the publisher reconnects.
+	r.GET("/health/ready", func(c *gin.Context) {
+		if h.ws.Draining() {
+			c.JSON(http.StatusServiceUnavailable, NewReadinessResponse("draining", nil))
+			return
+		}
+		mqHealth, ok := h.chat.MQHealth()
+		if !ok {
+			c.JSON(http.StatusOK, NewReadinessResponse("ready", nil))
+			return
+		}
+		if !mqHealth.Ready {
+			c.JSON(http.StatusServiceUnavailable, NewReadinessResponse("not_ready", &mqHealth))
+			return
+		}
+		c.JSON(http.StatusOK, NewReadinessResponse("ready", &mqHealth))
+	})
 	r.GET("/ws", h.handleWS)
+	r.GET("/metrics/ws", func(c *gin.Context) { c.JSON(http.StatusOK, h.ws.Metrics()) })
+	r.POST("/hooks/incoming/:tenantId/:hookId/:token", h.postIncomingWebhook)
 
 	api := r.Group("/api/v1")
-	api.Use(middleware.AuthRequired(h.auth))
+	api.Use(middleware.AuthRequired(h.auth), h.apiRateLimit())
 	{
 		api.POST("/rooms", h.createRoom)
 		api.GET("/rooms", h.listMyRooms)
-		api.POST("/rooms/:id/members", h.addMember)
-		api.POST("/rooms/:id/messages", h.createMessage)
-		api.GET("/rooms/:id/messages", h.listMessages)
-		api.GET("/rooms/:id/unread-count", h.getRoomUnreadCount)
 		api.GET("/rooms/unread-counts", h.getMyUnreadCounts)
-		api.POST("/rooms/:id/read", h.markRoomRead)
-		api.GET("/rooms/:id/read", h.getMyReadState)
-		api.GET("/rooms/:id/messages/:messageId/readers", h.getMessageReaders)
 		api.GET("/messages/search", h.searchMessages)
+		api.GET("/sync", h.sync)
+		api.POST("/calls/ice-servers", h.issueICEServers)
+
+		room := api.Group("/rooms/:id")
+		room.Use(h.requireRoomMember())
+		room.GET("", h.getRoom)
+		room.PATCH("", h.requireRoomPermission(domain.RoomPermRename), h.renameRoom)
+		room.DELETE("", h.requireRoomPermission(domain.RoomPermManage), h.deleteRoom)
+		room.POST("/archive", h.requireRoomPermission(domain.RoomPermManage), h.archiveRoom)
+		room.POST("/unarchive", h.requireRoomPermission(domain.RoomPermManage), h.unarchiveRoom)
+		room.POST("/transfer-ownership", h.transferRoomOwnership)
+		room.POST("/leave", h.leaveRoom)
+		room.POST("/members", h.requireRoomPermission(domain.RoomPermInvite), h.addMember)
+		room.PUT("/members/:userId/role", h.requireRoomPermission(domain.RoomPermManage), h.setMemberRole)
+		room.DELETE("/members/:userId", h.requireRoomPermission(domain.RoomPermRemove), h.removeMember)
+		room.POST("/messages", h.requireRoomPermission(domain.RoomPermPost), h.messageRateLimit(), h.createMessage)
+		room.GET("/messages", h.listMessages)
+		room.GET("/unread-count", h.getRoomUnreadCount)
+		room.POST("/read", h.markRoomRead)
+		room.GET("/read", h.getMyReadState)
+		room.PATCH("/messages/:messageId", h.requireRoomPermission(domain.RoomPermPost), h.updateMessage)
+		room.DELETE("/messages/:messageId", h.deleteMessage)
+		room.GET("/messages/:messageId/revisions", h.listMessageRevisions)
+		room.GET("/messages/:messageId/thread", h.listThread)
+		room.POST("/messages/:messageId/reactions", h.requireRoomPermission(domain.RoomPermReact), h.addReaction)
+		room.DELETE("/messages/:messageId/reactions/:emoji", h.requireRoomPermission(domain.RoomPermReact), h.removeReaction)
+		room.GET("/messages/:messageId/readers", h.getMessageReaders)
+		room.GET("/pins", h.listPins)
+		room.POST("/messages/:messageId/pin", h.requireRoomPermission(domain.RoomPermPin), h.pinMessage)
+		room.DELETE("/messages/:messageId/pin", h.requireRoomPermission(domain.RoomPermPin), h.unpinMessage)
+		room.POST("/messages/:messageId/report", h.reportMessage)
+		// An unanswered call posts a missed-call message as the caller.
+		room.POST("/calls", h.requireRoomPermission(domain.RoomPermPost), h.startCall)
+		room.GET("/calls", h.listCalls)
+		room.GET("/calls/:callId", h.getCall)
+		room.POST("/calls/:callId/answer", h.answerCall)
+		room.POST("/calls/:callId/hangup", h.hangupCall)
+		room.POST("/calls/:callId/sfu-token", h.issueSFUToken)
+		room.POST("/incoming-webhooks", h.requireRoomAdmin(), h.createIncomingWebhook)
+		room.GET("/incoming-webhooks", h.requireRoomAdmin(), h.listIncomingWebhooks)
+		room.DELETE("/incoming-webhooks/:hookId", h.requireRoomAdmin(), h.revokeIncomingWebhook)
 
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(middleware.RequireRoles(string(domain.UserRoleAdmin)))
+		webhooks.POST("", h.createWebhook)
+		webhooks.GET("", h.listWebhooks)
+		webhooks.GET("/:id", h.getWebhook)
+		webhooks.PATCH("/:id", h.updateWebhook)
+		webhooks.DELETE("/:id", h.deleteWebhook)
+		webhooks.POST("/:id/rotate-secret", h.rotateWebhookSecret)
+		webhooks.GET("/:id/deliveries", h.listWebhookDeliveries)
+
+		rateLimits := api.Group("/rate-limits")
+		rateLimits.Use(middleware.RequireRoles(string(domain.UserRoleAdmin)))
+		rateLimits.GET("", h.listRateLimits)
+		rateLimits.PUT("", h.upsertRateLimit)
+		rateLimits.DELETE("/:id", h.deleteRateLimit)
+
+		mod := api.Group("/moderation")
+		mod.Use(middleware.RequireRoles(string(domain.UserRoleAdmin)))
+		mod.GET("/rules", h.listModerationRules)
+		mod.POST("/rules", h.createModerationRule)
+		mod.PUT("/rules/:ruleId", h.updateModerationRule)
+		mod.DELETE("/rules/:ruleId", h.deleteModerationRule)
+		mod.GET("/flags", h.listModerationFlags)
+		mod.POST("/flags/:flagId/approve", h.approveModerationFlag)
+		mod.POST("/flags/:flagId/remove", h.removeModerationFlag)
+		mod.POST("/rooms/:roomId/messages/:messageId/delete", h.moderatorDeleteMessage)
+		mod.POST("/rooms/:roomId/members/:userId/remove", h.moderatorRemoveMember)
+		mod.POST("/rooms/:roomId/members/:userId/ban", h.moderatorBanMember)
+		mod.GET("/rooms/:roomId/bans", h.listRoomBans)
+		mod.DELETE("/rooms/:roomId/bans/:userId", h.moderatorUnbanMember)
+		mod.GET("/audit", h.listModerationAudit)
 	}
 }
 
@@ -55,28 +153,22 @@ func (h *Handler) handleWS(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, NewErrorResponse("bearer token is required"))
 		return
 	}
-	userID, tenantID, _, err := h.auth.ParseAuthContext(token)
+	userID, tenantID, role, err := h.auth.ParseAuthContext(token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, NewErrorResponse("invalid token"))
 		return
 	}
-	roomID := strings.TrimSpace(c.Query("room_id"))
-	if roomID == "" {
-		c.JSON(http.StatusBadRequest, NewErrorResponse("room_id required"))
-		return
-	}
-	isMember, err := h.chat.IsRoomMember(c.Request.Context(), tenantID, roomID, userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
-		return
-	}
-	if !isMember {
-		c.JSON(http.StatusForbidden, NewErrorResponse("room access denied"))
-		return
+	// Without room_id the connection is multiplexed and membership is checked
+	// on every subscribe command instead.
+	if roomID := strings.TrimSpace(c.Query("room_id")); roomID != "" {
+		if _, ok := h.authorizeRoom(c, "ws", tenantID, roomID, userID); !ok {
+			return
+		}
 	}
 	c.Set("auth_access_token", token)
 	c.Set("auth_user_id", userID)
 	c.Set("auth_tenant_id", tenantID)
+	c.Set("auth_role", role)
 	h.ws.HandleWS(c)
 }
 
@@ -150,6 +242,30 @@ func (h *Handler) listMyRooms(c *gin.Context) {
 	c.JSON(http.StatusOK, NewPaginatedResponse(items, nextCursor))
 }
 
+func (h *Handler) sync(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	batch, nextToken, err := h.chat.Sync(c.Request.Context(), tenantID, actorID, c.Query("since"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSyncToken) {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewSyncResponse(batch, nextToken))
+}
+
 func (h *Handler) addMember(c *gin.Context) {
 	tenantID, err := tenantFromContext(c)
 	if err != nil {
@@ -158,16 +274,22 @@ func (h *Handler) addMember(c *gin.Context) {
 	}
 	roomID := c.Param("id")
 	var req struct {
-		UserID string `json:"user_id" binding:"required"`
+		UserID string          `json:"user_id" binding:"required"`
+		Role   domain.RoomRole `json:"role"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
 		return
 	}
-	if err := h.chat.AddMember(c.Request.Context(), tenantID, roomID, req.UserID); err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
+	added, err := h.chat.AddMember(c.Request.Context(), tenantID, roomID, roomRole(c), req.UserID, req.Role)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
 		return
 	}
+	if added {
+		actorID, _, _ := actorFromContext(c)
+		_ = h.ws.PublishMembership(c.Request.Context(), tenantID, roomID, actorID, req.UserID, domain.MembershipJoined)
+	}
 	c.JSON(http.StatusOK, NewOKResponse())
 }
 
@@ -184,10 +306,11 @@ func (h *Handler) createMessage(c *gin.Context) {
 		return
 	}
 	var req struct {
-		Body    string   `json:"body" binding:"required"`
-		FileID  *string  `json:"file_id"`
-		FileIDs []string `json:"file_ids"`
-		Emojis  []string `json:"emojis"`
+		Body            string   `json:"body" binding:"required"`
+		FileID          *string  `json:"file_id"`
+		FileIDs         []string `json:"file_ids"`
+		Emojis          []string `json:"emojis"`
+		ParentMessageID *string  `json:"parent_message_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
@@ -195,15 +318,16 @@ func (h *Handler) createMessage(c *gin.Context) {
 	}
 	start := time.Now()
 	msg, err := h.chat.CreateMessage(c.Request.Context(), domain.Message{
-		TenantID: tenantID,
-		RoomID:   roomID,
-		SenderID: actorID,
-		Body:     req.Body,
-		MetaJSON: service.BuildMessageMeta(req.FileID, req.FileIDs, req.Emojis),
+		TenantID:        tenantID,
+		RoomID:          roomID,
+		SenderID:        actorID,
+		Body:            req.Body,
+		MetaJSON:        service.BuildMessageMeta(req.FileID, req.FileIDs, req.Emojis),
+		ParentMessageID: req.ParentMessageID,
 	})
 	if err != nil {
 		commonlog.Errorf("event=chat_message_persist action=create status=failed source=rest tenant_id=%s room_id=%s user_id=%s latency_ms=%d error=%v", tenantID, roomID, actorID, time.Since(start).Milliseconds(), err)
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
 		return
 	}
 	commonlog.Infof("event=chat_message_persist action=create status=ok source=rest tenant_id=%s room_id=%s user_id=%s message_id=%s latency_ms=%d", tenantID, roomID, actorID, msg.ID, time.Since(start).Milliseconds())
@@ -216,6 +340,166 @@ func (h *Handler) createMessage(c *gin.Context) {
 	c.JSON(http.StatusCreated, msg)
 }
 
+func (h *Handler) updateMessage(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	msg, err := h.chat.UpdateMessage(c.Request.Context(), tenantID, roomID, messageID, actorID, req.Body)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=chat_message_persist action=update status=ok source=rest tenant_id=%s room_id=%s user_id=%s message_id=%s", tenantID, roomID, actorID, msg.ID)
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, roomID, actorID, "message.updated", msg)
+	c.JSON(http.StatusOK, msg)
+}
+
+func (h *Handler) deleteMessage(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	msg, err := h.chat.DeleteMessage(c.Request.Context(), tenantID, roomID, messageID, actorID, roomRole(c))
+	if err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=chat_message_persist action=delete status=ok source=rest tenant_id=%s room_id=%s user_id=%s message_id=%s", tenantID, roomID, actorID, msg.ID)
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, roomID, actorID, "message.deleted", msg)
+	c.JSON(http.StatusOK, msg)
+}
+
+func (h *Handler) listMessageRevisions(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	items, err := h.chat.ListMessageRevisions(c.Request.Context(), tenantID, roomID, messageID)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) listThread(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	cursor := c.Query("cursor")
+	userID, _, _ := actorFromContext(c)
+	items, nextCursor, err := h.chat.ListThread(c.Request.Context(), tenantID, roomID, messageID, userID, limit, cursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewPaginatedResponse(items, nextCursor))
+}
+
+func (h *Handler) addReaction(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Emoji string `json:"emoji" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	item, changed, err := h.chat.AddReaction(c.Request.Context(), tenantID, roomID, messageID, userID, req.Emoji)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	if changed {
+		_ = h.ws.PublishEvent(c.Request.Context(), tenantID, roomID, userID, "reaction.added", item)
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) removeReaction(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	item, changed, err := h.chat.RemoveReaction(c.Request.Context(), tenantID, roomID, messageID, userID, c.Param("emoji"))
+	if err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	if changed {
+		_ = h.ws.PublishEvent(c.Request.Context(), tenantID, roomID, userID, "reaction.removed", item)
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func messageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrMessageBodyRequired), errors.Is(err, service.ErrInvalidThreadParent), errors.Is(err, service.ErrInvalidReaction):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrMessageNotFound), errors.Is(err, service.ErrMessageNotPinned):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrMessageForbidden), errors.Is(err, service.ErrRoomPermission):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrRoomArchived):
+		return http.StatusConflict
+	case errors.As(err, new(*moderation.BlockedError)):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *Handler) listMessages(c *gin.Context) {
 	tenantID, err := tenantFromContext(c)
 	if err != nil {
@@ -225,7 +509,8 @@ func (h *Handler) listMessages(c *gin.Context) {
 	roomID := c.Param("id")
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	cursor := c.Query("cursor")
-	items, nextCursor, err := h.chat.ListMessages(c.Request.Context(), tenantID, roomID, limit, cursor)
+	userID, _, _ := actorFromContext(c)
+	items, nextCursor, err := h.chat.ListMessages(c.Request.Context(), tenantID, roomID, userID, limit, cursor)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
 		return
@@ -292,7 +577,7 @@ func (h *Handler) markRoomRead(c *gin.Context) {
 		return
 	}
 	if err := h.chat.MarkReadUpTo(c.Request.Context(), tenantID, roomID, actorID, req.MessageID); err != nil {
-		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
 		return
 	}
 	c.JSON(http.StatusOK, NewOKResponse())
@@ -340,15 +625,23 @@ func (h *Handler) searchMessages(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
 		return
 	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
 	q := c.Query("q")
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "30"))
 	cursor := c.Query("cursor")
 	var roomID *string
-	if raw := c.Query("room_id"); raw != "" {
+	if raw := strings.TrimSpace(c.Query("room_id")); raw != "" {
+		if _, ok := h.authorizeRoom(c, "rest", tenantID, raw, actorID); !ok {
+			return
+		}
 		parsed := raw
 		roomID = &parsed
 	}
-	items, nextCursor, err := h.chat.SearchMessages(c.Request.Context(), tenantID, q, roomID, limit, cursor)
+	items, nextCursor, err := h.chat.SearchMessages(c.Request.Context(), tenantID, actorID, q, roomID, limit, cursor)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
 		return
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/chat/service"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/middleware"
+)
+
+func incomingWebhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrIncomingWebhookName), errors.Is(err, service.ErrInvalidBotUser):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrIncomingWebhookUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrIncomingWebhookNotFound):
+		return http.StatusNotFound
+	default:
+		return messageErrorStatus(err)
+	}
+}
+
+func incomingWebhookPath(item domain.IncomingWebhook) string {
+	return "/hooks/incoming/" + item.TenantID + "/" + item.ID + "/" + item.Token
+}
+
+func (h *Handler) createIncomingWebhook(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Name      string `json:"name" binding:"required"`
+		BotUserID string `json:"bot_user_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	item, err := h.incoming.CreateIncomingWebhook(c.Request.Context(), tenantID, roomID, actorID, req.Name, req.BotUserID)
+	if err != nil {
+		c.JSON(incomingWebhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=chat_incoming_webhook action=create status=ok tenant_id=%s room_id=%s hook_id=%s bot_user_id=%s user_id=%s", tenantID, roomID, item.ID, item.BotUserID, actorID)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, IncomingWebhookResponse{IncomingWebhook: item, URL: incomingWebhookPath(item)})
+}
+
+func (h *Handler) listIncomingWebhooks(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	items, err := h.incoming.ListIncomingWebhooks(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		c.JSON(incomingWebhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) revokeIncomingWebhook(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	item, err := h.incoming.RevokeIncomingWebhook(c.Request.Context(), tenantID, roomID, c.Param("hookId"), actorID)
+	if err != nil {
+		c.JSON(incomingWebhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=chat_incoming_webhook action=revoke status=ok tenant_id=%s room_id=%s hook_id=%s user_id=%s", tenantID, roomID, item.ID, actorID)
+	c.JSON(http.StatusOK, item)
+}
+
+// postIncomingWebhook is public; the token in the URL authenticates the caller.
+func (h *Handler) postIncomingWebhook(c *gin.Context) {
+	tenantID := strings.TrimSpace(c.Param("tenantId"))
+	hookID := strings.TrimSpace(c.Param("hookId"))
+	var req struct {
+		Body            string   `json:"body" binding:"required"`
+		FileID          *string  `json:"file_id"`
+		FileIDs         []string `json:"file_ids"`
+		Emojis          []string `json:"emojis"`
+		ParentMessageID *string  `json:"parent_message_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	start := time.Now()
+	msg, err := h.incoming.Post(c.Request.Context(), tenantID, hookID, c.Param("token"), domain.Message{
+		Body:            req.Body,
+		MetaJSON:        service.BuildMessageMeta(req.FileID, req.FileIDs, req.Emojis),
+		ParentMessageID: req.ParentMessageID,
+	})
+	if err != nil {
+		var limited *service.RateLimitError
+		if errors.As(err, &limited) {
+			commonlog.Warnf("event=rate_limit action=throttle source=incoming_webhook tenant_id=%s hook_id=%s client_ip=%s", tenantID, hookID, c.ClientIP())
+			middleware.AbortRateLimited(c, limited.RetryAfter)
+			return
+		}
+		status := incomingWebhookErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			commonlog.Errorf("event=chat_message_persist action=create status=failed source=incoming_webhook tenant_id=%s hook_id=%s latency_ms=%d error=%v", tenantID, hookID, time.Since(start).Milliseconds(), err)
+		} else {
+			commonlog.Warnf("event=chat_incoming_webhook action=post status=rejected tenant_id=%s hook_id=%s client_ip=%s http_status=%d error=%v", tenantID, hookID, c.ClientIP(), status, err)
+		}
+		c.JSON(status, NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=chat_message_persist action=create status=ok source=incoming_webhook tenant_id=%s room_id=%s user_id=%s hook_id=%s message_id=%s latency_ms=%d", tenantID, msg.RoomID, msg.SenderID, hookID, msg.ID, time.Since(start).Milliseconds())
+	if !h.chat.IsMQEnabled() {
+		if err := h.ws.PublishMessage(c.Request.Context(), tenantID, msg.RoomID, msg.SenderID, msg); err != nil {
+			c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
+			return
+		}
+	}
+	c.JSON(http.StatusCreated, msg)
+}
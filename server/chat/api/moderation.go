@@ -0,0 +1,328 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/chat/service"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/moderation"
+)
+
+func moderationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, moderation.ErrInvalidRule), errors.Is(err, service.ErrModerationReason), errors.Is(err, service.ErrModerationReasonNeeded):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrModerationRuleNotFound), errors.Is(err, service.ErrModerationFlagNotFound),
+		errors.Is(err, service.ErrMessageNotFound), errors.Is(err, service.ErrMemberNotFound),
+		errors.Is(err, service.ErrModerationUser), errors.Is(err, service.ErrModerationBanNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrRoomOwner):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type moderationRuleRequest struct {
+	Name     string            `json:"name" binding:"required"`
+	Kind     moderation.Kind   `json:"kind" binding:"required"`
+	Words    []string          `json:"words"`
+	Pattern  string            `json:"pattern"`
+	MaxLinks int               `json:"max_links"`
+	Action   moderation.Action `json:"action" binding:"required"`
+	Enabled  *bool             `json:"enabled"`
+}
+
+func (r moderationRuleRequest) rule(id string) moderation.Rule {
+	enabled := r.Enabled == nil || *r.Enabled
+	return moderation.Rule{ID: id, Name: r.Name, Kind: r.Kind, Words: r.Words, Pattern: r.Pattern, MaxLinks: r.MaxLinks, Action: r.Action, Enabled: enabled}
+}
+
+func (h *Handler) listModerationRules(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	items, err := h.moderation.ListRules(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) createModerationRule(c *gin.Context) {
+	h.saveModerationRule(c, "")
+}
+
+func (h *Handler) updateModerationRule(c *gin.Context) {
+	h.saveModerationRule(c, c.Param("ruleId"))
+}
+
+func (h *Handler) saveModerationRule(c *gin.Context, ruleID string) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req moderationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	item, err := h.moderation.SaveRule(c.Request.Context(), tenantID, actorID, req.rule(ruleID))
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=moderation action=save_rule status=ok tenant_id=%s rule_id=%s kind=%s rule_action=%s enabled=%t user_id=%s", tenantID, item.ID, item.Kind, item.Action, item.Enabled, actorID)
+	if ruleID == "" {
+		c.JSON(http.StatusCreated, item)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) deleteModerationRule(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	if err := h.moderation.DeleteRule(c.Request.Context(), tenantID, c.Param("ruleId")); err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewOKResponse())
+}
+
+// listModerationFlags defaults to the pending review queue; status=all lists
+// resolved flags too.
+func (h *Handler) listModerationFlags(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	status := moderation.FlagStatus(c.DefaultQuery("status", string(moderation.FlagPending)))
+	switch status {
+	case moderation.FlagPending, moderation.FlagApproved, moderation.FlagRemoved:
+	case "all":
+		status = ""
+	default:
+		c.JSON(http.StatusBadRequest, NewErrorResponse("status must be pending, approved, removed or all"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	items, err := h.moderation.ListFlags(c.Request.Context(), tenantID, status, limit)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) approveModerationFlag(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	flag, err := h.moderation.ApproveFlag(c.Request.Context(), tenantID, c.Param("flagId"), actorID)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// removeModerationFlag deletes the flagged content; a removed message is
+// announced to the room like a sender's own delete.
+func (h *Handler) removeModerationFlag(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	flag, removed, err := h.moderation.RemoveFlag(c.Request.Context(), tenantID, c.Param("flagId"), actorID)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	if removed != nil {
+		_ = h.ws.PublishEvent(c.Request.Context(), tenantID, removed.RoomID, actorID, "message.deleted", removed)
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+type moderationReasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// reportMessage lets a member send a message to the review queue. Reports are
+// not announced to the room.
+func (h *Handler) reportMessage(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req moderationReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	flag, err := h.moderation.ReportMessage(c.Request.Context(), tenantID, c.Param("id"), c.Param("messageId"), actorID, req.Reason)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, NewIDResponse(flag.ID))
+}
+
+// publishModerationAction tells the room what an admin did, with the audit
+// entry as payload.
+func (h *Handler) publishModerationAction(c *gin.Context, tenantID, actorID string, entry moderation.AuditEntry) {
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, entry.RoomID, actorID, "moderation.action", entry)
+}
+
+func (h *Handler) moderatorDeleteMessage(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req moderationReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	msg, entry, err := h.moderation.DeleteMessage(c.Request.Context(), tenantID, c.Param("roomId"), c.Param("messageId"), actorID, req.Reason)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, msg.RoomID, actorID, "message.deleted", msg)
+	h.publishModerationAction(c, tenantID, actorID, entry)
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) moderatorRemoveMember(c *gin.Context) {
+	h.removeRoomMember(c, false)
+}
+
+func (h *Handler) moderatorBanMember(c *gin.Context) {
+	h.removeRoomMember(c, true)
+}
+
+func (h *Handler) removeRoomMember(c *gin.Context, ban bool) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req moderationReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	entry, removed, err := h.moderation.RemoveMember(c.Request.Context(), tenantID, c.Param("roomId"), c.Param("userId"), actorID, req.Reason, ban)
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	if removed {
+		// Also evicts the user's open connections to the room.
+		_ = h.ws.PublishMembership(c.Request.Context(), tenantID, entry.RoomID, actorID, entry.TargetUserID, domain.MembershipRemoved)
+	}
+	h.publishModerationAction(c, tenantID, actorID, entry)
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) moderatorUnbanMember(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	entry, err := h.moderation.UnbanMember(c.Request.Context(), tenantID, c.Param("roomId"), c.Param("userId"), actorID, c.Query("reason"))
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	h.publishModerationAction(c, tenantID, actorID, entry)
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) listRoomBans(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	items, err := h.moderation.ListBans(c.Request.Context(), tenantID, c.Param("roomId"))
+	if err != nil {
+		c.JSON(moderationErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// listModerationAudit pages the tenant's audit log, newest first; room_id
+// narrows it to one room.
+func (h *Handler) listModerationAudit(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	items, nextCursor, err := h.moderation.ListAudit(c.Request.Context(), tenantID, c.Query("room_id"), limit, c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewPaginatedResponse(items, nextCursor))
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) pinMessage(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	pin, err := h.chat.PinMessage(c.Request.Context(), tenantID, roomID, c.Param("messageId"), userID)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, roomID, userID, "message.pinned", pin)
+	c.JSON(http.StatusOK, pin)
+}
+
+func (h *Handler) unpinMessage(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	messageID := c.Param("messageId")
+	userID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	if err := h.chat.UnpinMessage(c.Request.Context(), tenantID, roomID, messageID); err != nil {
+		c.JSON(messageErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, roomID, userID, "message.unpinned", gin.H{"room_id": roomID, "message_id": messageID, "unpinned_by": userID})
+	c.JSON(http.StatusOK, NewOKResponse())
+}
+
+func (h *Handler) listPins(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	items, err := h.chat.ListPinnedMessages(c.Request.Context(), tenantID, c.Param("id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/chat/service"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/middleware"
+)
+
+func rateLimitErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidRateLimitPolicy), errors.Is(err, service.ErrInvalidRateLimitValue):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrRateLimitPolicyNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// apiRateLimit draws every authenticated call from the tenant and user api
+// buckets.
+func (h *Handler) apiRateLimit() gin.HandlerFunc {
+	return middleware.RateLimit(h.limits.Limiter(), func(c *gin.Context) (string, []middleware.Bucket, bool) {
+		return h.rateLimitBuckets(c, "", domain.RateLimitActionAPI)
+	})
+}
+
+// messageRateLimit adds the tenant, room and user message buckets.
+func (h *Handler) messageRateLimit() gin.HandlerFunc {
+	return middleware.RateLimit(h.limits.Limiter(), func(c *gin.Context) (string, []middleware.Bucket, bool) {
+		return h.rateLimitBuckets(c, c.Param("id"), domain.RateLimitActionMessage)
+	})
+}
+
+func (h *Handler) rateLimitBuckets(c *gin.Context, roomID string, action domain.RateLimitAction) (string, []middleware.Bucket, bool) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		return "", nil, false
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		return "", nil, false
+	}
+	buckets, err := h.limits.Buckets(c.Request.Context(), tenantID, actorID, roomID, action)
+	if err != nil {
+		commonlog.Warnf("event=rate_limit action=resolve status=failed tenant_id=%s room_id=%s user_id=%s limit_action=%s error=%v", tenantID, roomID, actorID, action, err)
+		return "", nil, false
+	}
+	return tenantID, buckets, true
+}
+
+func (h *Handler) listRateLimits(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	items, err := h.limits.ListPolicies(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(rateLimitErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, RateLimitsResponse{Defaults: h.limits.Defaults(), Items: items})
+}
+
+func (h *Handler) upsertRateLimit(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Scope     domain.RateLimitScope  `json:"scope" binding:"required"`
+		SubjectID string                 `json:"subject_id"`
+		Action    domain.RateLimitAction `json:"action" binding:"required"`
+		PerMinute int                    `json:"per_minute"`
+		Burst     int                    `json:"burst"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	item, err := h.limits.UpsertPolicy(c.Request.Context(), tenantID, actorID, domain.RateLimitPolicy{
+		Scope:     req.Scope,
+		SubjectID: req.SubjectID,
+		Action:    req.Action,
+		PerMinute: req.PerMinute,
+		Burst:     req.Burst,
+	})
+	if err != nil {
+		c.JSON(rateLimitErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	commonlog.Infof("event=rate_limit action=upsert status=ok tenant_id=%s scope=%s subject_id=%s limit_action=%s per_minute=%d burst=%d user_id=%s", tenantID, item.Scope, item.SubjectID, item.Action, item.PerMinute, item.Burst, actorID)
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) deleteRateLimit(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	if err := h.limits.DeletePolicy(c.Request.Context(), tenantID, c.Param("id")); err != nil {
+		c.JSON(rateLimitErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewOKResponse())
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/mq"
 	"msg_server/server/common/transport/httpresp"
 )
 
@@ -10,6 +12,8 @@ const (
 	ErrCannotUpdateOtherUserState = httpresp.ErrCannotUpdateOtherUserState
 	ErrFromMustBeRFC3339          = httpresp.ErrFromMustBeRFC3339
 	ErrToMustBeRFC3339            = httpresp.ErrToMustBeRFC3339
+	ErrRoomAccessDenied           = httpresp.ErrRoomAccessDenied
+	ErrInsufficientRole           = httpresp.ErrInsufficientRole
 )
 
 type PaginatedResponse[T any] struct {
@@ -27,6 +31,25 @@ type HealthResponse struct {
 	Status string `json:"status"`
 }
 
+type ReadinessResponse struct {
+	Status string              `json:"status"`
+	MQ     *mq.PublisherHealth `json:"mq,omitempty"`
+}
+
+// IncomingWebhookResponse adds the path external systems post to; it embeds
+// the token and is only returned on creation.
+type IncomingWebhookResponse struct {
+	domain.IncomingWebhook
+	URL string `json:"url"`
+}
+
+// RateLimitsResponse lists the tenant's policies next to the configured
+// defaults they override.
+type RateLimitsResponse struct {
+	Defaults []domain.RateLimitPolicy `json:"defaults"`
+	Items    []domain.RateLimitPolicy `json:"items"`
+}
+
 type AliasesResponse struct {
 	Aliases []string `json:"aliases"`
 }
@@ -43,6 +66,14 @@ type ReadStateResponse struct {
 	LastReadMessageID string `json:"last_read_message_id"`
 }
 
+type SyncResponse struct {
+	Messages    []domain.Message          `json:"messages"`
+	ReadStates  []domain.RoomReadState    `json:"read_states"`
+	Memberships []domain.MembershipChange `json:"memberships"`
+	NextToken   string                    `json:"next_token"`
+	HasMore     bool                      `json:"has_more"`
+}
+
 func NewPaginatedResponse[T any](items []T, nextCursor string) PaginatedResponse[T] {
 	return PaginatedResponse[T]{
 		Items:      items,
@@ -74,6 +105,10 @@ func NewHealthResponse(status string) HealthResponse {
 	return HealthResponse{Status: status}
 }
 
+func NewReadinessResponse(status string, mqHealth *mq.PublisherHealth) ReadinessResponse {
+	return ReadinessResponse{Status: status, MQ: mqHealth}
+}
+
 func NewAliasesResponse(aliases []string) AliasesResponse {
 	return AliasesResponse{Aliases: aliases}
 }
@@ -85,3 +120,13 @@ func NewRoomUnreadCountResponse(roomID, userID string, unreadCount int64) RoomUn
 func NewReadStateResponse(roomID, userID, lastReadMessageID string) ReadStateResponse {
 	return ReadStateResponse{RoomID: roomID, UserID: userID, LastReadMessageID: lastReadMessageID}
 }
+
+func NewSyncResponse(batch domain.SyncBatch, nextToken string) SyncResponse {
+	return SyncResponse{
+		Messages:    batch.Messages,
+		ReadStates:  batch.ReadStates,
+		Memberships: batch.Memberships,
+		NextToken:   nextToken,
+		HasMore:     batch.HasMore,
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/chat/service"
+	"msg_server/server/common/infra/dbman"
+	"msg_server/server/common/middleware"
+	"msg_server/server/common/transport/wsconn"
+)
+
+// newRoomAccessHandler registers the real routes against a dbman stand-in
+// where only "member-1" belongs to "room-1". Every other dbman call is a 404,
+// which also leaves rate limiting unresolved so requests are not limited.
+func newRoomAccessHandler(t *testing.T) (*Handler, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dbman.BasePath+"/rooms/members/get" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			RoomID string `json:"room_id"`
+			UserID string `json:"user_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]any{"ok": false}
+		if req.RoomID == "room-1" && req.UserID == "member-1" {
+			resp = map[string]any{"ok": true, "member": domain.RoomMember{RoomID: req.RoomID, UserID: req.UserID, Role: domain.RoomRoleMember}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(db.Close)
+
+	client := service.NewDBManClient(db.URL)
+	chat := service.NewChatService(nil, client, service.NewVectormanClient("", false), false)
+	ws := service.NewRealtimeService(nil, chat, wsconn.Config{})
+	limiter := middleware.NewRateLimiter(nil)
+	h := NewHandler(
+		chat,
+		service.NewCallService(chat, ws, 0),
+		service.NewWebhookService(client),
+		service.NewIncomingWebhookService(client, chat, limiter, 0),
+		service.NewRateLimitService(client, limiter, service.RateLimitDefaults{}),
+		service.NewModerationService(client, chat),
+		ws,
+		"test-secret", 5,
+	)
+	r := gin.New()
+	h.RegisterRoutes(r)
+	return h, r
+}
+
+func token(t *testing.T, h *Handler, userID string) string {
+	t.Helper()
+	tok, err := h.auth.GenerateToken(userID, "tenant-1", string(domain.UserRoleUser))
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return tok
+}
+
+// roomRoutes lists every route under /api/v1/rooms/:id and the room-scoped
+// moderation routes, with room-1 and placeholder ids filled in.
+var roomRoutes = []struct {
+	method, path string
+}{
+	{http.MethodGet, "/api/v1/rooms/room-1"},
+	{http.MethodPatch, "/api/v1/rooms/room-1"},
+	{http.MethodDelete, "/api/v1/rooms/room-1"},
+	{http.MethodPost, "/api/v1/rooms/room-1/archive"},
+	{http.MethodPost, "/api/v1/rooms/room-1/unarchive"},
+	{http.MethodPost, "/api/v1/rooms/room-1/transfer-ownership"},
+	{http.MethodPost, "/api/v1/rooms/room-1/leave"},
+	{http.MethodPost, "/api/v1/rooms/room-1/members"},
+	{http.MethodPut, "/api/v1/rooms/room-1/members/member-1/role"},
+	{http.MethodDelete, "/api/v1/rooms/room-1/members/member-1"},
+	{http.MethodPost, "/api/v1/rooms/room-1/messages"},
+	{http.MethodGet, "/api/v1/rooms/room-1/messages"},
+	{http.MethodGet, "/api/v1/rooms/room-1/unread-count"},
+	{http.MethodPost, "/api/v1/rooms/room-1/read"},
+	{http.MethodGet, "/api/v1/rooms/room-1/read"},
+	{http.MethodPatch, "/api/v1/rooms/room-1/messages/m1"},
+	{http.MethodDelete, "/api/v1/rooms/room-1/messages/m1"},
+	{http.MethodGet, "/api/v1/rooms/room-1/messages/m1/revisions"},
+	{http.MethodGet, "/api/v1/rooms/room-1/messages/m1/thread"},
+	{http.MethodPost, "/api/v1/rooms/room-1/messages/m1/reactions"},
+	{http.MethodDelete, "/api/v1/rooms/room-1/messages/m1/reactions/ok"},
+	{http.MethodGet, "/api/v1/rooms/room-1/messages/m1/readers"},
+	{http.MethodGet, "/api/v1/rooms/room-1/pins"},
+	{http.MethodPost, "/api/v1/rooms/room-1/messages/m1/pin"},
+	{http.MethodDelete, "/api/v1/rooms/room-1/messages/m1/pin"},
+	{http.MethodPost, "/api/v1/rooms/room-1/messages/m1/report"},
+	{http.MethodPost, "/api/v1/rooms/room-1/calls"},
+	{http.MethodGet, "/api/v1/rooms/room-1/calls"},
+	{http.MethodGet, "/api/v1/rooms/room-1/calls/c1"},
+	{http.MethodPost, "/api/v1/rooms/room-1/calls/c1/answer"},
+	{http.MethodPost, "/api/v1/rooms/room-1/calls/c1/hangup"},
+	{http.MethodPost, "/api/v1/rooms/room-1/calls/c1/sfu-token"},
+	{http.MethodPost, "/api/v1/rooms/room-1/incoming-webhooks"},
+	{http.MethodGet, "/api/v1/rooms/room-1/incoming-webhooks"},
+	{http.MethodDelete, "/api/v1/rooms/room-1/incoming-webhooks/h1"},
+	{http.MethodPost, "/api/v1/moderation/rooms/room-1/messages/m1/delete"},
+	{http.MethodPost, "/api/v1/moderation/rooms/room-1/members/member-1/remove"},
+	{http.MethodPost, "/api/v1/moderation/rooms/room-1/members/member-1/ban"},
+	{http.MethodGet, "/api/v1/moderation/rooms/room-1/bans"},
+	{http.MethodDelete, "/api/v1/moderation/rooms/room-1/bans/member-1"},
+}
+
+func TestRoomRoutesRejectNonMember(t *testing.T) {
+	h, r := newRoomAccessHandler(t)
+	tok := token(t, h, "outsider")
+	for _, route := range roomRoutes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tok)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, http.StatusForbidden, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoomRoutesAdmitMember(t *testing.T) {
+	h, r := newRoomAccessHandler(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/rooms/room-1/messages/m1/revisions", nil)
+	req.Header.Set("Authorization", "Bearer "+token(t, h, "member-1"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusForbidden {
+		t.Fatalf("member was refused: %s", w.Body.String())
+	}
+}
+
+func TestHandleWSRejectsNonMember(t *testing.T) {
+	h, r := newRoomAccessHandler(t)
+	req := httptest.NewRequest(http.MethodGet, "/ws?room_id=room-1&access_token="+token(t, h, "outsider"), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d (%s)", w.Code, http.StatusForbidden, w.Body.String())
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/chat/service"
+)
+
+func roomErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidRoomName), errors.Is(err, service.ErrDirectRoom), errors.Is(err, service.ErrInvalidRoomRole):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrMemberBanned), errors.Is(err, service.ErrRoomPermission):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrRoomOwner), errors.Is(err, service.ErrOwnerMustTransfer):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrRoomNotFound), errors.Is(err, service.ErrMemberNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) getRoom(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	room, err := h.chat.GetRoom(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, room)
+}
+
+func (h *Handler) renameRoom(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	room, err := h.chat.RenameRoom(c.Request.Context(), tenantID, c.Param("id"), req.Name)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, room.ID, actorID, service.EventRoomUpdated, room)
+	c.JSON(http.StatusOK, room)
+}
+
+func (h *Handler) archiveRoom(c *gin.Context) {
+	h.setRoomArchived(c, true)
+}
+
+func (h *Handler) unarchiveRoom(c *gin.Context) {
+	h.setRoomArchived(c, false)
+}
+
+func (h *Handler) setRoomArchived(c *gin.Context, archived bool) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	room, err := h.chat.SetRoomArchived(c.Request.Context(), tenantID, c.Param("id"), archived)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishEvent(c.Request.Context(), tenantID, room.ID, actorID, service.EventRoomUpdated, room)
+	c.JSON(http.StatusOK, room)
+}
+
+// deleteRoom removes the room for everyone. The room.deleted event closes or
+// unsubscribes every open connection to it.
+func (h *Handler) deleteRoom(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	memberIDs, err := h.chat.DeleteRoom(c.Request.Context(), tenantID, roomID)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishRoomDeleted(c.Request.Context(), tenantID, roomID, actorID, memberIDs)
+	c.JSON(http.StatusOK, NewOKResponse())
+}
+
+func (h *Handler) leaveRoom(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	if err := h.chat.LeaveRoom(c.Request.Context(), tenantID, roomID, actorID); err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishMembership(c.Request.Context(), tenantID, roomID, actorID, actorID, domain.MembershipLeft)
+	c.JSON(http.StatusOK, NewOKResponse())
+}
+
+func (h *Handler) removeMember(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	roomID := c.Param("id")
+	userID := c.Param("userId")
+	if userID == actorID {
+		h.leaveRoom(c)
+		return
+	}
+	if err := h.chat.RemoveMember(c.Request.Context(), tenantID, roomID, roomRole(c), userID); err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishMembership(c.Request.Context(), tenantID, roomID, actorID, userID, domain.MembershipRemoved)
+	c.JSON(http.StatusOK, NewOKResponse())
+}
+
+func (h *Handler) setMemberRole(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Role domain.RoomRole `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	member, err := h.chat.SetMemberRole(c.Request.Context(), tenantID, c.Param("id"), roomRole(c), c.Param("userId"), req.Role)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	_ = h.ws.PublishRoleChange(c.Request.Context(), tenantID, actorID, member)
+	c.JSON(http.StatusOK, member)
+}
+
+// transferRoomOwnership makes another member the owner; the caller, if they
+// were the owner, becomes an admin.
+func (h *Handler) transferRoomOwnership(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	changed, err := h.chat.TransferOwnership(c.Request.Context(), tenantID, c.Param("id"), roomRole(c), req.UserID)
+	if err != nil {
+		c.JSON(roomErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	for _, member := range changed {
+		_ = h.ws.PublishRoleChange(c.Request.Context(), tenantID, actorID, member)
+	}
+	c.JSON(http.StatusOK, changed)
+}
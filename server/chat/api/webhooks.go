@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/chat/service"
+)
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhookURL), errors.Is(err, service.ErrWebhookURLNotPublic), errors.Is(err, service.ErrInvalidWebhookEvents), errors.Is(err, service.ErrInvalidWebhookSecret):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrWebhookNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) createWebhook(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	actorID, _, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		TargetURL   string   `json:"target_url" binding:"required"`
+		EventTypes  []string `json:"event_types" binding:"required"`
+		Description string   `json:"description"`
+		Secret      string   `json:"secret"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	item, err := h.webhooks.CreateWebhook(c.Request.Context(), tenantID, actorID, req.TargetURL, req.EventTypes, req.Description, req.Secret)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) listWebhooks(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	items, err := h.webhooks.ListWebhooks(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) getWebhook(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	item, err := h.webhooks.GetWebhook(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) updateWebhook(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		TargetURL   *string  `json:"target_url"`
+		EventTypes  []string `json:"event_types"`
+		Description *string  `json:"description"`
+		IsActive    *bool    `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	item, err := h.webhooks.UpdateWebhook(c.Request.Context(), tenantID, c.Param("id"), req.TargetURL, req.EventTypes, req.Description, req.IsActive)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) rotateWebhookSecret(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	var req struct {
+		Secret string `json:"secret"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+			return
+		}
+	}
+	item, err := h.webhooks.RotateWebhookSecret(c.Request.Context(), tenantID, c.Param("id"), req.Secret)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	if err := h.webhooks.DeleteWebhook(c.Request.Context(), tenantID, c.Param("id")); err != nil {
+		c.JSON(webhookErrorStatus(err), NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewOKResponse())
+}
+
+func (h *Handler) listWebhookDeliveries(c *gin.Context) {
+	tenantID, err := tenantFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse(err.Error()))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "30"))
+	items, nextCursor, err := h.webhooks.ListDeliveries(c.Request.Context(), tenantID, c.Param("id"), limit, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, NewPaginatedResponse(items, nextCursor))
+}
@@ -7,21 +7,24 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 
 	"msg_server/server/chat/api"
 	"msg_server/server/chat/service"
+	commonauth "msg_server/server/common/auth"
 	"msg_server/server/common/infra/cache"
 	"msg_server/server/common/infra/mq"
+	"msg_server/server/common/middleware"
+	"msg_server/server/common/transport/wsconn"
 )
 
 type Server struct {
 	HTTPServer        *http.Server
 	Redis             *redis.Client
-	MQConn            *amqp.Connection
 	TenantRedisRouter *cache.TenantRedisRouter
-	TenantMQPublisher *service.AMQPPublisher
+	TenantMQPublisher *mq.AMQPPublisher
+	Realtime          *service.RealtimeService
+	stopBackground    context.CancelFunc
 }
 
 func NewServer(cfg Config) (*Server, error) {
@@ -37,28 +40,65 @@ func NewServer(cfg Config) (*Server, error) {
 	tenantRedisRouter := cache.NewTenantRedisRouter(redisClient, dbClient)
 	var err error
 
-	var (
-		mqConn            *amqp.Connection
-		tenantMQPublisher *service.AMQPPublisher
-	)
+	var tenantMQPublisher *mq.AMQPPublisher
 	if cfg.UseMQ {
-		mqConn, err = mq.NewConnection(cfg.LavinMQURL)
+		tenantMQPublisher, err = mq.NewAMQPPublisher(cfg.LavinMQURL, dbClient, mq.PublisherConfig{
+			ChannelPoolSize: cfg.MQChannelPoolSize,
+			ConfirmTimeout:  time.Duration(cfg.MQConfirmTimeoutMS) * time.Millisecond,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("initialize lavinmq: %w", err)
 		}
-
-		tenantMQPublisher, err = service.NewAMQPPublisher(mqConn, dbClient)
-		if err != nil {
-			return nil, fmt.Errorf("initialize amqp publisher: %w", err)
-		}
 	}
 
 	vectorClient := service.NewVectormanClient(cfg.VectormanEndpoint, cfg.MilvusEnabled)
 	chatSvc := service.NewChatService(tenantMQPublisher, dbClient, vectorClient, cfg.UseMQ)
-	wsSvc := service.NewRealtimeService(tenantRedisRouter, chatSvc)
+	moderationSvc := service.NewModerationService(dbClient, chatSvc)
+	chatSvc.UseMessageHooks(moderationSvc)
+	queueCfg := wsconn.DefaultConfig()
+	queueCfg.QueueSize = cfg.WSQueueSize
+	queueCfg.Policy = wsconn.ParsePolicy(cfg.WSOverflowPolicy)
+	queueCfg.PingInterval = time.Duration(cfg.WSPingIntervalSec) * time.Second
+	queueCfg.PongWait = time.Duration(cfg.WSPongWaitSec) * time.Second
+	queueCfg.DrainWindow = time.Duration(cfg.WSDrainWindowMS) * time.Millisecond
+	queueCfg.DrainReconnectAfter = time.Duration(cfg.WSReconnectAfterMS) * time.Millisecond
+	wsSvc := service.NewRealtimeService(tenantRedisRouter, chatSvc, queueCfg)
+	wsSvc.UseSignalRateLimit(cfg.WSSignalRate, cfg.WSSignalBurst)
+	rateLimiter := middleware.NewRateLimiter(tenantRedisRouter)
+	rateLimitSvc := service.NewRateLimitService(dbClient, rateLimiter, service.RateLimitDefaults{
+		TenantMessage: middleware.Limit{PerMinute: cfg.RateLimitTenantMessagePerMin},
+		TenantAPI:     middleware.Limit{PerMinute: cfg.RateLimitTenantAPIPerMin},
+		RoomMessage:   middleware.Limit{PerMinute: cfg.RateLimitRoomMessagePerMin},
+		UserMessage:   middleware.Limit{PerMinute: cfg.RateLimitUserMessagePerMin, Burst: cfg.RateLimitUserMessageBurst},
+		UserAPI:       middleware.Limit{PerMinute: cfg.RateLimitUserAPIPerMin, Burst: cfg.RateLimitUserAPIBurst},
+	})
+	wsSvc.UseRateLimits(rateLimitSvc)
+
+	callSvc := service.NewCallService(chatSvc, wsSvc, time.Duration(cfg.CallRingTimeoutSec)*time.Second)
+	callSvc.UseICEConfig(service.ICEConfig{
+		StunURLs:   cfg.StunURLs,
+		TurnURLs:   cfg.TurnURLs,
+		TurnSecret: cfg.TurnSharedSecret,
+		TurnTTL:    time.Duration(cfg.TurnCredentialTTL) * time.Second,
+	})
+	callSvc.UseSessionNotifier(service.NewSessionClient(cfg.SessionEndpoint, commonauth.NewService(cfg.JWTSecret, 1)))
+	callSvc.UseSFUConfig(service.SFUConfig{
+		URL:       cfg.SFUURL,
+		APIKey:    cfg.SFUAPIKey,
+		APISecret: cfg.SFUAPISecret,
+		TokenTTL:  time.Duration(cfg.SFUTokenTTLSec) * time.Second,
+	})
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	go callSvc.RunRingSweeper(backgroundCtx)
 
-	h := api.NewHandler(chatSvc, wsSvc, cfg.JWTSecret, cfg.JWTTTLMinutes)
+	webhookSvc := service.NewWebhookService(dbClient)
+	webhookSvc.AllowPrivateTargets(cfg.WebhookAllowPrivateTargets)
+	incomingSvc := service.NewIncomingWebhookService(dbClient, chatSvc, rateLimiter, cfg.IncomingWebhookPerMin)
+
+	h := api.NewHandler(chatSvc, callSvc, webhookSvc, incomingSvc, rateLimitSvc, moderationSvc, wsSvc, cfg.JWTSecret, cfg.JWTTTLMinutes)
 	r := gin.Default()
+	r.Use(middleware.TraceContext())
 	h.RegisterRoutes(r)
 
 	httpServer := &http.Server{
@@ -72,22 +112,28 @@ func NewServer(cfg Config) (*Server, error) {
 	return &Server{
 		HTTPServer:        httpServer,
 		Redis:             redisClient,
-		MQConn:            mqConn,
 		TenantRedisRouter: tenantRedisRouter,
 		TenantMQPublisher: tenantMQPublisher,
+		Realtime:          wsSvc,
+		stopBackground:    stopBackground,
 	}, nil
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+	// Sockets are hijacked, so HTTPServer.Shutdown does not wait for them;
+	// drain them first while Redis and MQ are still available.
+	if s.Realtime != nil {
+		s.Realtime.Drain(ctx)
+	}
 	if s.TenantMQPublisher != nil {
 		s.TenantMQPublisher.Close()
 	}
 	if s.TenantRedisRouter != nil {
 		s.TenantRedisRouter.Close()
 	}
-	if s.MQConn != nil {
-		_ = s.MQConn.Close()
-	}
 	if s.Redis != nil {
 		_ = s.Redis.Close()
 	}
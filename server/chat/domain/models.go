@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type UserStatus string
 type UserRole string
@@ -17,6 +20,13 @@ type Tenant struct {
 	DedicatedMinIOSecretKey string    `json:"dedicated_minio_secret_key"`
 	DedicatedMinIOBucket    string    `json:"dedicated_minio_bucket"`
 	DedicatedMinIOUseSSL    bool      `json:"dedicated_minio_use_ssl"`
+	StunURLs                []string  `json:"stun_urls"`
+	TurnURLs                []string  `json:"turn_urls"`
+	TurnSharedSecret        string    `json:"turn_shared_secret"`
+	TurnCredentialTTLSec    int       `json:"turn_credential_ttl_sec"`
+	SFUURL                  string    `json:"sfu_url"`
+	SFUAPIKey               string    `json:"sfu_api_key"`
+	SFUAPISecret            string    `json:"sfu_api_secret"`
 	UserCountThreshold      int       `json:"user_count_threshold"`
 	IsActive                bool      `json:"is_active"`
 	CreatedAt               time.Time `json:"created_at"`
@@ -35,6 +45,8 @@ const (
 	UserRoleAdmin   UserRole = "admin"
 	UserRoleManager UserRole = "manager"
 	UserRoleUser    UserRole = "user"
+	// UserRoleBot users cannot log in; they only post through incoming webhooks.
+	UserRoleBot UserRole = "bot"
 )
 
 type OrgUnit struct {
@@ -61,22 +73,152 @@ type User struct {
 }
 
 type ChatRoom struct {
-	TenantID  string    `json:"tenant_id"`
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	RoomType  string    `json:"room_type"`
-	CreatedBy string    `json:"created_by"`
-	CreatedAt time.Time `json:"created_at"`
+	TenantID   string     `json:"tenant_id"`
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	RoomType   string     `json:"room_type"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+type RoomRole string
+
+const (
+	RoomRoleOwner    RoomRole = "owner"
+	RoomRoleAdmin    RoomRole = "admin"
+	RoomRoleMember   RoomRole = "member"
+	RoomRoleReadOnly RoomRole = "read_only"
+)
+
+// RoomPermission is an action gated by the member's room role. Manage covers
+// archiving and deleting the room and changing roles. React is granted to
+// every role so read-only members of announcement channels can still react.
+type RoomPermission string
+
+const (
+	RoomPermInvite       RoomPermission = "invite"
+	RoomPermRemove       RoomPermission = "remove"
+	RoomPermRename       RoomPermission = "rename"
+	RoomPermPin          RoomPermission = "pin"
+	RoomPermPost         RoomPermission = "post"
+	RoomPermReact        RoomPermission = "react"
+	RoomPermDeleteOthers RoomPermission = "delete_others"
+	RoomPermManage       RoomPermission = "manage"
+)
+
+var roomPermissions = map[RoomRole][]RoomPermission{
+	RoomRoleOwner:    {RoomPermInvite, RoomPermRemove, RoomPermRename, RoomPermPin, RoomPermPost, RoomPermReact, RoomPermDeleteOthers, RoomPermManage},
+	RoomRoleAdmin:    {RoomPermInvite, RoomPermRemove, RoomPermRename, RoomPermPin, RoomPermPost, RoomPermReact, RoomPermDeleteOthers},
+	RoomRoleMember:   {RoomPermInvite, RoomPermPost, RoomPermReact},
+	RoomRoleReadOnly: {RoomPermReact},
+}
+
+func (r RoomRole) Valid() bool {
+	_, ok := roomPermissions[r]
+	return ok
+}
+
+func (r RoomRole) Can(p RoomPermission) bool {
+	for _, granted := range roomPermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Outranks reports whether r may remove a member with role other or change
+// their role.
+func (r RoomRole) Outranks(other RoomRole) bool {
+	return roomRoleRank(r) > roomRoleRank(other)
+}
+
+func roomRoleRank(r RoomRole) int {
+	switch r {
+	case RoomRoleOwner:
+		return 3
+	case RoomRoleAdmin:
+		return 2
+	case RoomRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type RoomMember struct {
+	TenantID string    `json:"tenant_id"`
+	RoomID   string    `json:"room_id"`
+	UserID   string    `json:"user_id"`
+	Role     RoomRole  `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
 }
 
 type Message struct {
+	TenantID  string     `json:"tenant_id"`
+	ID        string     `json:"id"`
+	RoomID    string     `json:"room_id"`
+	Seq       int64      `json:"seq"`
+	SenderID  string     `json:"sender_id"`
+	Body      string     `json:"body"`
+	MetaJSON  string     `json:"meta_json"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	ParentMessageID *string           `json:"parent_message_id,omitempty"`
+	Thread          *ThreadSummary    `json:"thread,omitempty"`
+	Reactions       []ReactionSummary `json:"reactions,omitempty"`
+}
+
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int64  `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+type ReactionEvent struct {
+	MessageID string `json:"message_id"`
+	RoomID    string `json:"room_id"`
+	UserID    string `json:"user_id"`
+	Emoji     string `json:"emoji"`
+	Count     int64  `json:"count"`
+}
+
+type ThreadSummary struct {
+	ReplyCount        int64      `json:"reply_count"`
+	LastReplyID       *string    `json:"last_reply_id,omitempty"`
+	LastReplySenderID *string    `json:"last_reply_sender_id,omitempty"`
+	LastReplyAt       *time.Time `json:"last_reply_at,omitempty"`
+}
+
+type ThreadReplyEvent struct {
+	ParentMessageID string        `json:"parent_message_id"`
+	Message         Message       `json:"message"`
+	Thread          ThreadSummary `json:"thread"`
+}
+
+type MessageRevision struct {
+	TenantID   string    `json:"tenant_id"`
+	RevisionID string    `json:"revision_id"`
+	MessageID  string    `json:"message_id"`
+	RoomID     string    `json:"room_id"`
+	EditorID   string    `json:"editor_id"`
+	Body       string    `json:"body"`
+	MetaJSON   string    `json:"meta_json"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MessagePin is a message pinned to the top of its room. Message is only set
+// when listing the room's pins.
+type MessagePin struct {
 	TenantID  string    `json:"tenant_id"`
-	ID        string    `json:"id"`
 	RoomID    string    `json:"room_id"`
-	SenderID  string    `json:"sender_id"`
-	Body      string    `json:"body"`
-	MetaJSON  string    `json:"meta_json"`
-	CreatedAt time.Time `json:"created_at"`
+	MessageID string    `json:"message_id"`
+	PinnedBy  string    `json:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at"`
+	Message   *Message  `json:"message,omitempty"`
 }
 
 type MessageRead struct {
@@ -92,6 +234,130 @@ type RoomUnread struct {
 	UnreadCount int64  `json:"unread_count"`
 }
 
+type RoomReadState struct {
+	RoomID            string `json:"room_id"`
+	LastReadSeq       int64  `json:"last_read_seq"`
+	LastReadMessageID string `json:"last_read_message_id"`
+}
+
+// Membership change actions. Sync reports joined and left; realtime events
+// also tell a removal apart from leaving.
+const (
+	MembershipJoined  = "joined"
+	MembershipLeft    = "left"
+	MembershipRemoved = "removed"
+)
+
+type MembershipChange struct {
+	RoomID    string    `json:"room_id"`
+	UserID    string    `json:"user_id"`
+	Action    string    `json:"action"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SyncCursor is a position in the change feed, ordered by the writing
+// transaction and then change_id.
+type SyncCursor struct {
+	TxID     int64 `json:"tx_id"`
+	ChangeID int64 `json:"change_id"`
+}
+
+type SyncBatch struct {
+	Messages    []Message          `json:"messages"`
+	ReadStates  []RoomReadState    `json:"read_states"`
+	Memberships []MembershipChange `json:"memberships"`
+	Last        SyncCursor         `json:"last"`
+	HasMore     bool               `json:"has_more"`
+}
+
+type CallStatus string
+
+const (
+	CallStatusRinging  CallStatus = "ringing"
+	CallStatusActive   CallStatus = "active"
+	CallStatusEnded    CallStatus = "ended"
+	CallStatusDeclined CallStatus = "declined"
+	CallStatusMissed   CallStatus = "missed"
+)
+
+// Terminal reports whether no further transitions are possible.
+func (s CallStatus) Terminal() bool {
+	return s == CallStatusEnded || s == CallStatusDeclined || s == CallStatusMissed
+}
+
+type CallAction string
+
+const (
+	CallActionAnswer  CallAction = "answer"
+	CallActionDecline CallAction = "decline"
+	CallActionHangup  CallAction = "hangup"
+	CallActionExpire  CallAction = "expire"
+)
+
+type CallParticipantState string
+
+const (
+	CallParticipantInvited  CallParticipantState = "invited"
+	CallParticipantJoined   CallParticipantState = "joined"
+	CallParticipantDeclined CallParticipantState = "declined"
+	CallParticipantLeft     CallParticipantState = "left"
+	CallParticipantMissed   CallParticipantState = "missed"
+)
+
+type CallParticipant struct {
+	UserID    string               `json:"user_id"`
+	State     CallParticipantState `json:"state"`
+	InvitedAt time.Time            `json:"invited_at"`
+	JoinedAt  *time.Time           `json:"joined_at,omitempty"`
+	LeftAt    *time.Time           `json:"left_at,omitempty"`
+}
+
+type Call struct {
+	TenantID     string            `json:"tenant_id"`
+	ID           string            `json:"id"`
+	RoomID       string            `json:"room_id"`
+	CallerID     string            `json:"caller_id"`
+	Media        string            `json:"media"`
+	Status       CallStatus        `json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	StartedAt    *time.Time        `json:"started_at,omitempty"`
+	EndedAt      *time.Time        `json:"ended_at,omitempty"`
+	DurationSec  int64             `json:"duration_sec"`
+	Participants []CallParticipant `json:"participants"`
+}
+
+// ICEServer mirrors the browser RTCIceServer dictionary.
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+type ICEServerConfig struct {
+	ICEServers []ICEServer `json:"ice_servers"`
+	TTL        int         `json:"ttl"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+}
+
+type SFUToken struct {
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	Room      string    `json:"room"`
+	Identity  string    `json:"identity"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TurnCredentialAudit struct {
+	TenantID  string    `json:"tenant_id"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type AliasAudit struct {
 	TenantID  string    `json:"tenant_id"`
 	ID        string    `json:"id"`
@@ -110,6 +376,8 @@ type ChatRoomSummary struct {
 	RoomType                   string     `json:"room_type"`
 	CreatedBy                  string     `json:"created_by"`
 	CreatedAt                  time.Time  `json:"created_at"`
+	ArchivedAt                 *time.Time `json:"archived_at,omitempty"`
+	Role                       RoomRole   `json:"role"`
 	PeerUserID                 *string    `json:"peer_user_id,omitempty"`
 	PeerName                   *string    `json:"peer_name,omitempty"`
 	PeerStatus                 *string    `json:"peer_status,omitempty"`
@@ -124,3 +392,129 @@ type ChatRoomSummary struct {
 	LatestMessageSender        *string    `json:"latest_message_sender,omitempty"`
 	UnreadCount                int64      `json:"unread_count"`
 }
+
+// Webhook is a tenant's outgoing event subscription. Secret is only returned
+// when the webhook is created or its secret is rotated.
+type Webhook struct {
+	TenantID            string     `json:"tenant_id"`
+	ID                  string     `json:"id"`
+	TargetURL           string     `json:"target_url"`
+	EventTypes          []string   `json:"event_types"`
+	Secret              string     `json:"secret,omitempty"`
+	Description         string     `json:"description"`
+	IsActive            bool       `json:"is_active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	CreatedBy           string     `json:"created_by"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// IncomingWebhook lets an external system post into one room as a bot user.
+// Token is only returned when the hook is created; dbman keeps TokenHash and
+// never returns it.
+type IncomingWebhook struct {
+	TenantID   string     `json:"tenant_id"`
+	ID         string     `json:"id"`
+	RoomID     string     `json:"room_id"`
+	BotUserID  string     `json:"bot_user_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	TokenHash  string     `json:"token_hash,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  string     `json:"revoked_by,omitempty"`
+}
+
+type RateLimitScope string
+
+const (
+	RateLimitScopeTenant RateLimitScope = "tenant"
+	RateLimitScopeRoom   RateLimitScope = "room"
+	RateLimitScopeUser   RateLimitScope = "user"
+)
+
+func (s RateLimitScope) Valid() bool {
+	return s == RateLimitScopeTenant || s == RateLimitScopeRoom || s == RateLimitScopeUser
+}
+
+type RateLimitAction string
+
+const (
+	// RateLimitActionMessage covers message sends over REST and WS.
+	RateLimitActionMessage RateLimitAction = "message"
+	// RateLimitActionAPI covers every authenticated API call and WS frame.
+	RateLimitActionAPI RateLimitAction = "api"
+)
+
+func (a RateLimitAction) Valid() bool {
+	return a == RateLimitActionMessage || a == RateLimitActionAPI
+}
+
+// RateLimitPolicy overrides the configured default for one scope and action.
+// An empty SubjectID applies to every user or room without its own policy;
+// PerMinute 0 lifts the limit.
+type RateLimitPolicy struct {
+	TenantID  string          `json:"tenant_id"`
+	ID        string          `json:"id"`
+	Scope     RateLimitScope  `json:"scope"`
+	SubjectID string          `json:"subject_id"`
+	Action    RateLimitAction `json:"action"`
+	PerMinute int             `json:"per_minute"`
+	Burst     int             `json:"burst"`
+	UpdatedBy string          `json:"updated_by"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event queued for one webhook. TargetURL and Secret
+// are only filled when the dispatcher claims the delivery.
+type WebhookDelivery struct {
+	TenantID       string                `json:"tenant_id"`
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhook_id"`
+	EventID        string                `json:"event_id"`
+	EventType      string                `json:"event_type"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status"`
+	ResponseBody   string                `json:"response_body"`
+	LastError      string                `json:"last_error"`
+	DurationMS     int64                 `json:"duration_ms"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+	CompletedAt    *time.Time            `json:"completed_at,omitempty"`
+	TargetURL      string                `json:"target_url,omitempty"`
+	Secret         string                `json:"secret,omitempty"`
+}
+
+// WebhookAttempt is the outcome of one delivery attempt. A pending status
+// schedules a retry at NextAttemptAt. Failures count towards disabling the
+// webhook once DisableAfter consecutive attempts failed.
+type WebhookAttempt struct {
+	TenantID       string                `json:"tenant_id"`
+	DeliveryID     string                `json:"delivery_id"`
+	WebhookID      string                `json:"webhook_id"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Succeeded      bool                  `json:"succeeded"`
+	ResponseStatus int                   `json:"response_status"`
+	ResponseBody   string                `json:"response_body"`
+	Error          string                `json:"error"`
+	DurationMS     int64                 `json:"duration_ms"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	DisableAfter   int                   `json:"disable_after"`
+}
@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/auth"
+	"msg_server/server/common/events"
+	commonlog "msg_server/server/common/log"
+)
+
+var (
+	ErrCallNotFound     = errors.New("call not found")
+	ErrCallInProgress   = errors.New("a call is already in progress in this room")
+	ErrCallInvalidState = errors.New("call action is not allowed in the current state")
+	ErrInvalidCallMedia = errors.New("media must be audio or video")
+
+	ErrICEServersNotConfigured = errors.New("ice servers are not configured")
+	ErrSFUNotConfigured        = errors.New("sfu is not configured")
+	ErrNotCallParticipant      = errors.New("user is not an active participant of this call")
+)
+
+const (
+	defaultCallRingTimeout = 45 * time.Second
+	callSweepInterval      = 15 * time.Second
+	callSweepBatch         = 100
+	defaultTurnTTL         = time.Hour
+	defaultSFUTokenTTL     = 10 * time.Minute
+	missedCallBody         = "부재중 통화"
+)
+
+// CallService tracks call sessions on top of WebRTC signaling. Every state
+// change is fanned out to the room as a call.* event; unanswered calls are
+// marked missed after the ring timeout and leave a system message in the room.
+// Invitees are also rung on all their devices through the session service.
+type CallService struct {
+	chat        *ChatService
+	ws          *RealtimeService
+	ringTimeout time.Duration
+	ice         ICEConfig
+	sfu         SFUConfig
+	session     *SessionClient
+}
+
+// ICEConfig holds the service-wide STUN/TURN defaults. Tenants override any
+// field they set in their tenant config.
+type ICEConfig struct {
+	StunURLs   []string
+	TurnURLs   []string
+	TurnSecret string
+	TurnTTL    time.Duration
+}
+
+// SFUConfig points group calls at a LiveKit-compatible SFU. Tenants with their
+// own sfu_url/api key/secret use those instead.
+type SFUConfig struct {
+	URL       string
+	APIKey    string
+	APISecret string
+	TokenTTL  time.Duration
+}
+
+func NewCallService(chat *ChatService, ws *RealtimeService, ringTimeout time.Duration) *CallService {
+	if ringTimeout <= 0 {
+		ringTimeout = defaultCallRingTimeout
+	}
+	return &CallService{chat: chat, ws: ws, ringTimeout: ringTimeout, ice: ICEConfig{TurnTTL: defaultTurnTTL}, sfu: SFUConfig{TokenTTL: defaultSFUTokenTTL}}
+}
+
+func (s *CallService) UseICEConfig(cfg ICEConfig) {
+	if cfg.TurnTTL <= 0 {
+		cfg.TurnTTL = defaultTurnTTL
+	}
+	s.ice = cfg
+}
+
+func (s *CallService) UseSFUConfig(cfg SFUConfig) {
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = defaultSFUTokenTTL
+	}
+	s.sfu = cfg
+}
+
+// UseSessionNotifier rings invitees on every logged-in device.
+func (s *CallService) UseSessionNotifier(client *SessionClient) {
+	s.session = client
+}
+
+// IssueICEServers returns STUN/TURN servers for userID. TURN credentials
+// follow the coturn REST API convention (use-auth-secret): the username is
+// "<expiry unix>:<user_id>" and the password is base64(HMAC-SHA1(secret, username)).
+// Every issuance is audited; nothing is returned if the audit write fails.
+func (s *CallService) IssueICEServers(ctx context.Context, tenantID, userID, ip, userAgent string) (domain.ICEServerConfig, error) {
+	cfg := s.ice
+	tenant, err := s.chat.dbman.GetTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ICEServerConfig{}, err
+	}
+	if len(tenant.StunURLs) > 0 {
+		cfg.StunURLs = tenant.StunURLs
+	}
+	if len(tenant.TurnURLs) > 0 {
+		cfg.TurnURLs = tenant.TurnURLs
+	}
+	if strings.TrimSpace(tenant.TurnSharedSecret) != "" {
+		cfg.TurnSecret = tenant.TurnSharedSecret
+	}
+	if tenant.TurnCredentialTTLSec > 0 {
+		cfg.TurnTTL = time.Duration(tenant.TurnCredentialTTLSec) * time.Second
+	}
+
+	out := domain.ICEServerConfig{ICEServers: make([]domain.ICEServer, 0, 2)}
+	if len(cfg.StunURLs) > 0 {
+		out.ICEServers = append(out.ICEServers, domain.ICEServer{URLs: cfg.StunURLs})
+	}
+	if len(cfg.TurnURLs) > 0 && cfg.TurnSecret != "" {
+		expiresAt := time.Now().Add(cfg.TurnTTL).UTC().Truncate(time.Second)
+		username := fmt.Sprintf("%d:%s", expiresAt.Unix(), userID)
+		mac := hmac.New(sha1.New, []byte(cfg.TurnSecret))
+		mac.Write([]byte(username))
+		if err := s.chat.dbman.RecordTurnCredential(ctx, domain.TurnCredentialAudit{
+			TenantID:  tenantID,
+			UserID:    userID,
+			Username:  username,
+			ExpiresAt: expiresAt,
+			IP:        ip,
+			UserAgent: userAgent,
+		}); err != nil {
+			commonlog.Errorf("event=chat_turn_credential action=audit status=failed tenant_id=%s user_id=%s error=%v", tenantID, userID, err)
+			return domain.ICEServerConfig{}, err
+		}
+		commonlog.Infof("event=chat_turn_credential action=issue status=ok tenant_id=%s user_id=%s ttl_sec=%d", tenantID, userID, int(cfg.TurnTTL.Seconds()))
+		out.ICEServers = append(out.ICEServers, domain.ICEServer{
+			URLs:       cfg.TurnURLs,
+			Username:   username,
+			Credential: base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		})
+		out.TTL = int(cfg.TurnTTL.Seconds())
+		out.ExpiresAt = &expiresAt
+	}
+	if len(out.ICEServers) == 0 {
+		return domain.ICEServerConfig{}, ErrICEServersNotConfigured
+	}
+	return out, nil
+}
+
+// IssueSFUToken mints an SFU access token for userID to join the media room of
+// a live call. Only participants who are invited or joined get one; audio calls
+// may publish the microphone only. The SFU room is scoped by tenant and call so
+// tokens never cross calls.
+func (s *CallService) IssueSFUToken(ctx context.Context, tenantID, roomID, callID, userID string) (domain.SFUToken, error) {
+	cfg := s.sfu
+	tenant, err := s.chat.dbman.GetTenant(ctx, tenantID)
+	if err != nil {
+		return domain.SFUToken{}, err
+	}
+	if strings.TrimSpace(tenant.SFUAPIKey) != "" && strings.TrimSpace(tenant.SFUAPISecret) != "" {
+		cfg.APIKey = tenant.SFUAPIKey
+		cfg.APISecret = tenant.SFUAPISecret
+		if strings.TrimSpace(tenant.SFUURL) != "" {
+			cfg.URL = tenant.SFUURL
+		}
+	}
+	if cfg.URL == "" || cfg.APIKey == "" || cfg.APISecret == "" {
+		return domain.SFUToken{}, ErrSFUNotConfigured
+	}
+
+	call, err := s.GetCall(ctx, tenantID, roomID, callID)
+	if err != nil {
+		return domain.SFUToken{}, err
+	}
+	if call.Status.Terminal() {
+		return domain.SFUToken{}, ErrCallInvalidState
+	}
+	allowed := false
+	for _, p := range call.Participants {
+		if p.UserID == userID && (p.State == domain.CallParticipantInvited || p.State == domain.CallParticipantJoined) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return domain.SFUToken{}, ErrNotCallParticipant
+	}
+
+	grant := auth.SFUVideoGrant{
+		Room:           sfuRoomName(tenantID, call.ID),
+		RoomJoin:       true,
+		CanPublish:     true,
+		CanSubscribe:   true,
+		CanPublishData: true,
+	}
+	if call.Media == "audio" {
+		grant.CanPublishSources = []string{"microphone"}
+	}
+	token, expiresAt, err := auth.MintSFUToken(cfg.APIKey, cfg.APISecret, userID, grant, cfg.TokenTTL)
+	if err != nil {
+		return domain.SFUToken{}, err
+	}
+	commonlog.Infof("event=chat_sfu_token action=issue status=ok tenant_id=%s room_id=%s call_id=%s user_id=%s ttl_sec=%d", tenantID, roomID, call.ID, userID, int(cfg.TokenTTL.Seconds()))
+	return domain.SFUToken{
+		URL:       cfg.URL,
+		Token:     token,
+		Room:      grant.Room,
+		Identity:  userID,
+		ExpiresAt: expiresAt.UTC(),
+	}, nil
+}
+
+func sfuRoomName(tenantID, callID string) string {
+	return tenantID + ":call:" + callID
+}
+
+func (s *CallService) StartCall(ctx context.Context, tenantID, roomID, callerID, media string) (domain.Call, error) {
+	media = strings.ToLower(strings.TrimSpace(media))
+	if media == "" {
+		media = "audio"
+	}
+	if media != "audio" && media != "video" {
+		return domain.Call{}, ErrInvalidCallMedia
+	}
+	req := domain.Call{TenantID: tenantID, RoomID: roomID, CallerID: callerID, Media: media}
+	call, created, err := s.chat.dbman.CreateCall(ctx, req)
+	if err != nil {
+		return domain.Call{}, err
+	}
+	// A ringing call can outlive its timer when the instance that started it
+	// restarts; expire it here instead of blocking the room.
+	if !created && call.Status == domain.CallStatusRinging && time.Since(call.CreatedAt) > s.ringTimeout {
+		s.expire(ctx, tenantID, roomID, call.ID)
+		call, created, err = s.chat.dbman.CreateCall(ctx, req)
+		if err != nil {
+			return domain.Call{}, err
+		}
+	}
+	if !created {
+		return call, ErrCallInProgress
+	}
+
+	s.publish(ctx, call, callerID, "call.ringing")
+	s.ring(ctx, call)
+	time.AfterFunc(s.ringTimeout, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s.expire(ctx, tenantID, roomID, call.ID)
+	})
+	return call, nil
+}
+
+// AnswerCall accepts or declines an incoming call for userID.
+func (s *CallService) AnswerCall(ctx context.Context, tenantID, roomID, callID, userID string, accept bool) (domain.Call, error) {
+	action := domain.CallActionAnswer
+	if !accept {
+		action = domain.CallActionDecline
+	}
+	return s.transition(ctx, tenantID, roomID, callID, userID, action)
+}
+
+func (s *CallService) HangupCall(ctx context.Context, tenantID, roomID, callID, userID string) (domain.Call, error) {
+	return s.transition(ctx, tenantID, roomID, callID, userID, domain.CallActionHangup)
+}
+
+func (s *CallService) GetCall(ctx context.Context, tenantID, roomID, callID string) (domain.Call, error) {
+	call, ok, err := s.chat.dbman.GetCall(ctx, tenantID, roomID, callID)
+	if err != nil {
+		return domain.Call{}, err
+	}
+	if !ok {
+		return domain.Call{}, ErrCallNotFound
+	}
+	return call, nil
+}
+
+func (s *CallService) ListCalls(ctx context.Context, tenantID, roomID string, limit int, cursor string) ([]domain.Call, string, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 30
+	}
+	var cursorCreatedAt *time.Time
+	var cursorID *string
+	if strings.TrimSpace(cursor) != "" {
+		createdAt, callID, err := decodeRoomCursor(cursor)
+		if err != nil {
+			return nil, "", errors.New("cursor is invalid")
+		}
+		cursorCreatedAt = &createdAt
+		cursorID = &callID
+	}
+	items, err := s.chat.dbman.ListCalls(ctx, tenantID, roomID, limit+1, cursorCreatedAt, cursorID)
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		nextCursor = encodeRoomCursor(last.CreatedAt.UTC(), last.ID)
+	}
+	return items, nextCursor, nil
+}
+
+func (s *CallService) transition(ctx context.Context, tenantID, roomID, callID, userID string, action domain.CallAction) (domain.Call, error) {
+	if _, err := s.GetCall(ctx, tenantID, roomID, callID); err != nil {
+		return domain.Call{}, err
+	}
+	call, changed, err := s.chat.dbman.TransitionCall(ctx, tenantID, roomID, callID, userID, action)
+	if err != nil {
+		return domain.Call{}, err
+	}
+	if !changed {
+		return call, ErrCallInvalidState
+	}
+	s.afterTransition(ctx, call, userID)
+	s.stopRinging(ctx, call, userID, action)
+	return call, nil
+}
+
+func (s *CallService) expire(ctx context.Context, tenantID, roomID, callID string) {
+	call, changed, err := s.chat.dbman.TransitionCall(ctx, tenantID, roomID, callID, "", domain.CallActionExpire)
+	if err != nil {
+		commonlog.Errorf("event=chat_call action=expire status=failed tenant_id=%s room_id=%s call_id=%s error=%v", tenantID, roomID, callID, err)
+		return
+	}
+	if changed {
+		s.afterTransition(ctx, call, call.CallerID)
+		s.stopRinging(ctx, call, call.CallerID, domain.CallActionExpire)
+	}
+}
+
+// RunRingSweeper expires calls left ringing past the ring timeout whose timer
+// was lost to a restart or drain. Every replica may run it; the transition is
+// applied once under a row lock, so only one of them posts the missed call.
+func (s *CallService) RunRingSweeper(ctx context.Context) {
+	ticker := time.NewTicker(callSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tenants, err := s.chat.dbman.ListTenants(ctx)
+		if err != nil {
+			commonlog.Warnf("event=chat_call action=sweep_list_tenants status=failed error=%v", err)
+			continue
+		}
+		for _, tenant := range tenants {
+			if ctx.Err() != nil {
+				return
+			}
+			if tenant.IsActive {
+				s.sweepTenant(ctx, tenant.TenantID)
+			}
+		}
+	}
+}
+
+func (s *CallService) sweepTenant(ctx context.Context, tenantID string) {
+	calls, err := s.chat.dbman.ListExpiredCalls(ctx, tenantID, time.Now().Add(-s.ringTimeout), callSweepBatch)
+	if err != nil {
+		commonlog.Warnf("event=chat_call action=sweep status=failed tenant_id=%s error=%v", tenantID, err)
+		return
+	}
+	for _, call := range calls {
+		s.expire(ctx, tenantID, call.RoomID, call.ID)
+	}
+}
+
+func (s *CallService) afterTransition(ctx context.Context, call domain.Call, userID string) {
+	eventType := "call.updated"
+	if call.Status.Terminal() {
+		eventType = "call." + string(call.Status)
+	}
+	s.publish(ctx, call, userID, eventType)
+	if call.Status == domain.CallStatusMissed {
+		s.postMissedCallMessage(ctx, call)
+	}
+}
+
+// ring sends call.incoming to every invitee's devices.
+func (s *CallService) ring(ctx context.Context, call domain.Call) {
+	s.notifySession(ctx, call, call.CallerID, "call.incoming", "", participantsIn(call, domain.CallParticipantInvited))
+}
+
+// stopRinging silences devices that are still ringing. The user who answered
+// or declined gets call.cancelled so their other devices stop; once the call
+// is over, invitees who never answered get call.missed.
+func (s *CallService) stopRinging(ctx context.Context, call domain.Call, userID string, action domain.CallAction) {
+	switch action {
+	case domain.CallActionAnswer:
+		s.notifySession(ctx, call, userID, "call.cancelled", "answered", []string{userID})
+	case domain.CallActionDecline:
+		s.notifySession(ctx, call, userID, "call.cancelled", "declined", []string{userID})
+	}
+	if call.Status.Terminal() {
+		s.notifySession(ctx, call, userID, "call.missed", string(call.Status), participantsIn(call, domain.CallParticipantMissed))
+	}
+}
+
+func (s *CallService) notifySession(ctx context.Context, call domain.Call, actorID, event, reason string, recipients []string) {
+	if s.session == nil || len(recipients) == 0 {
+		return
+	}
+	if err := s.session.NotifyCall(ctx, call.TenantID, actorID, CallNotification{
+		RoomID:       call.RoomID,
+		CallID:       call.ID,
+		Event:        event,
+		Media:        call.Media,
+		CallerID:     call.CallerID,
+		Reason:       reason,
+		RecipientIDs: recipients,
+	}); err != nil {
+		commonlog.Errorf("event=chat_call action=session_notify status=failed tenant_id=%s room_id=%s call_id=%s notify=%s error=%v", call.TenantID, call.RoomID, call.ID, event, err)
+	}
+}
+
+func participantsIn(call domain.Call, state domain.CallParticipantState) []string {
+	ids := make([]string, 0, len(call.Participants))
+	for _, p := range call.Participants {
+		if p.State == state {
+			ids = append(ids, p.UserID)
+		}
+	}
+	return ids
+}
+
+func (s *CallService) publish(ctx context.Context, call domain.Call, userID, eventType string) {
+	commonlog.Infof("event=chat_call action=%s status=ok tenant_id=%s room_id=%s call_id=%s user_id=%s call_status=%s", eventType, call.TenantID, call.RoomID, call.ID, userID, call.Status)
+	if err := s.ws.PublishEvent(ctx, call.TenantID, call.RoomID, userID, eventType, call); err != nil {
+		commonlog.Errorf("event=chat_call action=publish status=failed tenant_id=%s room_id=%s call_id=%s error=%v", call.TenantID, call.RoomID, call.ID, err)
+	}
+	if s.chat.IsMQEnabled() {
+		_ = s.chat.mq.Publish(ctx, call.TenantID, events.Call{
+			Event:       eventType,
+			CallID:      call.ID,
+			RoomID:      call.RoomID,
+			CallerID:    call.CallerID,
+			UserID:      userID,
+			Media:       call.Media,
+			Status:      string(call.Status),
+			StartedAt:   call.StartedAt,
+			EndedAt:     call.EndedAt,
+			DurationSec: call.DurationSec,
+		})
+	}
+}
+
+// postMissedCallMessage records the missed call in the room as the caller. The
+// participants' devices already got call.missed from session, so the message
+// only reaches the room's connections.
+func (s *CallService) postMissedCallMessage(ctx context.Context, call domain.Call) {
+	meta, _ := json.Marshal(map[string]any{"system": "call.missed", "call_id": call.ID, "media": call.Media})
+	msg, err := s.chat.CreateSystemMessage(ctx, domain.Message{
+		TenantID: call.TenantID,
+		RoomID:   call.RoomID,
+		SenderID: call.CallerID,
+		Body:     missedCallBody,
+		MetaJSON: string(meta),
+	})
+	if err != nil {
+		commonlog.Errorf("event=chat_call action=missed_message status=failed tenant_id=%s room_id=%s call_id=%s error=%v", call.TenantID, call.RoomID, call.ID, err)
+		return
+	}
+	_ = s.ws.PublishMessage(ctx, call.TenantID, call.RoomID, call.CallerID, msg)
+}
@@ -9,78 +9,603 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"msg_server/server/chat/domain"
+	"msg_server/server/common/events"
+	"msg_server/server/common/infra/mq"
 )
 
+var (
+	ErrMessageNotFound     = errors.New("message not found")
+	ErrMessageForbidden    = errors.New("only the sender can modify this message")
+	ErrMessageBodyRequired = errors.New("body required")
+	ErrInvalidThreadParent = errors.New("parent message not found or is itself a reply")
+	ErrInvalidReaction     = errors.New("emoji is required and must be at most 64 bytes")
+	ErrInvalidSyncToken    = errors.New("sync token is invalid")
+	ErrMemberBanned        = errors.New("user is banned from this room")
+	ErrMemberNotFound      = errors.New("user is not a member of this room")
+	ErrRoomNotFound        = errors.New("room not found")
+	ErrRoomArchived        = errors.New("room is archived")
+	ErrInvalidRoomName     = errors.New("name is required and must be at most 100 characters")
+	ErrDirectRoom          = errors.New("not supported for direct rooms")
+	ErrRoomPermission      = errors.New("your room role does not allow this")
+	ErrInvalidRoomRole     = errors.New("role must be admin, member or read_only")
+	ErrRoomOwner           = errors.New("the room owner cannot be removed or demoted; transfer ownership first")
+	ErrOwnerMustTransfer   = errors.New("transfer ownership before leaving the room")
+	ErrMessageNotPinned    = errors.New("message is not pinned")
+)
+
+const maxRoomNameLength = 100
+
+// MessageHook runs before a message is created or edited, in the order the
+// hooks were added. It may rewrite msg.Body or reject the message with an
+// error, and returns the names of rules that flagged it; dbman queues a
+// flagged message for review in the transaction that stores it.
+type MessageHook interface {
+	BeforeStoreMessage(ctx context.Context, msg *domain.Message) (flagged []string, err error)
+}
+
 type ChatService struct {
-	mq     *AMQPPublisher
+	mq     *mq.AMQPPublisher
 	dbman  *DBManClient
 	vector *VectormanClient
 	useMQ  bool
+	hooks  []MessageHook
+}
+
+func NewChatService(publisher *mq.AMQPPublisher, dbman *DBManClient, vector *VectormanClient, useMQ bool) *ChatService {
+	return &ChatService{mq: publisher, dbman: dbman, vector: vector, useMQ: useMQ}
+}
+
+func (s *ChatService) UseMessageHooks(hooks ...MessageHook) {
+	s.hooks = append(s.hooks, hooks...)
 }
 
-func NewChatService(mq *AMQPPublisher, dbman *DBManClient, vector *VectormanClient, useMQ bool) *ChatService {
-	return &ChatService{mq: mq, dbman: dbman, vector: vector, useMQ: useMQ}
+func (s *ChatService) runMessageHooks(ctx context.Context, msg *domain.Message) ([]string, error) {
+	var flagged []string
+	for _, hook := range s.hooks {
+		rules, err := hook.BeforeStoreMessage(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, rules...)
+	}
+	return flagged, nil
 }
 
 func (s *ChatService) IsMQEnabled() bool {
 	return s.useMQ && s.mq != nil
 }
 
+// MQHealth reports the publisher's broker connections; ok is false when MQ is
+// disabled.
+func (s *ChatService) MQHealth() (mq.PublisherHealth, bool) {
+	if !s.IsMQEnabled() {
+		return mq.PublisherHealth{}, false
+	}
+	return s.mq.Health(), true
+}
+
 func (s *ChatService) CreateRoom(ctx context.Context, tenantID string, room domain.ChatRoom, memberIDs []string) (string, error) {
-	return s.dbman.CreateRoom(ctx, tenantID, room, memberIDs)
+	id, err := s.dbman.CreateRoom(ctx, tenantID, room, memberIDs)
+	if err != nil {
+		return "", err
+	}
+	room.ID = id
+	data := roomEventData(room)
+	data.MemberIDs = append([]string{room.CreatedBy}, memberIDs...)
+	s.publishEvent(ctx, tenantID, events.RoomCreated{Room: data})
+	return id, nil
+}
+
+// AddMember adds the user with role, member by default, and reports whether
+// they joined; adding an existing member is a no-op and keeps their role.
+// Only members who can manage the room may add admins, and direct rooms keep
+// their two participants.
+func (s *ChatService) AddMember(ctx context.Context, tenantID, roomID string, actorRole domain.RoomRole, userID string, role domain.RoomRole) (bool, error) {
+	if role == "" {
+		role = domain.RoomRoleMember
+	}
+	if !role.Valid() || role == domain.RoomRoleOwner {
+		return false, ErrInvalidRoomRole
+	}
+	if !actorRole.Can(domain.RoomPermInvite) || (role == domain.RoomRoleAdmin && !actorRole.Can(domain.RoomPermManage)) {
+		return false, ErrRoomPermission
+	}
+	room, err := s.GetRoom(ctx, tenantID, roomID)
+	if err != nil {
+		return false, err
+	}
+	if room.RoomType == "direct" {
+		return false, ErrDirectRoom
+	}
+	added, ok, err := s.dbman.AddMember(ctx, tenantID, roomID, userID, role)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, ErrMemberBanned
+	}
+	if added {
+		s.publishEvent(ctx, tenantID, events.MemberJoined{Member: events.Member{
+			RoomID: roomID, UserID: userID, Action: domain.MembershipJoined, Role: string(role),
+		}})
+	}
+	return added, nil
+}
+
+// LeaveRoom removes the user from the room. The owner of a group room has to
+// transfer ownership first unless they are the last member.
+func (s *ChatService) LeaveRoom(ctx context.Context, tenantID, roomID, userID string) error {
+	member, ok, err := s.dbman.GetRoomMember(ctx, tenantID, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMemberNotFound
+	}
+	if member.Role == domain.RoomRoleOwner {
+		room, err := s.GetRoom(ctx, tenantID, roomID)
+		if err != nil {
+			return err
+		}
+		memberIDs, err := s.dbman.ListRoomMemberIDs(ctx, tenantID, roomID)
+		if err != nil {
+			return err
+		}
+		if room.RoomType != "direct" && len(memberIDs) > 1 {
+			return ErrOwnerMustTransfer
+		}
+	}
+	return s.removeMember(ctx, tenantID, roomID, userID, domain.MembershipLeft)
 }
 
-func (s *ChatService) AddMember(ctx context.Context, tenantID, roomID, userID string) error {
-	return s.dbman.AddMember(ctx, tenantID, roomID, userID)
+// RemoveMember removes someone else from the room. The actor must outrank
+// them, so admins can remove members but only the owner can remove admins.
+// Nobody can be removed from a direct room.
+func (s *ChatService) RemoveMember(ctx context.Context, tenantID, roomID string, actorRole domain.RoomRole, userID string) error {
+	if !actorRole.Can(domain.RoomPermRemove) {
+		return ErrRoomPermission
+	}
+	room, err := s.GetRoom(ctx, tenantID, roomID)
+	if err != nil {
+		return err
+	}
+	if room.RoomType == "direct" {
+		return ErrDirectRoom
+	}
+	target, ok, err := s.dbman.GetRoomMember(ctx, tenantID, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMemberNotFound
+	}
+	if target.Role == domain.RoomRoleOwner {
+		return ErrRoomOwner
+	}
+	if !actorRole.Outranks(target.Role) {
+		return ErrRoomPermission
+	}
+	return s.removeMember(ctx, tenantID, roomID, userID, domain.MembershipRemoved)
+}
+
+func (s *ChatService) removeMember(ctx context.Context, tenantID, roomID, userID, action string) error {
+	ok, err := s.dbman.RemoveMember(ctx, tenantID, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMemberNotFound
+	}
+	s.publishEvent(ctx, tenantID, events.MemberLeft{Member: events.Member{RoomID: roomID, UserID: userID, Action: action}})
+	return nil
+}
+
+// SetMemberRole changes another member's role. Ownership only changes hands
+// through TransferOwnership, and roles in direct rooms are fixed.
+func (s *ChatService) SetMemberRole(ctx context.Context, tenantID, roomID string, actorRole domain.RoomRole, userID string, role domain.RoomRole) (domain.RoomMember, error) {
+	if !role.Valid() || role == domain.RoomRoleOwner {
+		return domain.RoomMember{}, ErrInvalidRoomRole
+	}
+	if !actorRole.Can(domain.RoomPermManage) {
+		return domain.RoomMember{}, ErrRoomPermission
+	}
+	room, err := s.GetRoom(ctx, tenantID, roomID)
+	if err != nil {
+		return domain.RoomMember{}, err
+	}
+	if room.RoomType == "direct" {
+		return domain.RoomMember{}, ErrDirectRoom
+	}
+	target, ok, err := s.dbman.GetRoomMember(ctx, tenantID, roomID, userID)
+	if err != nil {
+		return domain.RoomMember{}, err
+	}
+	if !ok {
+		return domain.RoomMember{}, ErrMemberNotFound
+	}
+	if target.Role == domain.RoomRoleOwner {
+		return domain.RoomMember{}, ErrRoomOwner
+	}
+	member, ok, err := s.dbman.SetMemberRole(ctx, tenantID, roomID, userID, role)
+	if err != nil {
+		return domain.RoomMember{}, err
+	}
+	if !ok {
+		return domain.RoomMember{}, ErrMemberNotFound
+	}
+	s.publishRoleChanged(ctx, tenantID, member)
+	return member, nil
+}
+
+// TransferOwnership hands the room to another member; the previous owner
+// stays on as an admin. It returns the members whose role changed.
+func (s *ChatService) TransferOwnership(ctx context.Context, tenantID, roomID string, actorRole domain.RoomRole, userID string) ([]domain.RoomMember, error) {
+	if actorRole != domain.RoomRoleOwner {
+		return nil, ErrRoomPermission
+	}
+	changed, ok, err := s.dbman.TransferOwnership(ctx, tenantID, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+	for _, member := range changed {
+		s.publishRoleChanged(ctx, tenantID, member)
+	}
+	return changed, nil
+}
+
+func (s *ChatService) GetRoom(ctx context.Context, tenantID, roomID string) (domain.ChatRoom, error) {
+	room, ok, err := s.dbman.GetRoom(ctx, tenantID, roomID)
+	if err != nil {
+		return domain.ChatRoom{}, err
+	}
+	if !ok {
+		return domain.ChatRoom{}, ErrRoomNotFound
+	}
+	return room, nil
+}
+
+func (s *ChatService) RenameRoom(ctx context.Context, tenantID, roomID, name string) (domain.ChatRoom, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxRoomNameLength {
+		return domain.ChatRoom{}, ErrInvalidRoomName
+	}
+	room, err := s.GetRoom(ctx, tenantID, roomID)
+	if err != nil {
+		return domain.ChatRoom{}, err
+	}
+	if room.RoomType == "direct" {
+		return domain.ChatRoom{}, ErrDirectRoom
+	}
+	room, ok, err := s.dbman.RenameRoom(ctx, tenantID, roomID, name)
+	if err != nil {
+		return domain.ChatRoom{}, err
+	}
+	if !ok {
+		return domain.ChatRoom{}, ErrRoomNotFound
+	}
+	s.publishEvent(ctx, tenantID, events.RoomUpdated{Room: roomEventData(room)})
+	return room, nil
+}
+
+// SetRoomArchived archives or restores a room. Archived rooms stay readable
+// but reject new messages.
+func (s *ChatService) SetRoomArchived(ctx context.Context, tenantID, roomID string, archived bool) (domain.ChatRoom, error) {
+	room, ok, err := s.dbman.SetRoomArchived(ctx, tenantID, roomID, archived)
+	if err != nil {
+		return domain.ChatRoom{}, err
+	}
+	if !ok {
+		return domain.ChatRoom{}, ErrRoomNotFound
+	}
+	s.publishEvent(ctx, tenantID, events.RoomUpdated{Room: roomEventData(room)})
+	return room, nil
+}
+
+// DeleteRoom removes the room with its messages and returns the ids of the
+// former members.
+func (s *ChatService) DeleteRoom(ctx context.Context, tenantID, roomID string) ([]string, error) {
+	memberIDs, ok, err := s.dbman.DeleteRoom(ctx, tenantID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	s.publishEvent(ctx, tenantID, events.RoomDeleted{RoomID: roomID, MemberIDs: memberIDs})
+	return memberIDs, nil
+}
+
+// publishEvent hands a room or membership event to chat.events for webhooks
+// and other consumers. Like reactions it is best effort: the change is
+// already committed and the realtime frame goes out separately.
+func (s *ChatService) publishEvent(ctx context.Context, tenantID string, data events.Data) {
+	if s.IsMQEnabled() {
+		_ = s.mq.Publish(ctx, tenantID, data)
+	}
+}
+
+func (s *ChatService) publishRoleChanged(ctx context.Context, tenantID string, member domain.RoomMember) {
+	s.publishEvent(ctx, tenantID, events.MemberRoleChanged{Member: events.Member{
+		RoomID: member.RoomID, UserID: member.UserID, Role: string(member.Role),
+	}})
+}
+
+func roomEventData(room domain.ChatRoom) events.Room {
+	return events.Room{
+		RoomID:     room.ID,
+		Name:       room.Name,
+		RoomType:   room.RoomType,
+		CreatedBy:  room.CreatedBy,
+		ArchivedAt: room.ArchivedAt,
+	}
 }
 
 func (s *ChatService) IsRoomMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
 	return s.dbman.IsRoomMember(ctx, tenantID, roomID, userID)
 }
 
+func (s *ChatService) GetRoomMember(ctx context.Context, tenantID, roomID, userID string) (domain.RoomMember, bool, error) {
+	return s.dbman.GetRoomMember(ctx, tenantID, roomID, userID)
+}
+
+func (s *ChatService) ListMemberRoomIDs(ctx context.Context, tenantID, userID string) ([]string, error) {
+	return s.dbman.ListMemberRoomIDs(ctx, tenantID, userID)
+}
+
 func (s *ChatService) CreateMessage(ctx context.Context, msg domain.Message) (domain.Message, error) {
 	if msg.MetaJSON == "" {
 		msg.MetaJSON = "{}"
 	}
-	created, err := s.dbman.CreateMessage(ctx, msg)
+	if msg.ParentMessageID != nil {
+		parentID := strings.TrimSpace(*msg.ParentMessageID)
+		if parentID == "" {
+			msg.ParentMessageID = nil
+		} else {
+			parent, ok, err := s.dbman.GetMessage(ctx, msg.TenantID, msg.RoomID, parentID)
+			if err != nil {
+				return domain.Message{}, err
+			}
+			if !ok || parent.DeletedAt != nil || parent.ParentMessageID != nil {
+				return domain.Message{}, ErrInvalidThreadParent
+			}
+			msg.ParentMessageID = &parentID
+		}
+	}
+	flagged, err := s.runMessageHooks(ctx, &msg)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	// dbman marks the message read for the sender, queues message.created in
+	// its outbox and stores any moderation flag within the same transaction.
+	created, ok, err := s.dbman.CreateMessage(ctx, msg, flagged)
 	if err != nil {
 		return created, err
 	}
-
-	event := map[string]any{
-		"event":      "message.created",
-		"message_id": created.ID,
-		"room_id":    created.RoomID,
-		"sender_id":  created.SenderID,
-		"body":       created.Body,
-		"created_at": created.CreatedAt,
+	if !ok {
+		return domain.Message{}, ErrRoomArchived
 	}
-	if s.IsMQEnabled() {
-		_ = s.mq.Publish(ctx, msg.TenantID, "message.created", event)
+	if !s.IsMQEnabled() {
+		_ = s.vector.IndexMessage(ctx, created.ID, created.RoomID, created.Body)
 	}
-	_ = s.vector.IndexMessage(ctx, created.ID, created.RoomID, created.Body)
-	_ = s.dbman.MarkReadUpTo(ctx, msg.TenantID, created.RoomID, created.SenderID, created.ID)
+	return created, nil
+}
 
+// CreateSystemMessage posts a message on the server's behalf, such as a missed
+// call. It skips the message hooks and the archived check, and chatworker
+// sends no push notification for it.
+func (s *ChatService) CreateSystemMessage(ctx context.Context, msg domain.Message) (domain.Message, error) {
+	if msg.MetaJSON == "" {
+		msg.MetaJSON = "{}"
+	}
+	created, err := s.dbman.CreateSystemMessage(ctx, msg)
+	if err != nil {
+		return created, err
+	}
+	if !s.IsMQEnabled() {
+		_ = s.vector.IndexMessage(ctx, created.ID, created.RoomID, created.Body)
+	}
 	return created, nil
 }
 
-func (s *ChatService) ListMessages(ctx context.Context, tenantID, roomID string, limit int, cursor string) ([]domain.Message, string, error) {
+func (s *ChatService) UpdateMessage(ctx context.Context, tenantID, roomID, messageID, actorID, body string) (domain.Message, error) {
+	if strings.TrimSpace(body) == "" {
+		return domain.Message{}, ErrMessageBodyRequired
+	}
+	if err := s.checkMessageOwner(ctx, tenantID, roomID, messageID, actorID); err != nil {
+		return domain.Message{}, err
+	}
+	msg := domain.Message{TenantID: tenantID, ID: messageID, RoomID: roomID, SenderID: actorID, Body: body}
+	flagged, err := s.runMessageHooks(ctx, &msg)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	updated, err := s.dbman.UpdateMessage(ctx, tenantID, roomID, messageID, actorID, msg.Body, flagged)
+	if err != nil {
+		return updated, err
+	}
+
+	if !s.IsMQEnabled() {
+		_ = s.vector.IndexMessage(ctx, updated.ID, updated.RoomID, updated.Body)
+	}
+	return updated, nil
+}
+
+// DeleteMessage lets the sender delete their own message, and members whose
+// role has the delete_others permission anyone's.
+func (s *ChatService) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID string, actorRole domain.RoomRole) (domain.Message, error) {
+	if actorRole.Can(domain.RoomPermDeleteOthers) {
+		existing, ok, err := s.dbman.GetMessage(ctx, tenantID, roomID, messageID)
+		if err != nil {
+			return domain.Message{}, err
+		}
+		if !ok || existing.DeletedAt != nil {
+			return domain.Message{}, ErrMessageNotFound
+		}
+	} else if err := s.checkMessageOwner(ctx, tenantID, roomID, messageID, actorID); err != nil {
+		return domain.Message{}, err
+	}
+	deleted, err := s.dbman.DeleteMessage(ctx, tenantID, roomID, messageID, actorID)
+	if err != nil {
+		return deleted, err
+	}
+
+	if !s.IsMQEnabled() {
+		_ = s.vector.DeleteMessage(ctx, deleted.ID)
+	}
+	return deleted, nil
+}
+
+// ListMessageRevisions returns ErrMessageNotFound once the message is
+// deleted; its edit history goes with it.
+func (s *ChatService) ListMessageRevisions(ctx context.Context, tenantID, roomID, messageID string) ([]domain.MessageRevision, error) {
+	existing, ok, err := s.dbman.GetMessage(ctx, tenantID, roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || existing.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+	return s.dbman.ListMessageRevisions(ctx, tenantID, roomID, messageID)
+}
+
+// PinMessage pins a live message to the top of its room. Pinning an already
+// pinned message returns the existing pin.
+func (s *ChatService) PinMessage(ctx context.Context, tenantID, roomID, messageID, userID string) (domain.MessagePin, error) {
+	return s.dbman.PinMessage(ctx, tenantID, roomID, messageID, userID)
+}
+
+func (s *ChatService) UnpinMessage(ctx context.Context, tenantID, roomID, messageID string) error {
+	changed, err := s.dbman.UnpinMessage(ctx, tenantID, roomID, messageID)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrMessageNotPinned
+	}
+	return nil
+}
+
+func (s *ChatService) ListPinnedMessages(ctx context.Context, tenantID, roomID string, limit int) ([]domain.MessagePin, error) {
+	return s.dbman.ListPinnedMessages(ctx, tenantID, roomID, limit)
+}
+
+func (s *ChatService) AddReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	emoji, err := s.checkReactionTarget(ctx, tenantID, roomID, messageID, emoji)
+	if err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	item, changed, err := s.dbman.AddReaction(ctx, tenantID, roomID, messageID, userID, emoji)
+	if err != nil {
+		return item, false, err
+	}
+	if changed && s.IsMQEnabled() {
+		_ = s.mq.Publish(ctx, tenantID, events.ReactionAdded{Reaction: reactionEventData(item)})
+	}
+	return item, changed, nil
+}
+
+func (s *ChatService) RemoveReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	emoji, err := s.checkReactionTarget(ctx, tenantID, roomID, messageID, emoji)
+	if err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	item, changed, err := s.dbman.RemoveReaction(ctx, tenantID, roomID, messageID, userID, emoji)
+	if err != nil {
+		return item, false, err
+	}
+	if changed && s.IsMQEnabled() {
+		_ = s.mq.Publish(ctx, tenantID, events.ReactionRemoved{Reaction: reactionEventData(item)})
+	}
+	return item, changed, nil
+}
+
+func (s *ChatService) checkReactionTarget(ctx context.Context, tenantID, roomID, messageID, emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || len(emoji) > 64 {
+		return "", ErrInvalidReaction
+	}
+	existing, ok, err := s.dbman.GetMessage(ctx, tenantID, roomID, messageID)
+	if err != nil {
+		return "", err
+	}
+	if !ok || existing.DeletedAt != nil {
+		return "", ErrMessageNotFound
+	}
+	return emoji, nil
+}
+
+func reactionEventData(item domain.ReactionEvent) events.Reaction {
+	return events.Reaction{
+		MessageID: item.MessageID,
+		RoomID:    item.RoomID,
+		UserID:    item.UserID,
+		Emoji:     item.Emoji,
+		Count:     item.Count,
+	}
+}
+
+func (s *ChatService) checkMessageOwner(ctx context.Context, tenantID, roomID, messageID, actorID string) error {
+	existing, ok, err := s.dbman.GetMessage(ctx, tenantID, roomID, messageID)
+	if err != nil {
+		return err
+	}
+	if !ok || existing.DeletedAt != nil {
+		return ErrMessageNotFound
+	}
+	if existing.SenderID != actorID {
+		return ErrMessageForbidden
+	}
+	return nil
+}
+
+func (s *ChatService) ListMessages(ctx context.Context, tenantID, roomID, userID string, limit int, cursor string) ([]domain.Message, string, error) {
 	if limit <= 0 || limit > 200 {
 		limit = 50
 	}
 
-	var cursorID *string
+	var cursorSeq *int64
+	if strings.TrimSpace(cursor) != "" {
+		parsed, err := decodeMessageCursor(cursor)
+		if err != nil {
+			return nil, "", errors.New("cursor is invalid")
+		}
+		cursorSeq = &parsed
+	}
+
+	items, err := s.dbman.ListMessages(ctx, tenantID, roomID, userID, limit+1, cursorSeq)
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		nextCursor = encodeMessageCursor(last.Seq)
+	}
+	return items, nextCursor, nil
+}
+
+func (s *ChatService) ListThread(ctx context.Context, tenantID, roomID, parentMessageID, userID string, limit int, cursor string) ([]domain.Message, string, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var cursorSeq *int64
 	if strings.TrimSpace(cursor) != "" {
 		parsed, err := decodeMessageCursor(cursor)
 		if err != nil {
 			return nil, "", errors.New("cursor is invalid")
 		}
-		cursorID = &parsed
+		cursorSeq = &parsed
 	}
 
-	items, err := s.dbman.ListMessages(ctx, tenantID, roomID, limit+1, cursorID)
+	items, err := s.dbman.ListThreadReplies(ctx, tenantID, roomID, parentMessageID, userID, limit+1, cursorSeq)
 	if err != nil {
 		return nil, "", err
 	}
@@ -88,26 +613,32 @@ func (s *ChatService) ListMessages(ctx context.Context, tenantID, roomID string,
 	if len(items) > limit {
 		items = items[:limit]
 		last := items[len(items)-1]
-		nextCursor = encodeMessageCursor(last.ID)
+		nextCursor = encodeMessageCursor(last.Seq)
 	}
 	return items, nextCursor, nil
 }
 
-func (s *ChatService) SearchMessages(ctx context.Context, tenantID string, q string, roomID *string, limit int, cursor string) ([]domain.Message, string, error) {
+func (s *ChatService) ThreadSummary(ctx context.Context, tenantID, roomID, parentMessageID string) (domain.ThreadSummary, error) {
+	return s.dbman.GetThreadSummary(ctx, tenantID, roomID, parentMessageID)
+}
+
+func (s *ChatService) SearchMessages(ctx context.Context, tenantID, userID string, q string, roomID *string, limit int, cursor string) ([]domain.Message, string, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 30
 	}
 
+	var cursorCreatedAt *time.Time
 	var cursorID *string
 	if strings.TrimSpace(cursor) != "" {
-		parsed, err := decodeMessageCursor(cursor)
+		createdAt, messageID, err := decodeSearchCursor(cursor)
 		if err != nil {
 			return nil, "", errors.New("cursor is invalid")
 		}
-		cursorID = &parsed
+		cursorCreatedAt = &createdAt
+		cursorID = &messageID
 	}
 
-	items, err := s.dbman.SearchMessages(ctx, tenantID, q, roomID, limit+1, cursorID)
+	items, err := s.dbman.SearchMessages(ctx, tenantID, userID, q, roomID, limit+1, cursorCreatedAt, cursorID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -116,7 +647,7 @@ func (s *ChatService) SearchMessages(ctx context.Context, tenantID string, q str
 	if len(items) > limit {
 		items = items[:limit]
 		last := items[len(items)-1]
-		nextCursor = encodeMessageCursor(last.ID)
+		nextCursor = encodeSearchCursor(last.CreatedAt, last.ID)
 	}
 
 	if cursorID != nil {
@@ -232,20 +763,88 @@ func (s *ChatService) ListMyRooms(ctx context.Context, tenantID, userID string,
 	return items, nextCursor, nil
 }
 
-func encodeMessageCursor(messageID string) string {
-	return base64.RawURLEncoding.EncodeToString([]byte(messageID))
+// Sync returns the changes visible to userID since the given token. An empty
+// token returns no changes, only the current position to sync from.
+func (s *ChatService) Sync(ctx context.Context, tenantID, userID, since string, limit int) (domain.SyncBatch, string, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+	var sincePos *domain.SyncCursor
+	if strings.TrimSpace(since) != "" {
+		parsed, err := decodeSyncToken(since)
+		if err != nil {
+			return domain.SyncBatch{}, "", ErrInvalidSyncToken
+		}
+		sincePos = &parsed
+	}
+	batch, err := s.dbman.ListSyncChanges(ctx, tenantID, userID, sincePos, limit)
+	if err != nil {
+		return domain.SyncBatch{}, "", err
+	}
+	return batch, encodeSyncToken(batch.Last), nil
+}
+
+func encodeSyncToken(pos domain.SyncCursor) string {
+	raw := "s2:" + strconv.FormatInt(pos.TxID, 10) + ":" + strconv.FormatInt(pos.ChangeID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSyncToken also accepts s1 tokens issued before the feed was ordered
+// by transaction; those rows all carry tx_id 0.
+func decodeSyncToken(token string) (domain.SyncCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return domain.SyncCursor{}, err
+	}
+	var txRaw, changeRaw string
+	if raw, ok := strings.CutPrefix(string(decoded), "s1:"); ok {
+		txRaw, changeRaw = "0", raw
+	} else if raw, ok := strings.CutPrefix(string(decoded), "s2:"); ok {
+		txRaw, changeRaw, ok = strings.Cut(raw, ":")
+		if !ok {
+			return domain.SyncCursor{}, errors.New("invalid sync token")
+		}
+	} else {
+		return domain.SyncCursor{}, errors.New("invalid sync token")
+	}
+	txID, err := strconv.ParseInt(txRaw, 10, 64)
+	if err != nil || txID < 0 {
+		return domain.SyncCursor{}, errors.New("invalid sync token")
+	}
+	changeID, err := strconv.ParseInt(changeRaw, 10, 64)
+	if err != nil || changeID < 0 {
+		return domain.SyncCursor{}, errors.New("invalid sync token")
+	}
+	return domain.SyncCursor{TxID: txID, ChangeID: changeID}, nil
+}
+
+func encodeMessageCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
 }
 
-func decodeMessageCursor(cursor string) (string, error) {
+func decodeMessageCursor(cursor string) (int64, error) {
 	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return "", err
+		return 0, err
+	}
+	seq, err := strconv.ParseInt(strings.TrimSpace(string(decoded)), 10, 64)
+	if err != nil {
+		return 0, err
 	}
-	messageID := strings.TrimSpace(string(decoded))
-	if messageID == "" {
-		return "", errors.New("invalid cursor")
+	if seq <= 0 {
+		return 0, errors.New("invalid cursor")
 	}
-	return messageID, nil
+	return seq, nil
+}
+
+// Search results span rooms, so their cursors reuse the room cursor layout
+// ("<unix nanos>:<id>") instead of a room sequence.
+func encodeSearchCursor(createdAt time.Time, messageID string) string {
+	return encodeRoomCursor(createdAt.UTC(), messageID)
+}
+
+func decodeSearchCursor(cursor string) (time.Time, string, error) {
+	return decodeRoomCursor(cursor)
 }
 
 func roomCursorTime(item domain.ChatRoomSummary) time.Time {
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/dbman"
+)
+
+// messageDB stands in for the dbman message endpoints with a single message.
+// Like a message deleted before revisions were dropped on delete, it keeps the
+// edit history after the delete.
+type messageDB struct {
+	mu        sync.Mutex
+	message   domain.Message
+	revisions []domain.MessageRevision
+}
+
+func newMessageDB(t *testing.T) (*messageDB, *ChatService) {
+	t.Helper()
+	db := &messageDB{message: domain.Message{TenantID: "tenant-1", ID: "m1", RoomID: "room-1", SenderID: "user-1", Body: "original"}}
+	srv := httptest.NewServer(http.HandlerFunc(db.serve))
+	t.Cleanup(srv.Close)
+	return db, NewChatService(nil, NewDBManClient(srv.URL), NewVectormanClient("", false), false)
+}
+
+func (db *messageDB) serve(w http.ResponseWriter, r *http.Request) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var req struct {
+		MessageID string `json:"message_id"`
+		EditorID  string `json:"editor_id"`
+		Body      string `json:"body"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case dbman.BasePath + "/messages/get":
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": req.MessageID == db.message.ID, "message": db.message})
+	case dbman.BasePath + "/messages/update":
+		db.revisions = append(db.revisions, domain.MessageRevision{MessageID: db.message.ID, RoomID: db.message.RoomID, EditorID: req.EditorID, Body: db.message.Body})
+		now := time.Now()
+		db.message.Body, db.message.EditedAt = req.Body, &now
+		_ = json.NewEncoder(w).Encode(db.message)
+	case dbman.BasePath + "/messages/delete":
+		now := time.Now()
+		db.message.Body, db.message.DeletedAt = "", &now
+		_ = json.NewEncoder(w).Encode(db.message)
+	case dbman.BasePath + "/messages/revisions":
+		_ = json.NewEncoder(w).Encode(db.revisions)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestListMessageRevisionsAfterDelete(t *testing.T) {
+	_, chat := newMessageDB(t)
+	ctx := context.Background()
+
+	if _, err := chat.UpdateMessage(ctx, "tenant-1", "room-1", "m1", "user-1", "edited"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	items, err := chat.ListMessageRevisions(ctx, "tenant-1", "room-1", "m1")
+	if err != nil || len(items) != 1 || items[0].Body != "original" {
+		t.Fatalf("revisions before delete = %+v, %v", items, err)
+	}
+
+	if _, err := chat.DeleteMessage(ctx, "tenant-1", "room-1", "m1", "user-1", domain.RoomRoleMember); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	items, err = chat.ListMessageRevisions(ctx, "tenant-1", "room-1", "m1")
+	if !errors.Is(err, ErrMessageNotFound) || len(items) != 0 {
+		t.Fatalf("revisions after delete = %+v, %v; want ErrMessageNotFound", items, err)
+	}
+}
+
+func TestAddMemberRefusesDirectRoom(t *testing.T) {
+	var added atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case dbman.BasePath + "/rooms/get":
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "room": domain.ChatRoom{TenantID: "tenant-1", ID: "dm-1", RoomType: "direct"}})
+		case dbman.BasePath + "/rooms/members":
+			added.Store(true)
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "added": true})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	chat := NewChatService(nil, NewDBManClient(srv.URL), NewVectormanClient("", false), false)
+
+	_, err := chat.AddMember(context.Background(), "tenant-1", "dm-1", domain.RoomRoleOwner, "user-3", "")
+	if !errors.Is(err, ErrDirectRoom) {
+		t.Fatalf("err = %v, want ErrDirectRoom", err)
+	}
+	if added.Load() {
+		t.Error("third user was added to the direct room")
+	}
+}
+
+type rejectHook struct{}
+
+func (rejectHook) BeforeStoreMessage(context.Context, *domain.Message) ([]string, error) {
+	return nil, errors.New("blocked")
+}
+
+func TestCreateSystemMessageSkipsHooks(t *testing.T) {
+	var paths []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		var msg domain.Message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		msg.ID = "m1"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(msg)
+	}))
+	defer srv.Close()
+	chat := NewChatService(nil, NewDBManClient(srv.URL), NewVectormanClient("", false), false)
+	chat.UseMessageHooks(rejectHook{})
+
+	msg, err := chat.CreateSystemMessage(context.Background(), domain.Message{TenantID: "tenant-1", RoomID: "room-1", SenderID: "caller", Body: "missed call"})
+	if err != nil || msg.ID != "m1" {
+		t.Fatalf("create = %+v, %v", msg, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != dbman.BasePath+"/messages/system" {
+		t.Errorf("dbman calls = %v", paths)
+	}
+}
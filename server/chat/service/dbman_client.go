@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"strings"
 	"time"
 
 	"msg_server/server/chat/domain"
 	"msg_server/server/common/infra/cache"
 	commondbman "msg_server/server/common/infra/dbman"
+	"msg_server/server/common/infra/mq"
+	"msg_server/server/common/moderation"
 )
 
 type DBManClient struct {
@@ -33,10 +37,103 @@ func (c *DBManClient) CreateRoom(ctx context.Context, tenantID string, room doma
 	return resp.RoomID, nil
 }
 
-func (c *DBManClient) AddMember(ctx context.Context, tenantID, roomID, userID string) error {
+// AddMember reports ok=false when the user is banned from the room, and
+// added=false when they were already a member.
+func (c *DBManClient) AddMember(ctx context.Context, tenantID, roomID, userID string, role domain.RoomRole) (added bool, ok bool, err error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID, "role": role}
+	var resp struct {
+		OK    bool `json:"ok"`
+		Added bool `json:"added"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/members", payload, &resp); err != nil {
+		return false, false, err
+	}
+	return resp.Added, resp.OK, nil
+}
+
+// SetMemberRole reports false when the user is not a member or is the owner.
+func (c *DBManClient) SetMemberRole(ctx context.Context, tenantID, roomID, userID string, role domain.RoomRole) (domain.RoomMember, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID, "role": role}
+	var resp struct {
+		OK     bool              `json:"ok"`
+		Member domain.RoomMember `json:"member"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/members/role", payload, &resp); err != nil {
+		return domain.RoomMember{}, false, err
+	}
+	return resp.Member, resp.OK, nil
+}
+
+func (c *DBManClient) TransferOwnership(ctx context.Context, tenantID, roomID, userID string) ([]domain.RoomMember, bool, error) {
 	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID}
-	var resp map[string]any
-	return c.post(ctx, dbmanBasePath+"/rooms/members", payload, &resp)
+	var resp struct {
+		OK      bool                `json:"ok"`
+		Members []domain.RoomMember `json:"members"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/transfer-ownership", payload, &resp); err != nil {
+		return nil, false, err
+	}
+	return resp.Members, resp.OK, nil
+}
+
+// RemoveMember reports false when the user was not a member.
+func (c *DBManClient) RemoveMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID}
+	var resp struct {
+		OK bool `json:"ok"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/members/remove", payload, &resp); err != nil {
+		return false, err
+	}
+	return resp.OK, nil
+}
+
+func (c *DBManClient) GetRoom(ctx context.Context, tenantID, roomID string) (domain.ChatRoom, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID}
+	var resp struct {
+		OK   bool            `json:"ok"`
+		Room domain.ChatRoom `json:"room"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/get", payload, &resp); err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	return resp.Room, resp.OK, nil
+}
+
+func (c *DBManClient) RenameRoom(ctx context.Context, tenantID, roomID, name string) (domain.ChatRoom, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "name": name}
+	var resp struct {
+		OK   bool            `json:"ok"`
+		Room domain.ChatRoom `json:"room"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/rename", payload, &resp); err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	return resp.Room, resp.OK, nil
+}
+
+func (c *DBManClient) SetRoomArchived(ctx context.Context, tenantID, roomID string, archived bool) (domain.ChatRoom, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "archived": archived}
+	var resp struct {
+		OK   bool            `json:"ok"`
+		Room domain.ChatRoom `json:"room"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/archive", payload, &resp); err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	return resp.Room, resp.OK, nil
+}
+
+func (c *DBManClient) DeleteRoom(ctx context.Context, tenantID, roomID string) ([]string, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID}
+	var resp struct {
+		OK        bool     `json:"ok"`
+		MemberIDs []string `json:"member_ids"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/delete", payload, &resp); err != nil {
+		return nil, false, err
+	}
+	return resp.MemberIDs, resp.OK, nil
 }
 
 func (c *DBManClient) IsRoomMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
@@ -50,27 +147,172 @@ func (c *DBManClient) IsRoomMember(ctx context.Context, tenantID, roomID, userID
 	return resp.OK, nil
 }
 
-func (c *DBManClient) CreateMessage(ctx context.Context, msg domain.Message) (domain.Message, error) {
+func (c *DBManClient) GetRoomMember(ctx context.Context, tenantID, roomID, userID string) (domain.RoomMember, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID}
+	var resp struct {
+		OK     bool              `json:"ok"`
+		Member domain.RoomMember `json:"member"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rooms/members/get", payload, &resp); err != nil {
+		return domain.RoomMember{}, false, err
+	}
+	return resp.Member, resp.OK, nil
+}
+
+// CreateMessage reports false when the room is archived. A non-empty
+// flagRules queues the message for moderation review in the same transaction.
+func (c *DBManClient) CreateMessage(ctx context.Context, msg domain.Message, flagRules []string) (domain.Message, bool, error) {
+	payload := struct {
+		domain.Message
+		FlagRules []string `json:"flag_rules,omitempty"`
+	}{msg, flagRules}
+	var resp struct {
+		OK      bool           `json:"ok"`
+		Message domain.Message `json:"message"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/messages", payload, &resp); err != nil {
+		return domain.Message{}, false, err
+	}
+	return resp.Message, resp.OK, nil
+}
+
+// CreateSystemMessage stores a server-posted message; archived rooms accept it.
+func (c *DBManClient) CreateSystemMessage(ctx context.Context, msg domain.Message) (domain.Message, error) {
+	var out domain.Message
+	if err := c.post(ctx, dbmanBasePath+"/messages/system", msg, &out); err != nil {
+		return domain.Message{}, err
+	}
+	return out, nil
+}
+
+func (c *DBManClient) GetMessage(ctx context.Context, tenantID, roomID, messageID string) (domain.Message, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID}
+	var resp struct {
+		OK      bool           `json:"ok"`
+		Message domain.Message `json:"message"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/messages/get", payload, &resp); err != nil {
+		return domain.Message{}, false, err
+	}
+	return resp.Message, resp.OK, nil
+}
+
+func (c *DBManClient) UpdateMessage(ctx context.Context, tenantID, roomID, messageID, editorID, body string, flagRules []string) (domain.Message, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID, "editor_id": editorID, "body": body, "flag_rules": flagRules}
+	var out domain.Message
+	if err := c.post(ctx, dbmanBasePath+"/messages/update", payload, &out); err != nil {
+		return domain.Message{}, notFoundAs(err, ErrMessageNotFound)
+	}
+	return out, nil
+}
+
+func (c *DBManClient) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID string) (domain.Message, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID, "actor_id": actorID}
 	var out domain.Message
-	if err := c.post(ctx, dbmanBasePath+"/messages", msg, &out); err != nil {
-		return out, err
+	if err := c.post(ctx, dbmanBasePath+"/messages/delete", payload, &out); err != nil {
+		return domain.Message{}, notFoundAs(err, ErrMessageNotFound)
+	}
+	return out, nil
+}
+
+func (c *DBManClient) ListMessageRevisions(ctx context.Context, tenantID, roomID, messageID string) ([]domain.MessageRevision, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID}
+	var items []domain.MessageRevision
+	if err := c.post(ctx, dbmanBasePath+"/messages/revisions", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) PinMessage(ctx context.Context, tenantID, roomID, messageID, userID string) (domain.MessagePin, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID, "user_id": userID}
+	var out domain.MessagePin
+	if err := c.post(ctx, dbmanBasePath+"/messages/pin", payload, &out); err != nil {
+		return domain.MessagePin{}, notFoundAs(err, ErrMessageNotFound)
+	}
+	return out, nil
+}
+
+func (c *DBManClient) UnpinMessage(ctx context.Context, tenantID, roomID, messageID string) (bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID}
+	var out struct {
+		Changed bool `json:"changed"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/messages/unpin", payload, &out); err != nil {
+		return false, err
+	}
+	return out.Changed, nil
+}
+
+func (c *DBManClient) ListPinnedMessages(ctx context.Context, tenantID, roomID string, limit int) ([]domain.MessagePin, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "limit": limit}
+	var items []domain.MessagePin
+	if err := c.post(ctx, dbmanBasePath+"/messages/pins", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) ListThreadReplies(ctx context.Context, tenantID, roomID, parentMessageID, userID string, limit int, cursorSeq *int64) ([]domain.Message, error) {
+	payload := map[string]any{
+		"tenant_id":         tenantID,
+		"room_id":           roomID,
+		"parent_message_id": parentMessageID,
+		"user_id":           userID,
+		"limit":             limit,
+		"cursor_seq":        cursorSeq,
+	}
+	var items []domain.Message
+	if err := c.post(ctx, dbmanBasePath+"/messages/thread", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) GetThreadSummary(ctx context.Context, tenantID, roomID, parentMessageID string) (domain.ThreadSummary, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "parent_message_id": parentMessageID}
+	var out domain.ThreadSummary
+	if err := c.post(ctx, dbmanBasePath+"/messages/thread/summary", payload, &out); err != nil {
+		return domain.ThreadSummary{}, err
 	}
 	return out, nil
 }
 
+func (c *DBManClient) AddReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	return c.changeReaction(ctx, "/messages/reactions/add", tenantID, roomID, messageID, userID, emoji)
+}
+
+func (c *DBManClient) RemoveReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	return c.changeReaction(ctx, "/messages/reactions/remove", tenantID, roomID, messageID, userID, emoji)
+}
+
+func (c *DBManClient) changeReaction(ctx context.Context, path, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID, "user_id": userID, "emoji": emoji}
+	var out struct {
+		Changed  bool                 `json:"changed"`
+		Reaction domain.ReactionEvent `json:"reaction"`
+	}
+	if err := c.post(ctx, dbmanBasePath+path, payload, &out); err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	return out.Reaction, out.Changed, nil
+}
+
 func (c *DBManClient) MarkReadUpTo(ctx context.Context, tenantID, roomID, userID, messageID string) error {
 	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID, "message_id": messageID}
 	var resp map[string]any
-	return c.post(ctx, dbmanBasePath+"/messages/read", payload, &resp)
+	return notFoundAs(c.post(ctx, dbmanBasePath+"/messages/read", payload, &resp), ErrMessageNotFound)
 }
 
-func (c *DBManClient) SearchMessages(ctx context.Context, tenantID, q string, roomID *string, limit int, cursorID *string) ([]domain.Message, error) {
+func (c *DBManClient) SearchMessages(ctx context.Context, tenantID, userID, q string, roomID *string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.Message, error) {
 	payload := map[string]any{
-		"tenant_id": tenantID,
-		"q":         q,
-		"room_id":   roomID,
-		"limit":     limit,
-		"cursor_id": cursorID,
+		"tenant_id":         tenantID,
+		"user_id":           userID,
+		"q":                 q,
+		"room_id":           roomID,
+		"limit":             limit,
+		"cursor_created_at": cursorCreatedAt,
+		"cursor_id":         cursorID,
 	}
 	var items []domain.Message
 	if err := c.post(ctx, dbmanBasePath+"/messages/search", payload, &items); err != nil {
@@ -79,12 +321,13 @@ func (c *DBManClient) SearchMessages(ctx context.Context, tenantID, q string, ro
 	return items, nil
 }
 
-func (c *DBManClient) ListMessages(ctx context.Context, tenantID, roomID string, limit int, cursorID *string) ([]domain.Message, error) {
+func (c *DBManClient) ListMessages(ctx context.Context, tenantID, roomID, userID string, limit int, cursorSeq *int64) ([]domain.Message, error) {
 	payload := map[string]any{
-		"tenant_id": tenantID,
-		"room_id":   roomID,
-		"limit":     limit,
-		"cursor_id": cursorID,
+		"tenant_id":  tenantID,
+		"room_id":    roomID,
+		"user_id":    userID,
+		"limit":      limit,
+		"cursor_seq": cursorSeq,
 	}
 	var items []domain.Message
 	if err := c.post(ctx, dbmanBasePath+"/messages/list", payload, &items); err != nil {
@@ -124,6 +367,24 @@ func (c *DBManClient) GetUnreadCount(ctx context.Context, tenantID, roomID, user
 	return resp.Count, nil
 }
 
+func (c *DBManClient) ListMemberRoomIDs(ctx context.Context, tenantID, userID string) ([]string, error) {
+	payload := map[string]any{"tenant_id": tenantID, "user_id": userID}
+	var items []string
+	if err := c.post(ctx, dbmanBasePath+"/rooms/ids", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) ListRoomMemberIDs(ctx context.Context, tenantID, roomID string) ([]string, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID}
+	var items []string
+	if err := c.post(ctx, dbmanBasePath+"/rooms/members/list", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 func (c *DBManClient) GetUnreadCounts(ctx context.Context, tenantID, userID string) ([]domain.RoomUnread, error) {
 	payload := map[string]any{"tenant_id": tenantID, "user_id": userID}
 	var items []domain.RoomUnread
@@ -148,6 +409,428 @@ func (c *DBManClient) ListMyRooms(ctx context.Context, tenantID, userID string,
 	return items, nil
 }
 
+func (c *DBManClient) ListSyncChanges(ctx context.Context, tenantID, userID string, since *domain.SyncCursor, limit int) (domain.SyncBatch, error) {
+	payload := map[string]any{
+		"tenant_id": tenantID,
+		"user_id":   userID,
+		"since":     since,
+		"limit":     limit,
+	}
+	var out domain.SyncBatch
+	if err := c.post(ctx, dbmanBasePath+"/sync/changes", payload, &out); err != nil {
+		return domain.SyncBatch{}, err
+	}
+	return out, nil
+}
+
+func (c *DBManClient) CreateCall(ctx context.Context, call domain.Call) (domain.Call, bool, error) {
+	payload := map[string]any{"tenant_id": call.TenantID, "room_id": call.RoomID, "caller_id": call.CallerID, "media": call.Media}
+	var resp struct {
+		Created bool        `json:"created"`
+		Call    domain.Call `json:"call"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/calls/create", payload, &resp); err != nil {
+		return domain.Call{}, false, err
+	}
+	return resp.Call, resp.Created, nil
+}
+
+func (c *DBManClient) GetCall(ctx context.Context, tenantID, roomID, callID string) (domain.Call, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "call_id": callID}
+	var resp struct {
+		OK   bool        `json:"ok"`
+		Call domain.Call `json:"call"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/calls/get", payload, &resp); err != nil {
+		return domain.Call{}, false, err
+	}
+	return resp.Call, resp.OK, nil
+}
+
+func (c *DBManClient) GetLiveCall(ctx context.Context, tenantID, roomID string) (domain.Call, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID}
+	var resp struct {
+		OK   bool        `json:"ok"`
+		Call domain.Call `json:"call"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/calls/live", payload, &resp); err != nil {
+		return domain.Call{}, false, err
+	}
+	return resp.Call, resp.OK, nil
+}
+
+func (c *DBManClient) TransitionCall(ctx context.Context, tenantID, roomID, callID, userID string, action domain.CallAction) (domain.Call, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "call_id": callID, "user_id": userID, "action": action}
+	var resp struct {
+		Changed bool        `json:"changed"`
+		Call    domain.Call `json:"call"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/calls/transition", payload, &resp); err != nil {
+		return domain.Call{}, false, notFoundAs(err, ErrCallNotFound)
+	}
+	return resp.Call, resp.Changed, nil
+}
+
+func (c *DBManClient) ListCalls(ctx context.Context, tenantID, roomID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.Call, error) {
+	payload := map[string]any{
+		"tenant_id":         tenantID,
+		"room_id":           roomID,
+		"limit":             limit,
+		"cursor_created_at": cursorCreatedAt,
+		"cursor_id":         cursorID,
+	}
+	var items []domain.Call
+	if err := c.post(ctx, dbmanBasePath+"/calls/list", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ListExpiredCalls returns calls of the tenant still ringing since before
+// createdBefore.
+func (c *DBManClient) ListExpiredCalls(ctx context.Context, tenantID string, createdBefore time.Time, limit int) ([]domain.Call, error) {
+	payload := map[string]any{"tenant_id": tenantID, "created_before": createdBefore, "limit": limit}
+	var items []domain.Call
+	if err := c.post(ctx, dbmanBasePath+"/calls/expired", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) RecordTurnCredential(ctx context.Context, item domain.TurnCredentialAudit) error {
+	var out domain.TurnCredentialAudit
+	return c.post(ctx, dbmanBasePath+"/calls/turn-audit", item, &out)
+}
+
+func (c *DBManClient) CreateWebhook(ctx context.Context, item domain.Webhook) (domain.Webhook, error) {
+	var out domain.Webhook
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/create", item, &out); err != nil {
+		return domain.Webhook{}, err
+	}
+	return out, nil
+}
+
+func (c *DBManClient) ListWebhooks(ctx context.Context, tenantID string) ([]domain.Webhook, error) {
+	var items []domain.Webhook
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/list", map[string]any{"tenant_id": tenantID}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) GetWebhook(ctx context.Context, tenantID, webhookID string) (domain.Webhook, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "webhook_id": webhookID}
+	var resp struct {
+		OK      bool           `json:"ok"`
+		Webhook domain.Webhook `json:"webhook"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/get", payload, &resp); err != nil {
+		return domain.Webhook{}, false, err
+	}
+	return resp.Webhook, resp.OK, nil
+}
+
+func (c *DBManClient) UpdateWebhook(ctx context.Context, tenantID, webhookID string, targetURL *string, eventTypes []string, description *string, isActive *bool) (domain.Webhook, bool, error) {
+	payload := map[string]any{
+		"tenant_id":   tenantID,
+		"webhook_id":  webhookID,
+		"target_url":  targetURL,
+		"event_types": eventTypes,
+		"description": description,
+		"is_active":   isActive,
+	}
+	var resp struct {
+		OK      bool           `json:"ok"`
+		Webhook domain.Webhook `json:"webhook"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/update", payload, &resp); err != nil {
+		return domain.Webhook{}, false, err
+	}
+	return resp.Webhook, resp.OK, nil
+}
+
+func (c *DBManClient) RotateWebhookSecret(ctx context.Context, tenantID, webhookID, secret string) (domain.Webhook, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "webhook_id": webhookID, "secret": secret}
+	var resp struct {
+		OK      bool           `json:"ok"`
+		Webhook domain.Webhook `json:"webhook"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/rotate-secret", payload, &resp); err != nil {
+		return domain.Webhook{}, false, err
+	}
+	return resp.Webhook, resp.OK, nil
+}
+
+func (c *DBManClient) DeleteWebhook(ctx context.Context, tenantID, webhookID string) (bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "webhook_id": webhookID}
+	var resp struct {
+		OK bool `json:"ok"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/delete", payload, &resp); err != nil {
+		return false, err
+	}
+	return resp.OK, nil
+}
+
+func (c *DBManClient) EnqueueWebhookDeliveries(ctx context.Context, tenantID, eventID, eventType string, payload json.RawMessage) (int64, error) {
+	req := map[string]any{"tenant_id": tenantID, "event_id": eventID, "event_type": eventType, "payload": payload}
+	var resp struct {
+		Queued int64 `json:"queued"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/deliveries/enqueue", req, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Queued, nil
+}
+
+func (c *DBManClient) ClaimWebhookDeliveries(ctx context.Context, tenantID string, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	payload := map[string]any{"tenant_id": tenantID, "limit": limit, "lease_sec": int(lease.Seconds())}
+	var items []domain.WebhookDelivery
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/deliveries/claim", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) RecordWebhookAttempt(ctx context.Context, attempt domain.WebhookAttempt) (bool, error) {
+	var resp struct {
+		Disabled bool `json:"disabled"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/deliveries/attempt", attempt, &resp); err != nil {
+		return false, err
+	}
+	return resp.Disabled, nil
+}
+
+func (c *DBManClient) ListWebhookDeliveries(ctx context.Context, tenantID, webhookID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.WebhookDelivery, error) {
+	payload := map[string]any{
+		"tenant_id":         tenantID,
+		"webhook_id":        webhookID,
+		"limit":             limit,
+		"cursor_created_at": cursorCreatedAt,
+		"cursor_id":         cursorID,
+	}
+	var items []domain.WebhookDelivery
+	if err := c.post(ctx, dbmanBasePath+"/webhooks/deliveries/list", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) CreateIncomingWebhook(ctx context.Context, item domain.IncomingWebhook) (domain.IncomingWebhook, bool, error) {
+	var resp struct {
+		OK   bool                   `json:"ok"`
+		Hook domain.IncomingWebhook `json:"hook"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/incoming-webhooks/create", item, &resp); err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	return resp.Hook, resp.OK, nil
+}
+
+func (c *DBManClient) ListIncomingWebhooks(ctx context.Context, tenantID, roomID string) ([]domain.IncomingWebhook, error) {
+	var items []domain.IncomingWebhook
+	if err := c.post(ctx, dbmanBasePath+"/incoming-webhooks/list", map[string]any{"tenant_id": tenantID, "room_id": roomID}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) RevokeIncomingWebhook(ctx context.Context, tenantID, roomID, hookID, actorID string) (domain.IncomingWebhook, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "hook_id": hookID, "actor_id": actorID}
+	var resp struct {
+		OK   bool                   `json:"ok"`
+		Hook domain.IncomingWebhook `json:"hook"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/incoming-webhooks/revoke", payload, &resp); err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	return resp.Hook, resp.OK, nil
+}
+
+func (c *DBManClient) AuthenticateIncomingWebhook(ctx context.Context, tenantID, hookID, tokenHash string) (domain.IncomingWebhook, bool, error) {
+	payload := map[string]any{"tenant_id": tenantID, "hook_id": hookID, "token_hash": tokenHash}
+	var resp struct {
+		OK   bool                   `json:"ok"`
+		Hook domain.IncomingWebhook `json:"hook"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/incoming-webhooks/authenticate", payload, &resp); err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	return resp.Hook, resp.OK, nil
+}
+
+func (c *DBManClient) ListRateLimitPolicies(ctx context.Context, tenantID string) ([]domain.RateLimitPolicy, error) {
+	var items []domain.RateLimitPolicy
+	if err := c.post(ctx, dbmanBasePath+"/rate-limits/list", map[string]any{"tenant_id": tenantID}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) UpsertRateLimitPolicy(ctx context.Context, item domain.RateLimitPolicy) (domain.RateLimitPolicy, error) {
+	var out domain.RateLimitPolicy
+	if err := c.post(ctx, dbmanBasePath+"/rate-limits/upsert", item, &out); err != nil {
+		return domain.RateLimitPolicy{}, err
+	}
+	return out, nil
+}
+
+func (c *DBManClient) DeleteRateLimitPolicy(ctx context.Context, tenantID, policyID string) (bool, error) {
+	var resp struct {
+		OK bool `json:"ok"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/rate-limits/delete", map[string]any{"tenant_id": tenantID, "policy_id": policyID}, &resp); err != nil {
+		return false, err
+	}
+	return resp.OK, nil
+}
+
+func (c *DBManClient) ListModerationRules(ctx context.Context, tenantID string) ([]moderation.Rule, error) {
+	var items []moderation.Rule
+	if err := c.post(ctx, dbmanBasePath+"/moderation/rules/list", map[string]any{"tenant_id": tenantID}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) CreateModerationRule(ctx context.Context, item moderation.Rule) (moderation.Rule, error) {
+	var out moderation.Rule
+	if err := c.post(ctx, dbmanBasePath+"/moderation/rules/create", item, &out); err != nil {
+		return moderation.Rule{}, err
+	}
+	return out, nil
+}
+
+func (c *DBManClient) UpdateModerationRule(ctx context.Context, item moderation.Rule) (moderation.Rule, bool, error) {
+	var resp struct {
+		OK   bool            `json:"ok"`
+		Rule moderation.Rule `json:"rule"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/rules/update", item, &resp); err != nil {
+		return moderation.Rule{}, false, err
+	}
+	return resp.Rule, resp.OK, nil
+}
+
+func (c *DBManClient) DeleteModerationRule(ctx context.Context, tenantID, ruleID string) (bool, error) {
+	var resp struct {
+		OK bool `json:"ok"`
+	}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/rules/delete", map[string]any{"tenant_id": tenantID, "rule_id": ruleID}, &resp); err != nil {
+		return false, err
+	}
+	return resp.OK, nil
+}
+
+func (c *DBManClient) ListModerationFlags(ctx context.Context, tenantID string, status moderation.FlagStatus, limit int) ([]moderation.Flag, error) {
+	var items []moderation.Flag
+	payload := map[string]any{"tenant_id": tenantID, "status": status, "limit": limit}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/flags/list", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ResolveModerationFlag returns the soft-deleted message when removing the
+// flag deleted one.
+func (c *DBManClient) ResolveModerationFlag(ctx context.Context, tenantID, flagID, reviewerID string, status moderation.FlagStatus) (moderation.Flag, *domain.Message, bool, error) {
+	var resp struct {
+		OK             bool            `json:"ok"`
+		Flag           moderation.Flag `json:"flag"`
+		RemovedMessage *domain.Message `json:"removed_message"`
+	}
+	payload := map[string]any{"tenant_id": tenantID, "flag_id": flagID, "reviewer_id": reviewerID, "status": status}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/flags/resolve", payload, &resp); err != nil {
+		return moderation.Flag{}, nil, false, err
+	}
+	return resp.Flag, resp.RemovedMessage, resp.OK, nil
+}
+
+// ReportMessage reports false when the message is unknown or deleted.
+func (c *DBManClient) ReportMessage(ctx context.Context, tenantID, roomID, messageID, reporterID, reason string) (moderation.Flag, bool, error) {
+	var resp struct {
+		OK   bool            `json:"ok"`
+		Flag moderation.Flag `json:"flag"`
+	}
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID, "reporter_id": reporterID, "reason": reason}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/reports/create", payload, &resp); err != nil {
+		return moderation.Flag{}, false, err
+	}
+	return resp.Flag, resp.OK, nil
+}
+
+func (c *DBManClient) ModeratorDeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID, reason string) (domain.Message, moderation.AuditEntry, bool, error) {
+	var resp struct {
+		OK      bool                  `json:"ok"`
+		Message domain.Message        `json:"message"`
+		Audit   moderation.AuditEntry `json:"audit"`
+	}
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "message_id": messageID, "actor_id": actorID, "reason": reason}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/messages/delete", payload, &resp); err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	return resp.Message, resp.Audit, resp.OK, nil
+}
+
+// ModeratorRemoveMember reports whether the user was in the room (removed) and
+// whether anything was done at all (ok).
+func (c *DBManClient) ModeratorRemoveMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string, ban bool) (moderation.AuditEntry, bool, bool, error) {
+	var resp struct {
+		OK      bool                  `json:"ok"`
+		Removed bool                  `json:"removed"`
+		Audit   moderation.AuditEntry `json:"audit"`
+	}
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID, "actor_id": actorID, "reason": reason, "ban": ban}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/members/remove", payload, &resp); err != nil {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	return resp.Audit, resp.Removed, resp.OK, nil
+}
+
+func (c *DBManClient) ModeratorUnbanMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string) (moderation.AuditEntry, bool, error) {
+	var resp struct {
+		OK    bool                  `json:"ok"`
+		Audit moderation.AuditEntry `json:"audit"`
+	}
+	payload := map[string]any{"tenant_id": tenantID, "room_id": roomID, "user_id": userID, "actor_id": actorID, "reason": reason}
+	if err := c.post(ctx, dbmanBasePath+"/moderation/members/unban", payload, &resp); err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	return resp.Audit, resp.OK, nil
+}
+
+func (c *DBManClient) ListRoomBans(ctx context.Context, tenantID, roomID string) ([]moderation.RoomBan, error) {
+	var items []moderation.RoomBan
+	if err := c.post(ctx, dbmanBasePath+"/moderation/bans/list", map[string]any{"tenant_id": tenantID, "room_id": roomID}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) ListModerationAudit(ctx context.Context, tenantID, roomID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]moderation.AuditEntry, error) {
+	payload := map[string]any{
+		"tenant_id":         tenantID,
+		"room_id":           roomID,
+		"limit":             limit,
+		"cursor_created_at": cursorCreatedAt,
+		"cursor_id":         cursorID,
+	}
+	var items []moderation.AuditEntry
+	if err := c.post(ctx, dbmanBasePath+"/moderation/audit/list", payload, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) ListTenants(ctx context.Context) ([]domain.Tenant, error) {
+	var items []domain.Tenant
+	if err := c.post(ctx, dbmanBasePath+"/tenants/list", map[string]any{}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 func (c *DBManClient) GetTenant(ctx context.Context, tenantID string) (domain.Tenant, error) {
 	var item domain.Tenant
 	payload := map[string]any{"tenant_id": tenantID}
@@ -169,12 +852,12 @@ func (c *DBManClient) GetTenantRedisMeta(ctx context.Context, tenantID string) (
 	}, nil
 }
 
-func (c *DBManClient) GetTenantMQMeta(ctx context.Context, tenantID string) (TenantMQMeta, error) {
+func (c *DBManClient) GetTenantMQMeta(ctx context.Context, tenantID string) (mq.TenantMQMeta, error) {
 	tenant, err := c.GetTenant(ctx, tenantID)
 	if err != nil {
-		return TenantMQMeta{}, err
+		return mq.TenantMQMeta{}, err
 	}
-	return TenantMQMeta{
+	return mq.TenantMQMeta{
 		DeploymentMode:      strings.ToLower(strings.TrimSpace(tenant.DeploymentMode)),
 		DedicatedLavinMQURL: strings.TrimSpace(tenant.DedicatedLavinMQURL),
 		IsActive:            tenant.IsActive,
@@ -184,3 +867,11 @@ func (c *DBManClient) GetTenantMQMeta(ctx context.Context, tenantID string) (Ten
 func (c *DBManClient) post(ctx context.Context, path string, payload any, out any) error {
 	return c.client.Post(ctx, path, payload, out)
 }
+
+// notFoundAs replaces a dbman 404 with the caller's not-found error.
+func notFoundAs(err, notFound error) error {
+	if errors.Is(err, commondbman.ErrNotFound) {
+		return notFound
+	}
+	return err
+}
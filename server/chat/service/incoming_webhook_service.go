@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"msg_server/server/chat/domain"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/middleware"
+)
+
+var (
+	ErrIncomingWebhookNotFound     = errors.New("incoming webhook not found")
+	ErrIncomingWebhookUnauthorized = errors.New("incoming webhook token is invalid or revoked")
+	ErrIncomingWebhookName         = errors.New("name is required and must be at most 80 characters")
+	ErrInvalidBotUser              = errors.New("bot_user_id must be a bot user of this tenant")
+)
+
+const (
+	maxIncomingWebhookName       = 80
+	defaultIncomingWebhookPerMin = 30
+)
+
+// IncomingWebhookService manages room incoming webhooks and posts their
+// payloads as messages from the hook's bot user.
+type IncomingWebhookService struct {
+	dbman   *DBManClient
+	chat    *ChatService
+	limiter *middleware.RateLimiter
+	perMin  int
+}
+
+func NewIncomingWebhookService(dbman *DBManClient, chat *ChatService, limiter *middleware.RateLimiter, perMinute int) *IncomingWebhookService {
+	if perMinute <= 0 {
+		perMinute = defaultIncomingWebhookPerMin
+	}
+	return &IncomingWebhookService{dbman: dbman, chat: chat, limiter: limiter, perMin: perMinute}
+}
+
+// CreateIncomingWebhook issues a hook for the room. An empty botUserID creates
+// a new bot user named after the hook. The token is only returned here.
+func (s *IncomingWebhookService) CreateIncomingWebhook(ctx context.Context, tenantID, roomID, actorID, name, botUserID string) (domain.IncomingWebhook, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxIncomingWebhookName {
+		return domain.IncomingWebhook{}, ErrIncomingWebhookName
+	}
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return domain.IncomingWebhook{}, err
+	}
+	token := "whin_" + hex.EncodeToString(b[:])
+	item, ok, err := s.dbman.CreateIncomingWebhook(ctx, domain.IncomingWebhook{
+		TenantID:  tenantID,
+		RoomID:    roomID,
+		BotUserID: strings.TrimSpace(botUserID),
+		Name:      name,
+		TokenHash: hashIncomingWebhookToken(token),
+		CreatedBy: actorID,
+	})
+	if err != nil {
+		return domain.IncomingWebhook{}, err
+	}
+	if !ok {
+		return domain.IncomingWebhook{}, ErrInvalidBotUser
+	}
+	item.Token = token
+	return item, nil
+}
+
+func (s *IncomingWebhookService) ListIncomingWebhooks(ctx context.Context, tenantID, roomID string) ([]domain.IncomingWebhook, error) {
+	return s.dbman.ListIncomingWebhooks(ctx, tenantID, roomID)
+}
+
+func (s *IncomingWebhookService) RevokeIncomingWebhook(ctx context.Context, tenantID, roomID, hookID, actorID string) (domain.IncomingWebhook, error) {
+	item, ok, err := s.dbman.RevokeIncomingWebhook(ctx, tenantID, roomID, hookID, actorID)
+	if err != nil {
+		return domain.IncomingWebhook{}, err
+	}
+	if !ok {
+		return domain.IncomingWebhook{}, ErrIncomingWebhookNotFound
+	}
+	return item, nil
+}
+
+// Post authenticates the hook token, applies the per-hook rate limit and
+// stores msg in the hook's room as its bot user. msg carries body, metadata
+// and an optional thread parent.
+func (s *IncomingWebhookService) Post(ctx context.Context, tenantID, hookID, token string, msg domain.Message) (domain.Message, error) {
+	if strings.TrimSpace(msg.Body) == "" {
+		return domain.Message{}, ErrMessageBodyRequired
+	}
+	hook, ok, err := s.dbman.AuthenticateIncomingWebhook(ctx, tenantID, hookID, hashIncomingWebhookToken(token))
+	if err != nil {
+		return domain.Message{}, err
+	}
+	if !ok {
+		return domain.Message{}, ErrIncomingWebhookUnauthorized
+	}
+	if err := s.allow(ctx, tenantID, hook.ID); err != nil {
+		return domain.Message{}, err
+	}
+	meta := map[string]any{}
+	if msg.MetaJSON != "" {
+		if err := json.Unmarshal([]byte(msg.MetaJSON), &meta); err != nil {
+			return domain.Message{}, err
+		}
+	}
+	meta["incoming_webhook_id"] = hook.ID
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	msg.TenantID = tenantID
+	msg.RoomID = hook.RoomID
+	msg.SenderID = hook.BotUserID
+	msg.MetaJSON = string(metaJSON)
+	return s.chat.CreateMessage(ctx, msg)
+}
+
+// allow draws from the hook's bucket on the tenant's Redis so the limit holds
+// across chat instances. Redis failures let the post through.
+func (s *IncomingWebhookService) allow(ctx context.Context, tenantID, hookID string) error {
+	allowed, retryAfter, err := s.limiter.Allow(ctx, tenantID, []middleware.Bucket{{
+		Key:   "incoming_webhook:" + hookID,
+		Limit: middleware.Limit{PerMinute: s.perMin},
+	}})
+	if err != nil {
+		commonlog.Warnf("event=rate_limit action=check status=failed tenant_id=%s hook_id=%s error=%v", tenantID, hookID, err)
+		return nil
+	}
+	if !allowed {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func hashIncomingWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/events"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/moderation"
+)
+
+var (
+	ErrModerationRuleNotFound = errors.New("moderation rule not found")
+	ErrModerationFlagNotFound = errors.New("moderation flag not found or already resolved")
+	ErrModerationReason       = errors.New("reason must be at most 500 characters")
+	ErrModerationReasonNeeded = errors.New("reason is required")
+	ErrModerationUser         = errors.New("user not found")
+	ErrModerationBanNotFound  = errors.New("user is not banned from this room")
+)
+
+const maxModerationReason = 500
+
+// ModerationService runs the tenant's moderation rules over messages as a
+// MessageHook and serves the rule and review queue admin API.
+type ModerationService struct {
+	dbman   *DBManClient
+	chat    *ChatService
+	scanner *moderation.Scanner
+}
+
+func NewModerationService(dbman *DBManClient, chat *ChatService) *ModerationService {
+	return &ModerationService{dbman: dbman, chat: chat, scanner: moderation.NewScanner(dbman)}
+}
+
+// BeforeStoreMessage rejects blocked messages with *moderation.BlockedError,
+// stores masked bodies and returns the flag rules that matched.
+func (s *ModerationService) BeforeStoreMessage(ctx context.Context, msg *domain.Message) ([]string, error) {
+	res, err := s.scanner.Scan(ctx, msg.TenantID, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.Blocked != "" {
+		commonlog.Infof("event=moderation action=block status=ok source=message tenant_id=%s room_id=%s user_id=%s rule=%q", msg.TenantID, msg.RoomID, msg.SenderID, res.Blocked)
+		return nil, &moderation.BlockedError{Rule: res.Blocked}
+	}
+	msg.Body = res.Text
+	return res.Flagged, nil
+}
+
+func (s *ModerationService) ListRules(ctx context.Context, tenantID string) ([]moderation.Rule, error) {
+	return s.dbman.ListModerationRules(ctx, tenantID)
+}
+
+// SaveRule creates the rule, or replaces it when item.ID is set.
+func (s *ModerationService) SaveRule(ctx context.Context, tenantID, actorID string, item moderation.Rule) (moderation.Rule, error) {
+	item.Name = strings.TrimSpace(item.Name)
+	item.Pattern = strings.TrimSpace(item.Pattern)
+	if err := item.Validate(); err != nil {
+		return moderation.Rule{}, err
+	}
+	item.TenantID = tenantID
+	item.UpdatedBy = actorID
+	var saved moderation.Rule
+	var err error
+	if item.ID == "" {
+		saved, err = s.dbman.CreateModerationRule(ctx, item)
+	} else {
+		var ok bool
+		saved, ok, err = s.dbman.UpdateModerationRule(ctx, item)
+		if err == nil && !ok {
+			err = ErrModerationRuleNotFound
+		}
+	}
+	if err != nil {
+		return moderation.Rule{}, err
+	}
+	s.scanner.Invalidate(tenantID)
+	return saved, nil
+}
+
+func (s *ModerationService) DeleteRule(ctx context.Context, tenantID, ruleID string) error {
+	ok, err := s.dbman.DeleteModerationRule(ctx, tenantID, ruleID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrModerationRuleNotFound
+	}
+	s.scanner.Invalidate(tenantID)
+	return nil
+}
+
+func (s *ModerationService) ListFlags(ctx context.Context, tenantID string, status moderation.FlagStatus, limit int) ([]moderation.Flag, error) {
+	return s.dbman.ListModerationFlags(ctx, tenantID, status, limit)
+}
+
+func (s *ModerationService) ApproveFlag(ctx context.Context, tenantID, flagID, reviewerID string) (moderation.Flag, error) {
+	flag, _, err := s.resolveFlag(ctx, tenantID, flagID, reviewerID, moderation.FlagApproved)
+	return flag, err
+}
+
+// RemoveFlag deletes the flagged message or note. removed is the deleted
+// message, nil for notes or when the sender already deleted it.
+func (s *ModerationService) RemoveFlag(ctx context.Context, tenantID, flagID, reviewerID string) (moderation.Flag, *domain.Message, error) {
+	flag, removed, err := s.resolveFlag(ctx, tenantID, flagID, reviewerID, moderation.FlagRemoved)
+	if err != nil {
+		return flag, nil, err
+	}
+	if removed != nil && !s.chat.IsMQEnabled() {
+		_ = s.chat.vector.DeleteMessage(ctx, removed.ID)
+	}
+	return flag, removed, nil
+}
+
+func (s *ModerationService) resolveFlag(ctx context.Context, tenantID, flagID, reviewerID string, status moderation.FlagStatus) (moderation.Flag, *domain.Message, error) {
+	flag, removed, ok, err := s.dbman.ResolveModerationFlag(ctx, tenantID, flagID, reviewerID, status)
+	if err != nil {
+		return moderation.Flag{}, nil, err
+	}
+	if !ok {
+		return moderation.Flag{}, nil, ErrModerationFlagNotFound
+	}
+	commonlog.Infof("event=moderation action=resolve status=ok tenant_id=%s flag_id=%s source=%s reviewer_id=%s resolution=%s", tenantID, flag.ID, flag.Source, reviewerID, status)
+	return flag, removed, nil
+}
+
+func normalizeModerationReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxModerationReason {
+		return "", ErrModerationReason
+	}
+	return reason, nil
+}
+
+// ReportMessage queues a member's report for admin review. Reporting the same
+// message again while the report is pending returns the pending report.
+func (s *ModerationService) ReportMessage(ctx context.Context, tenantID, roomID, messageID, reporterID, reason string) (moderation.Flag, error) {
+	reason, err := normalizeModerationReason(reason)
+	if err != nil {
+		return moderation.Flag{}, err
+	}
+	flag, ok, err := s.dbman.ReportMessage(ctx, tenantID, roomID, messageID, reporterID, reason)
+	if err != nil {
+		return moderation.Flag{}, err
+	}
+	if !ok {
+		return moderation.Flag{}, ErrMessageNotFound
+	}
+	commonlog.Infof("event=moderation action=report status=ok tenant_id=%s room_id=%s message_id=%s flag_id=%s user_id=%s", tenantID, roomID, messageID, flag.ID, reporterID)
+	return flag, nil
+}
+
+// DeleteMessage removes any member's message; the reason is required and
+// kept in the audit log.
+func (s *ModerationService) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID, reason string) (domain.Message, moderation.AuditEntry, error) {
+	reason, err := normalizeModerationReason(reason)
+	if err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, err
+	}
+	if reason == "" {
+		return domain.Message{}, moderation.AuditEntry{}, ErrModerationReasonNeeded
+	}
+	msg, entry, ok, err := s.dbman.ModeratorDeleteMessage(ctx, tenantID, roomID, messageID, actorID, reason)
+	if err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, err
+	}
+	if !ok {
+		return domain.Message{}, moderation.AuditEntry{}, ErrMessageNotFound
+	}
+	if !s.chat.IsMQEnabled() {
+		_ = s.chat.vector.DeleteMessage(ctx, msg.ID)
+	}
+	commonlog.Infof("event=moderation action=delete_message status=ok tenant_id=%s room_id=%s message_id=%s user_id=%s", tenantID, roomID, msg.ID, actorID)
+	return msg, entry, nil
+}
+
+// RemoveMember takes the user out of the room; with ban they cannot be added
+// back until unbanned. A user who is not a member can still be banned, and
+// removed is false then. The room owner can be neither removed nor banned.
+func (s *ModerationService) RemoveMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string, ban bool) (moderation.AuditEntry, bool, error) {
+	reason, err := normalizeModerationReason(reason)
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	target, isMember, err := s.dbman.GetRoomMember(ctx, tenantID, roomID, userID)
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	if isMember && target.Role == domain.RoomRoleOwner {
+		return moderation.AuditEntry{}, false, ErrRoomOwner
+	}
+	entry, removed, ok, err := s.dbman.ModeratorRemoveMember(ctx, tenantID, roomID, userID, actorID, reason, ban)
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	if !ok {
+		if ban {
+			return moderation.AuditEntry{}, false, ErrModerationUser
+		}
+		return moderation.AuditEntry{}, false, ErrMemberNotFound
+	}
+	commonlog.Infof("event=moderation action=%s status=ok tenant_id=%s room_id=%s target_user_id=%s user_id=%s removed=%t", entry.Action, tenantID, roomID, userID, actorID, removed)
+	if removed {
+		s.chat.publishEvent(ctx, tenantID, events.MemberLeft{Member: events.Member{RoomID: roomID, UserID: userID, Action: domain.MembershipRemoved}})
+	}
+	return entry, removed, nil
+}
+
+func (s *ModerationService) UnbanMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string) (moderation.AuditEntry, error) {
+	reason, err := normalizeModerationReason(reason)
+	if err != nil {
+		return moderation.AuditEntry{}, err
+	}
+	entry, ok, err := s.dbman.ModeratorUnbanMember(ctx, tenantID, roomID, userID, actorID, reason)
+	if err != nil {
+		return moderation.AuditEntry{}, err
+	}
+	if !ok {
+		return moderation.AuditEntry{}, ErrModerationBanNotFound
+	}
+	commonlog.Infof("event=moderation action=%s status=ok tenant_id=%s room_id=%s target_user_id=%s user_id=%s", entry.Action, tenantID, roomID, userID, actorID)
+	return entry, nil
+}
+
+func (s *ModerationService) ListBans(ctx context.Context, tenantID, roomID string) ([]moderation.RoomBan, error) {
+	return s.dbman.ListRoomBans(ctx, tenantID, roomID)
+}
+
+func (s *ModerationService) ListAudit(ctx context.Context, tenantID, roomID string, limit int, cursor string) ([]moderation.AuditEntry, string, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	var cursorCreatedAt *time.Time
+	var cursorID *string
+	if strings.TrimSpace(cursor) != "" {
+		createdAt, auditID, err := decodeRoomCursor(cursor)
+		if err != nil {
+			return nil, "", errors.New("cursor is invalid")
+		}
+		cursorCreatedAt = &createdAt
+		cursorID = &auditID
+	}
+	items, err := s.dbman.ListModerationAudit(ctx, tenantID, strings.TrimSpace(roomID), limit+1, cursorCreatedAt, cursorID)
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		nextCursor = encodeRoomCursor(last.CreatedAt.UTC(), last.ID)
+	}
+	return items, nextCursor, nil
+}
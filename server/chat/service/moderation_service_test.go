@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/dbman"
+	"msg_server/server/common/moderation"
+)
+
+// newModerationTest returns a ModerationService whose dbman stand-in has an
+// owner and a member in "room-1", and the list of users it was asked to
+// remove or ban.
+func newModerationTest(t *testing.T) (*ModerationService, func() []string) {
+	t.Helper()
+	members := map[string]domain.RoomRole{"owner-1": domain.RoomRoleOwner, "member-1": domain.RoomRoleMember}
+	var mu sync.Mutex
+	var removals []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			UserID string `json:"user_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		role, isMember := members[req.UserID]
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case dbman.BasePath + "/rooms/members/get":
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": isMember, "member": domain.RoomMember{RoomID: "room-1", UserID: req.UserID, Role: role}})
+		case dbman.BasePath + "/moderation/members/remove":
+			mu.Lock()
+			removals = append(removals, req.UserID)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "removed": isMember, "audit": moderation.AuditEntry{RoomID: "room-1", TargetUserID: req.UserID}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client := NewDBManClient(srv.URL)
+	svc := NewModerationService(client, NewChatService(nil, client, NewVectormanClient("", false), false))
+	return svc, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), removals...)
+	}
+}
+
+func TestModerationRemoveMemberRefusesOwner(t *testing.T) {
+	svc, removals := newModerationTest(t)
+	for _, ban := range []bool{false, true} {
+		if _, _, err := svc.RemoveMember(context.Background(), "tenant-1", "room-1", "owner-1", "mod-1", "", ban); !errors.Is(err, ErrRoomOwner) {
+			t.Errorf("ban=%t: err = %v, want ErrRoomOwner", ban, err)
+		}
+	}
+	if got := removals(); len(got) != 0 {
+		t.Errorf("removals = %v", got)
+	}
+}
+
+func TestModerationRemoveMemberReportsRemoval(t *testing.T) {
+	svc, _ := newModerationTest(t)
+	ctx := context.Background()
+	if _, removed, err := svc.RemoveMember(ctx, "tenant-1", "room-1", "member-1", "mod-1", "", false); err != nil || !removed {
+		t.Errorf("remove member: removed = %t, err = %v", removed, err)
+	}
+	if _, removed, err := svc.RemoveMember(ctx, "tenant-1", "room-1", "outsider", "mod-1", "spam", true); err != nil || removed {
+		t.Errorf("ban non-member: removed = %t, err = %v", removed, err)
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"msg_server/server/chat/domain"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/middleware"
+)
+
+var (
+	ErrRateLimitPolicyNotFound = errors.New("rate limit policy not found")
+	ErrInvalidRateLimitPolicy  = errors.New("scope must be tenant, room or user and action message or api; room limits apply to messages only")
+	ErrInvalidRateLimitValue   = errors.New("per_minute and burst must be between 0 and 1000000")
+)
+
+// RateLimitError reports a rejected request and when the caller may retry.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limit exceeded"
+}
+
+const (
+	rateLimitPolicyTTL = 30 * time.Second
+	maxRateLimitValue  = 1000000
+)
+
+// RateLimitDefaults apply where a tenant has no policy. A zero Limit is
+// unlimited.
+type RateLimitDefaults struct {
+	TenantMessage middleware.Limit
+	TenantAPI     middleware.Limit
+	RoomMessage   middleware.Limit
+	UserMessage   middleware.Limit
+	UserAPI       middleware.Limit
+}
+
+func (d RateLimitDefaults) limit(scope domain.RateLimitScope, action domain.RateLimitAction) middleware.Limit {
+	switch {
+	case scope == domain.RateLimitScopeTenant && action == domain.RateLimitActionMessage:
+		return d.TenantMessage
+	case scope == domain.RateLimitScopeTenant && action == domain.RateLimitActionAPI:
+		return d.TenantAPI
+	case scope == domain.RateLimitScopeRoom && action == domain.RateLimitActionMessage:
+		return d.RoomMessage
+	case scope == domain.RateLimitScopeUser && action == domain.RateLimitActionMessage:
+		return d.UserMessage
+	case scope == domain.RateLimitScopeUser && action == domain.RateLimitActionAPI:
+		return d.UserAPI
+	default:
+		return middleware.Limit{}
+	}
+}
+
+type rateLimitKey struct {
+	scope   domain.RateLimitScope
+	subject string
+	action  domain.RateLimitAction
+}
+
+type cachedRateLimits struct {
+	limits    map[rateLimitKey]middleware.Limit
+	fetchedAt time.Time
+}
+
+// RateLimitService resolves a request's tenant, room and user buckets from
+// the tenant's policies (cached briefly) and the configured defaults.
+type RateLimitService struct {
+	dbman    *DBManClient
+	limiter  *middleware.RateLimiter
+	defaults RateLimitDefaults
+
+	mu    sync.Mutex
+	cache map[string]cachedRateLimits
+}
+
+func NewRateLimitService(dbman *DBManClient, limiter *middleware.RateLimiter, defaults RateLimitDefaults) *RateLimitService {
+	return &RateLimitService{dbman: dbman, limiter: limiter, defaults: defaults, cache: map[string]cachedRateLimits{}}
+}
+
+func (s *RateLimitService) Limiter() *middleware.RateLimiter {
+	return s.limiter
+}
+
+// Buckets returns the buckets for one action: the tenant's, the room's (for
+// messages with a room) and the user's. A subject's own policy wins over the
+// scope-wide policy, which wins over the default.
+func (s *RateLimitService) Buckets(ctx context.Context, tenantID, userID, roomID string, action domain.RateLimitAction) ([]middleware.Bucket, error) {
+	limits, err := s.policies(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(scope domain.RateLimitScope, subject string) middleware.Limit {
+		if subject != "" {
+			if l, ok := limits[rateLimitKey{scope: scope, subject: subject, action: action}]; ok {
+				return l
+			}
+		}
+		if l, ok := limits[rateLimitKey{scope: scope, action: action}]; ok {
+			return l
+		}
+		return s.defaults.limit(scope, action)
+	}
+	prefix := string(action) + ":"
+	buckets := []middleware.Bucket{{Key: prefix + "tenant", Limit: resolve(domain.RateLimitScopeTenant, "")}}
+	if roomID != "" && action == domain.RateLimitActionMessage {
+		buckets = append(buckets, middleware.Bucket{Key: prefix + "room:" + roomID, Limit: resolve(domain.RateLimitScopeRoom, roomID)})
+	}
+	if userID != "" {
+		buckets = append(buckets, middleware.Bucket{Key: prefix + "user:" + userID, Limit: resolve(domain.RateLimitScopeUser, userID)})
+	}
+	return buckets, nil
+}
+
+// Allow returns a *RateLimitError when the action is limited. Policy and
+// Redis failures are logged and let the action through.
+func (s *RateLimitService) Allow(ctx context.Context, tenantID, userID, roomID string, action domain.RateLimitAction) error {
+	buckets, err := s.Buckets(ctx, tenantID, userID, roomID, action)
+	if err == nil {
+		var allowed bool
+		var retryAfter time.Duration
+		allowed, retryAfter, err = s.limiter.Allow(ctx, tenantID, buckets)
+		if err == nil && !allowed {
+			return &RateLimitError{RetryAfter: retryAfter}
+		}
+	}
+	if err != nil {
+		commonlog.Warnf("event=rate_limit action=check status=failed tenant_id=%s room_id=%s user_id=%s limit_action=%s error=%v", tenantID, roomID, userID, action, err)
+	}
+	return nil
+}
+
+// policies serves a stale copy when dbman is unreachable.
+func (s *RateLimitService) policies(ctx context.Context, tenantID string) (map[rateLimitKey]middleware.Limit, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < rateLimitPolicyTTL {
+		return cached.limits, nil
+	}
+	items, err := s.dbman.ListRateLimitPolicies(ctx, tenantID)
+	if err != nil {
+		if ok {
+			return cached.limits, nil
+		}
+		return nil, err
+	}
+	limits := make(map[rateLimitKey]middleware.Limit, len(items))
+	for _, item := range items {
+		limits[rateLimitKey{scope: item.Scope, subject: item.SubjectID, action: item.Action}] = middleware.Limit{PerMinute: item.PerMinute, Burst: item.Burst}
+	}
+	s.mu.Lock()
+	s.cache[tenantID] = cachedRateLimits{limits: limits, fetchedAt: time.Now()}
+	s.mu.Unlock()
+	return limits, nil
+}
+
+func (s *RateLimitService) invalidate(tenantID string) {
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+}
+
+// Defaults lists the configured defaults as scope-wide policies without ids.
+func (s *RateLimitService) Defaults() []domain.RateLimitPolicy {
+	out := make([]domain.RateLimitPolicy, 0, 5)
+	for _, key := range []rateLimitKey{
+		{scope: domain.RateLimitScopeTenant, action: domain.RateLimitActionMessage},
+		{scope: domain.RateLimitScopeTenant, action: domain.RateLimitActionAPI},
+		{scope: domain.RateLimitScopeRoom, action: domain.RateLimitActionMessage},
+		{scope: domain.RateLimitScopeUser, action: domain.RateLimitActionMessage},
+		{scope: domain.RateLimitScopeUser, action: domain.RateLimitActionAPI},
+	} {
+		l := s.defaults.limit(key.scope, key.action)
+		out = append(out, domain.RateLimitPolicy{Scope: key.scope, Action: key.action, PerMinute: l.PerMinute, Burst: l.Burst})
+	}
+	return out
+}
+
+func (s *RateLimitService) ListPolicies(ctx context.Context, tenantID string) ([]domain.RateLimitPolicy, error) {
+	return s.dbman.ListRateLimitPolicies(ctx, tenantID)
+}
+
+func (s *RateLimitService) UpsertPolicy(ctx context.Context, tenantID, actorID string, item domain.RateLimitPolicy) (domain.RateLimitPolicy, error) {
+	item.SubjectID = strings.TrimSpace(item.SubjectID)
+	switch {
+	case !item.Action.Valid():
+		return domain.RateLimitPolicy{}, ErrInvalidRateLimitPolicy
+	case item.Scope == domain.RateLimitScopeTenant:
+		if item.SubjectID != "" {
+			return domain.RateLimitPolicy{}, ErrInvalidRateLimitPolicy
+		}
+	case item.Scope == domain.RateLimitScopeRoom:
+		if item.Action != domain.RateLimitActionMessage {
+			return domain.RateLimitPolicy{}, ErrInvalidRateLimitPolicy
+		}
+	case item.Scope != domain.RateLimitScopeUser:
+		return domain.RateLimitPolicy{}, ErrInvalidRateLimitPolicy
+	}
+	if item.PerMinute < 0 || item.PerMinute > maxRateLimitValue || item.Burst < 0 || item.Burst > maxRateLimitValue {
+		return domain.RateLimitPolicy{}, ErrInvalidRateLimitValue
+	}
+	item.TenantID = tenantID
+	item.UpdatedBy = actorID
+	saved, err := s.dbman.UpsertRateLimitPolicy(ctx, item)
+	if err != nil {
+		return domain.RateLimitPolicy{}, err
+	}
+	s.invalidate(tenantID)
+	return saved, nil
+}
+
+func (s *RateLimitService) DeletePolicy(ctx context.Context, tenantID, policyID string) error {
+	ok, err := s.dbman.DeleteRateLimitPolicy(ctx, tenantID, policyID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRateLimitPolicyNotFound
+	}
+	s.invalidate(tenantID)
+	return nil
+}
@@ -1,11 +1,13 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +19,9 @@ import (
 	"msg_server/server/chat/domain"
 	"msg_server/server/common/infra/cache"
 	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/middleware"
+	"msg_server/server/common/moderation"
+	"msg_server/server/common/transport/wsconn"
 )
 
 type wsEnvelope struct {
@@ -30,25 +35,196 @@ type wsEnvelope struct {
 type RealtimeService struct {
 	tenantRedisRouter *cache.TenantRedisRouter
 	chat              *ChatService
+	queueCfg          wsconn.Config
+	metrics           *wsconn.Metrics
+	registry          *wsconn.Registry
+	signalRate        float64
+	signalBurst       float64
+	limits            *RateLimitService
 	mu                sync.RWMutex
 	rooms             map[string]*roomState
+	users             map[string]*roomState
 }
 
 type roomState struct {
-	conns  map[*websocket.Conn]struct{}
-	cancel context.CancelFunc
+	clients map[*wsClient]struct{}
+	cancel  context.CancelFunc
 }
 
-func NewRealtimeService(tenantRedisRouter *cache.TenantRedisRouter, chat *ChatService) *RealtimeService {
+// wsClient is one upgraded connection. In single-room mode (bound) it is
+// subscribed to exactly the room given at connect time; in multiplexed mode
+// the client manages its subscriptions with subscribe/unsubscribe commands.
+type wsClient struct {
+	conn        *wsconn.Conn
+	tenantID    string
+	userID      string
+	bound       bool
+	tenantAdmin bool
+
+	mu     sync.Mutex
+	rooms  map[string]struct{}
+	roles  map[string]domain.RoomRole
+	signal tokenBucket
+}
+
+func newWSClient(conn *wsconn.Conn, tenantID, userID string) *wsClient {
+	return &wsClient{conn: conn, tenantID: tenantID, userID: userID, rooms: map[string]struct{}{}, roles: map[string]domain.RoomRole{}}
+}
+
+// roomRole returns the cached role in a subscribed room. It is filled from
+// dbman on the first message and dropped on member.role_changed frames, so the
+// role itself never comes from a frame.
+func (c *wsClient) roomRole(roomID string) (domain.RoomRole, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	role, ok := c.roles[roomID]
+	return role, ok
+}
+
+func (c *wsClient) setRoomRole(roomID string, role domain.RoomRole) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.rooms[roomID]; ok {
+		c.roles[roomID] = role
+	}
+}
+
+func (c *wsClient) forgetRoomRole(roomID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.roles, roomID)
+}
+
+// tokenBucket limits signaling frames per connection. Guarded by wsClient.mu.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (c *wsClient) allowSignal(rate, burst float64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.signal.last.IsZero() {
+		c.signal.tokens = burst
+	} else {
+		c.signal.tokens = min(burst, c.signal.tokens+now.Sub(c.signal.last).Seconds()*rate)
+	}
+	c.signal.last = now
+	if c.signal.tokens < 1 {
+		return false
+	}
+	c.signal.tokens--
+	return true
+}
+
+func (c *wsClient) write(b []byte) bool {
+	return c.conn.Send(b)
+}
+
+func (c *wsClient) writeJSON(v any) {
+	b, _ := json.Marshal(v)
+	_ = c.write(b)
+}
+
+func (c *wsClient) writeError(message string) {
+	c.writeJSON(gin.H{"type": "error", "error": message})
+}
+
+func (c *wsClient) writeRateLimited(err *RateLimitError) {
+	c.writeJSON(gin.H{"type": "error", "error": err.Error(), "retry_after": middleware.RetryAfterSeconds(err.RetryAfter)})
+}
+
+func (c *wsClient) subscribed(roomID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.rooms[roomID]
+	return ok
+}
+
+func (c *wsClient) subscribedRooms() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, 0, len(c.rooms))
+	for roomID := range c.rooms {
+		out = append(out, roomID)
+	}
+	return out
+}
+
+const wsMaxSubscriptions = 1000
+
+const (
+	defaultSignalRate  = 20
+	defaultSignalBurst = 60
+)
+
+func NewRealtimeService(tenantRedisRouter *cache.TenantRedisRouter, chat *ChatService, queueCfg wsconn.Config) *RealtimeService {
 	return &RealtimeService{
 		tenantRedisRouter: tenantRedisRouter,
 		chat:              chat,
+		queueCfg:          queueCfg,
+		metrics:           wsconn.NewMetrics("chat"),
+		registry:          wsconn.NewRegistry("chat"),
+		signalRate:        defaultSignalRate,
+		signalBurst:       defaultSignalBurst,
 		rooms:             map[string]*roomState{},
+		users:             map[string]*roomState{},
+	}
+}
+
+// UseSignalRateLimit sets the per-connection WebRTC signaling budget in
+// frames per second with the given burst.
+func (s *RealtimeService) UseSignalRateLimit(perSecond, burst int) {
+	if perSecond > 0 {
+		s.signalRate = float64(perSecond)
+	}
+	if burst > 0 {
+		s.signalBurst = float64(burst)
+	}
+}
+
+// UseRateLimits applies the tenant's api limits to every client frame and its
+// message limits to message frames.
+func (s *RealtimeService) UseRateLimits(limits *RateLimitService) {
+	s.limits = limits
+}
+
+// allowFrame writes the error frame itself when the frame is limited.
+func (s *RealtimeService) allowFrame(ctx context.Context, client *wsClient, roomID string, action domain.RateLimitAction) bool {
+	if s.limits == nil || client.userID == "" {
+		return true
 	}
+	err := s.limits.Allow(ctx, client.tenantID, client.userID, roomID, action)
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		commonlog.Warnf("event=rate_limit action=throttle source=ws tenant_id=%s room_id=%s user_id=%s limit_action=%s", client.tenantID, roomID, client.userID, action)
+		client.writeRateLimited(limited)
+		return false
+	}
+	return true
+}
+
+func (s *RealtimeService) Metrics() wsconn.MetricsSnapshot {
+	return s.metrics.Snapshot()
+}
+
+func (s *RealtimeService) Draining() bool {
+	return s.registry.Draining()
+}
+
+// Drain refuses new upgrades and closes open sockets gradually so clients do
+// not all reconnect to the remaining instances at once.
+func (s *RealtimeService) Drain(ctx context.Context) {
+	s.registry.Drain(ctx, s.queueCfg)
 }
 
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
+// HandleWS serves both connection modes. With room_id the connection is bound
+// to that room (the caller has already checked membership). Without it the
+// connection is multiplexed: rooms are added with {"type":"subscribe"} /
+// {"type":"unsubscribe"} commands, or all of the user's rooms with auto_join=true.
 func (s *RealtimeService) HandleWS(c *gin.Context) {
 	tenantID := strings.TrimSpace(c.Query("tenant_id"))
 	if rawTenantID, ok := c.Get("auth_tenant_id"); ok {
@@ -65,9 +241,15 @@ func (s *RealtimeService) HandleWS(c *gin.Context) {
 			authUserID = strings.TrimSpace(userID)
 		}
 	}
+	authRole, _ := c.Get("auth_role")
+	if s.registry.Draining() {
+		c.JSON(503, gin.H{"error": "server is draining"})
+		return
+	}
 	roomID := parseInt64(c.Query("room_id"))
-	if strings.TrimSpace(roomID) == "" {
-		c.JSON(400, gin.H{"error": "room_id required"})
+	multiplexed := roomID == ""
+	if multiplexed && authUserID == "" {
+		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
 	redisClient, err := s.tenantRedisRouter.ClientForTenant(c.Request.Context(), tenantID)
@@ -75,89 +257,263 @@ func (s *RealtimeService) HandleWS(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	roomKey := fmt.Sprintf("%s:%s", tenantID, roomID)
-	channel := fmt.Sprintf("tenant:%s:room:%s", tenantID, roomID)
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	s.join(roomKey, channel, redisClient, conn)
-	defer s.leave(roomKey, conn)
+	out := wsconn.New(conn, s.queueCfg, s.metrics, fmt.Sprintf("tenant_id=%s user_id=%s", tenantID, authUserID))
+	s.registry.Add(out)
+	out.StartKeepalive()
+	client := newWSClient(out, tenantID, authUserID)
+	client.bound = !multiplexed
+	client.tenantAdmin = authRole == string(domain.UserRoleAdmin)
+	if authUserID != "" {
+		s.attachUser(client, redisClient)
+	}
+	defer s.disconnect(client)
 
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
+	if !multiplexed {
+		s.join(client, roomID, redisClient)
+	} else if autoJoin, _ := strconv.ParseBool(c.Query("auto_join")); autoJoin {
+		roomIDs, err := s.chat.ListMemberRoomIDs(ctx, tenantID, authUserID)
+		if err != nil {
+			commonlog.Errorf("event=chat_ws_subscribe action=auto_join status=failed tenant_id=%s user_id=%s error=%v", tenantID, authUserID, err)
+			client.writeError("failed to load rooms")
+		}
+		for i, id := range roomIDs {
+			if i >= wsMaxSubscriptions {
+				break
+			}
+			s.join(client, id, redisClient)
+		}
+		client.writeJSON(gin.H{"type": "subscribed", "room_ids": client.subscribedRooms()})
+	}
+
 	for {
 		_, raw, err := conn.ReadMessage()
 		if err != nil {
 			return
 		}
+		out.Touch()
 		var env wsEnvelope
 		if err := json.Unmarshal(raw, &env); err != nil {
 			continue
 		}
-		env.RoomID = roomID
 		if authUserID != "" {
 			env.UserID = authUserID
 		}
-		if env.Type == "message" {
-			if strings.TrimSpace(env.UserID) == "" {
-				writeWSError(conn, "unauthorized")
+		if !s.allowFrame(ctx, client, "", domain.RateLimitActionAPI) {
+			continue
+		}
+		switch env.Type {
+		case "subscribe", "unsubscribe":
+			if !multiplexed {
+				client.writeError("subscribe is only available on multiplexed connections")
 				continue
 			}
-			persistStartedAt := time.Now()
-			parsed, err := parseWSMessagePayload(env.Payload)
-			if err != nil {
-				writeWSError(conn, err.Error())
+			s.handleSubscription(ctx, client, redisClient, env)
+			continue
+		}
+		if multiplexed {
+			env.RoomID = strings.TrimSpace(env.RoomID)
+			if !client.subscribed(env.RoomID) {
+				client.writeError("room not subscribed")
 				continue
 			}
-			idempotencyKey := ""
-			if parsed.ClientMsgID != "" {
-				idempotencyKey = wsMessageIdempotencyKey(tenantID, roomID, env.UserID, parsed.ClientMsgID)
-				ok, err := redisClient.SetNX(ctx, idempotencyKey, "1", wsMessageIdempotencyTTL).Result()
-				if err != nil {
-					writeWSError(conn, "failed to process message")
-					continue
-				}
-				if !ok {
-					writeWSError(conn, "duplicate client_msg_id")
-					continue
-				}
-			}
-			created, err := s.chat.CreateMessage(ctx, domain.Message{
-				TenantID: tenantID,
-				RoomID:   roomID,
-				SenderID: env.UserID,
-				Body:     parsed.Body,
-				MetaJSON: BuildMessageMeta(parsed.FileID, parsed.FileIDs, parsed.Emojis),
-			})
+		} else {
+			env.RoomID = roomID
+		}
+		s.handleClientEvent(ctx, client, redisClient, env)
+	}
+}
+
+func (s *RealtimeService) handleSubscription(ctx context.Context, client *wsClient, redisClient *redis.Client, env wsEnvelope) {
+	roomID := strings.TrimSpace(env.RoomID)
+	if roomID == "" {
+		client.writeError("room_id required")
+		return
+	}
+	if env.Type == "unsubscribe" {
+		s.leave(client, roomID)
+		client.writeJSON(gin.H{"type": "unsubscribed", "room_id": roomID})
+		return
+	}
+	if client.subscribed(roomID) {
+		client.writeJSON(gin.H{"type": "subscribed", "room_id": roomID})
+		return
+	}
+	if len(client.subscribedRooms()) >= wsMaxSubscriptions {
+		client.writeError("too many subscriptions")
+		return
+	}
+	_, ok, err := s.chat.GetRoomMember(ctx, client.tenantID, roomID, client.userID)
+	if err != nil {
+		commonlog.Errorf("event=chat_room_access action=check status=failed source=ws_subscribe tenant_id=%s room_id=%s user_id=%s error=%v", client.tenantID, roomID, client.userID, err)
+		client.writeError("failed to subscribe")
+		return
+	}
+	if !ok {
+		commonlog.Warnf("event=chat_room_access action=deny source=ws_subscribe tenant_id=%s room_id=%s user_id=%s", client.tenantID, roomID, client.userID)
+		client.writeError("room access denied")
+		return
+	}
+	s.join(client, roomID, redisClient)
+	client.writeJSON(gin.H{"type": "subscribed", "room_id": roomID})
+}
+
+// clientRelayFrames are the client frame types relayed to the room as sent.
+// Every other frame on the room channel is published by the server, and
+// membershipFrames evicts connections and updates roles on the strength of
+// that, so clients must not be able to forge one.
+var clientRelayFrames = map[string]bool{"typing": true}
+
+func (s *RealtimeService) handleClientEvent(ctx context.Context, client *wsClient, redisClient *redis.Client, env wsEnvelope) {
+	tenantID := client.tenantID
+	roomID := env.RoomID
+	switch env.Type {
+	case "message", "webrtc_offer", "webrtc_answer", "webrtc_ice":
+	default:
+		if !clientRelayFrames[env.Type] {
+			commonlog.Warnf("event=chat_ws_frame action=reject tenant_id=%s room_id=%s user_id=%s type=%s", tenantID, roomID, env.UserID, env.Type)
+			client.writeError("unsupported event type")
+			return
+		}
+	}
+	if env.Type == "message" {
+		if strings.TrimSpace(env.UserID) == "" {
+			client.writeError("unauthorized")
+			return
+		}
+		if !s.allowFrame(ctx, client, roomID, domain.RateLimitActionMessage) {
+			return
+		}
+		allowed, err := s.canPost(ctx, client, roomID)
+		if err != nil {
+			commonlog.Errorf("event=chat_room_access action=check status=failed source=ws_message tenant_id=%s room_id=%s user_id=%s error=%v", tenantID, roomID, env.UserID, err)
+			client.writeError("failed to persist message")
+			return
+		}
+		if !allowed {
+			client.writeError(ErrRoomPermission.Error())
+			return
+		}
+		persistStartedAt := time.Now()
+		parsed, err := parseWSMessagePayload(env.Payload)
+		if err != nil {
+			client.writeError(err.Error())
+			return
+		}
+		idempotencyKey := ""
+		if parsed.ClientMsgID != "" {
+			idempotencyKey = wsMessageIdempotencyKey(tenantID, roomID, env.UserID, parsed.ClientMsgID)
+			ok, err := redisClient.SetNX(ctx, idempotencyKey, "1", wsMessageIdempotencyTTL).Result()
 			if err != nil {
-				commonlog.Errorf("event=chat_message_persist action=create status=failed source=ws tenant_id=%s room_id=%s user_id=%s client_msg_id_present=%t latency_ms=%d error=%v", tenantID, roomID, env.UserID, parsed.ClientMsgID != "", time.Since(persistStartedAt).Milliseconds(), err)
-				if idempotencyKey != "" {
-					_, _ = redisClient.Del(ctx, idempotencyKey).Result()
-				}
-				writeWSError(conn, "failed to persist message")
-				continue
+				client.writeError("failed to process message")
+				return
+			}
+			if !ok {
+				client.writeError("duplicate client_msg_id")
+				return
 			}
-			commonlog.Infof("event=chat_message_persist action=create status=ok source=ws tenant_id=%s room_id=%s user_id=%s message_id=%s client_msg_id_present=%t latency_ms=%d", tenantID, roomID, env.UserID, created.ID, parsed.ClientMsgID != "", time.Since(persistStartedAt).Milliseconds())
-			env.Payload = created
 		}
-		if env.Type == "webrtc_offer" || env.Type == "webrtc_answer" || env.Type == "webrtc_ice" {
-			env.Type = "signal_" + env.Type
+		created, err := s.chat.CreateMessage(ctx, domain.Message{
+			TenantID:        tenantID,
+			RoomID:          roomID,
+			SenderID:        env.UserID,
+			Body:            parsed.Body,
+			MetaJSON:        BuildMessageMeta(parsed.FileID, parsed.FileIDs, parsed.Emojis),
+			ParentMessageID: parsed.ParentMessageID,
+		})
+		if err != nil {
+			commonlog.Errorf("event=chat_message_persist action=create status=failed source=ws tenant_id=%s room_id=%s user_id=%s client_msg_id_present=%t latency_ms=%d error=%v", tenantID, roomID, env.UserID, parsed.ClientMsgID != "", time.Since(persistStartedAt).Milliseconds(), err)
+			if idempotencyKey != "" {
+				_, _ = redisClient.Del(ctx, idempotencyKey).Result()
+			}
+			var blocked *moderation.BlockedError
+			if errors.As(err, &blocked) {
+				client.writeError(blocked.Error())
+				return
+			}
+			if errors.Is(err, ErrRoomArchived) {
+				client.writeError(err.Error())
+				return
+			}
+			client.writeError("failed to persist message")
+			return
 		}
-		b, _ := json.Marshal(env)
-		_ = redisClient.Publish(ctx, channel, b).Err()
+		commonlog.Infof("event=chat_message_persist action=create status=ok source=ws tenant_id=%s room_id=%s user_id=%s message_id=%s client_msg_id_present=%t latency_ms=%d", tenantID, roomID, env.UserID, created.ID, parsed.ClientMsgID != "", time.Since(persistStartedAt).Milliseconds())
+		env.Payload = created
+		if created.ParentMessageID != nil {
+			env.Type = "thread.reply"
+			env.Payload = s.threadReplyEvent(ctx, created)
+		}
+	}
+	if env.Type == "webrtc_offer" || env.Type == "webrtc_answer" || env.Type == "webrtc_ice" {
+		s.handleSignal(ctx, client, redisClient, env)
+		return
+	}
+	b, _ := json.Marshal(env)
+	_ = redisClient.Publish(ctx, roomChannel(tenantID, roomID), b).Err()
+}
+
+// handleSignal delivers a WebRTC signaling frame only to the target user's
+// connections subscribed to the same room, on any instance.
+func (s *RealtimeService) handleSignal(ctx context.Context, client *wsClient, redisClient *redis.Client, env wsEnvelope) {
+	tenantID := client.tenantID
+	env.TargetID = strings.TrimSpace(env.TargetID)
+	if strings.TrimSpace(env.UserID) == "" {
+		client.writeError("unauthorized")
+		return
+	}
+	if env.TargetID == "" {
+		client.writeError("target_id required")
+		return
+	}
+	if env.TargetID == env.UserID {
+		client.writeError("invalid target_id")
+		return
+	}
+	if !client.allowSignal(s.signalRate, s.signalBurst) {
+		commonlog.Warnf("event=chat_ws_signal action=throttle tenant_id=%s room_id=%s user_id=%s type=%s", tenantID, env.RoomID, env.UserID, env.Type)
+		client.writeError("signal rate limit exceeded")
+		return
+	}
+	ok, err := s.isSignalTarget(ctx, tenantID, env.RoomID, env.TargetID)
+	if err != nil {
+		commonlog.Errorf("event=chat_room_access action=check status=failed source=ws_signal tenant_id=%s room_id=%s user_id=%s target_id=%s error=%v", tenantID, env.RoomID, env.UserID, env.TargetID, err)
+		client.writeError("failed to deliver signal")
+		return
+	}
+	if !ok {
+		commonlog.Warnf("event=chat_room_access action=deny source=ws_signal tenant_id=%s room_id=%s user_id=%s target_id=%s", tenantID, env.RoomID, env.UserID, env.TargetID)
+		client.writeError("target is not a room member")
+		return
 	}
+	env.Type = "signal_" + env.Type
+	b, _ := json.Marshal(env)
+	_ = redisClient.Publish(ctx, userChannel(tenantID, env.TargetID), b).Err()
+}
+
+// isSignalTarget checks membership on every frame rather than caching it, so
+// a removed member stops receiving signals immediately on every instance.
+// The per-connection signal rate limit bounds the dbman load.
+func (s *RealtimeService) isSignalTarget(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
+	_, ok, err := s.chat.GetRoomMember(ctx, tenantID, roomID, userID)
+	return ok, err
 }
 
 type wsMessagePayload struct {
-	ClientMsgID string   `json:"client_msg_id"`
-	Body        string   `json:"body"`
-	FileID      *string  `json:"file_id"`
-	FileIDs     []string `json:"file_ids"`
-	Emojis      []string `json:"emojis"`
+	ClientMsgID     string   `json:"client_msg_id"`
+	Body            string   `json:"body"`
+	FileID          *string  `json:"file_id"`
+	FileIDs         []string `json:"file_ids"`
+	Emojis          []string `json:"emojis"`
+	ParentMessageID *string  `json:"parent_message_id"`
 }
 
 func parseWSMessagePayload(payload any) (wsMessagePayload, error) {
@@ -182,13 +538,18 @@ func wsMessageIdempotencyKey(tenantID, roomID, userID, clientMsgID string) strin
 	return fmt.Sprintf("ws:message:idempotency:%s:%s:%s:%s", tenantID, roomID, userID, clientMsgID)
 }
 
-func writeWSError(conn *websocket.Conn, message string) {
-	b, _ := json.Marshal(gin.H{"type": "error", "error": message})
-	_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	_ = conn.WriteMessage(websocket.TextMessage, b)
+func roomChannel(tenantID, roomID string) string {
+	return fmt.Sprintf("tenant:%s:room:%s", tenantID, roomID)
+}
+
+func userChannel(tenantID, userID string) string {
+	return fmt.Sprintf("tenant:%s:user:%s", tenantID, userID)
 }
 
-func (s *RealtimeService) consumeRedis(ctx context.Context, roomKey, channel string, redisClient *redis.Client) {
+// consumeRedis relays a channel to the local clients registered under key in
+// states. filter, when set, decides per client whether a message is delivered;
+// after, when set, runs once the message has been handed to every client.
+func (s *RealtimeService) consumeRedis(ctx context.Context, states map[string]*roomState, key, channel string, redisClient *redis.Client, filter func(*wsClient, []byte) bool, after func([]byte, []*wsClient)) {
 	pubsub := redisClient.Subscribe(ctx, channel)
 	defer pubsub.Close()
 
@@ -198,43 +559,199 @@ func (s *RealtimeService) consumeRedis(ctx context.Context, roomKey, channel str
 			return
 		}
 		s.mu.RLock()
-		state := s.rooms[roomKey]
+		state := states[key]
 		if state == nil {
 			s.mu.RUnlock()
 			continue
 		}
-		for conn := range state.conns {
-			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			_ = conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload))
+		clients := make([]*wsClient, 0, len(state.clients))
+		for client := range state.clients {
+			clients = append(clients, client)
 		}
 		s.mu.RUnlock()
+
+		payload := []byte(msg.Payload)
+		for _, client := range clients {
+			if filter != nil && !filter(client, payload) {
+				continue
+			}
+			client.write(payload)
+		}
+		if after != nil {
+			after(payload, clients)
+		}
+	}
+}
+
+// Room lifecycle events. member.left covers both leaving and removal; the
+// payload's action tells them apart.
+const (
+	EventMemberJoined      = "member.joined"
+	EventMemberLeft        = "member.left"
+	EventMemberRoleChanged = "member.role_changed"
+	EventRoomUpdated       = "room.updated"
+	EventRoomDeleted       = "room.deleted"
+)
+
+// membershipFrames keeps local connections in step with membership frames
+// on the room channel. A member who left or was removed loses their
+// connections to the room, and everyone does once the room is deleted: bound
+// connections are closed, multiplexed ones are unsubscribed from the room
+// only. Role changes drop the cached role used for the post check, and the
+// next message re-reads it from dbman. Every
+// instance consumes the room channel, so this runs wherever the user is
+// connected.
+func (s *RealtimeService) membershipFrames(tenantID, roomID string) func([]byte, []*wsClient) {
+	return func(payload []byte, clients []*wsClient) {
+		if !bytes.Contains(payload, []byte(`"member.`)) && !bytes.Contains(payload, []byte(EventRoomDeleted)) {
+			return
+		}
+		var env struct {
+			Type    string `json:"type"`
+			Payload struct {
+				UserID string `json:"user_id"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(payload, &env); err != nil {
+			return
+		}
+		switch env.Type {
+		case EventMemberRoleChanged:
+			for _, client := range clients {
+				if client.userID == env.Payload.UserID {
+					client.forgetRoomRole(roomID)
+				}
+			}
+			return
+		case EventMemberLeft, EventRoomDeleted:
+		default:
+			return
+		}
+		removedID := env.Payload.UserID
+		if env.Type == EventRoomDeleted {
+			removedID = ""
+		}
+		for _, client := range clients {
+			if removedID != "" && client.userID != removedID {
+				continue
+			}
+			commonlog.Infof("event=chat_ws_evict action=%s tenant_id=%s room_id=%s user_id=%s bound=%t", env.Type, tenantID, roomID, client.userID, client.bound)
+			if client.bound {
+				client.conn.CloseWithReason(websocket.ClosePolicyViolation, "removed from room")
+				continue
+			}
+			s.leave(client, roomID)
+			client.writeJSON(gin.H{"type": "unsubscribed", "room_id": roomID})
+		}
+	}
+}
+
+// canPost checks the post permission of the client's role in the room.
+// Tenant admins may always post.
+func (s *RealtimeService) canPost(ctx context.Context, client *wsClient, roomID string) (bool, error) {
+	if client.tenantAdmin {
+		return true, nil
+	}
+	role, ok := client.roomRole(roomID)
+	if !ok {
+		member, isMember, err := s.chat.GetRoomMember(ctx, client.tenantID, roomID, client.userID)
+		if err != nil || !isMember {
+			return false, err
+		}
+		role = member.Role
+		client.setRoomRole(roomID, role)
+	}
+	return role.Can(domain.RoomPermPost), nil
+}
+
+// userFrame decides which of the user's connections get a user-channel frame.
+// Signals only reach connections subscribed to the frame's room. member.joined
+// and room.deleted reach the multiplexed connections that are not subscribed
+// to the room; subscribed ones get them from the room channel.
+func userFrame(client *wsClient, payload []byte) bool {
+	var env struct {
+		Type   string `json:"type"`
+		RoomID string `json:"room_id"`
+	}
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return false
+	}
+	switch env.Type {
+	case EventMemberJoined, EventRoomDeleted:
+		return !client.bound && !client.subscribed(env.RoomID)
+	}
+	return client.subscribed(env.RoomID)
+}
+
+func (s *RealtimeService) attachUser(client *wsClient, redisClient *redis.Client) {
+	userKey := fmt.Sprintf("%s:%s", client.tenantID, client.userID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.users[userKey]
+	if !ok {
+		userCtx, cancel := context.WithCancel(context.Background())
+		state = &roomState{clients: map[*wsClient]struct{}{}, cancel: cancel}
+		s.users[userKey] = state
+		go s.consumeRedis(userCtx, s.users, userKey, userChannel(client.tenantID, client.userID), redisClient, userFrame, nil)
+	}
+	state.clients[client] = struct{}{}
+}
+
+func (s *RealtimeService) detachUser(client *wsClient) {
+	userKey := fmt.Sprintf("%s:%s", client.tenantID, client.userID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.users[userKey]; ok {
+		delete(state.clients, client)
+		if len(state.clients) == 0 {
+			state.cancel()
+			delete(s.users, userKey)
+		}
 	}
 }
 
-func (s *RealtimeService) join(roomKey, channel string, redisClient *redis.Client, conn *websocket.Conn) {
+func (s *RealtimeService) join(client *wsClient, roomID string, redisClient *redis.Client) {
+	roomKey := fmt.Sprintf("%s:%s", client.tenantID, roomID)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	state, ok := s.rooms[roomKey]
 	if !ok {
 		roomCtx, cancel := context.WithCancel(context.Background())
-		state = &roomState{conns: map[*websocket.Conn]struct{}{}, cancel: cancel}
+		state = &roomState{clients: map[*wsClient]struct{}{}, cancel: cancel}
 		s.rooms[roomKey] = state
-		go s.consumeRedis(roomCtx, roomKey, channel, redisClient)
+		go s.consumeRedis(roomCtx, s.rooms, roomKey, roomChannel(client.tenantID, roomID), redisClient, nil, s.membershipFrames(client.tenantID, roomID))
 	}
-	state.conns[conn] = struct{}{}
+	state.clients[client] = struct{}{}
+	client.mu.Lock()
+	client.rooms[roomID] = struct{}{}
+	client.mu.Unlock()
 }
 
-func (s *RealtimeService) leave(roomKey string, conn *websocket.Conn) {
+func (s *RealtimeService) leave(client *wsClient, roomID string) {
+	roomKey := fmt.Sprintf("%s:%s", client.tenantID, roomID)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if state, ok := s.rooms[roomKey]; ok {
-		delete(state.conns, conn)
-		if len(state.conns) == 0 {
+		delete(state.clients, client)
+		if len(state.clients) == 0 {
 			state.cancel()
 			delete(s.rooms, roomKey)
 		}
 	}
-	_ = conn.Close()
+	client.mu.Lock()
+	delete(client.rooms, roomID)
+	delete(client.roles, roomID)
+	client.mu.Unlock()
+}
+
+func (s *RealtimeService) disconnect(client *wsClient) {
+	for _, roomID := range client.subscribedRooms() {
+		s.leave(client, roomID)
+	}
+	if client.userID != "" {
+		s.detachUser(client)
+	}
+	client.conn.Close()
 }
 
 func parseInt64(v string) string {
@@ -242,20 +759,85 @@ func parseInt64(v string) string {
 }
 
 func (s *RealtimeService) PublishMessage(ctx context.Context, tenantID, roomID, userID string, message domain.Message) error {
+	if message.ParentMessageID != nil {
+		return s.PublishEvent(ctx, tenantID, roomID, userID, "thread.reply", s.threadReplyEvent(ctx, message))
+	}
+	return s.PublishEvent(ctx, tenantID, roomID, userID, "message", message)
+}
+
+func (s *RealtimeService) threadReplyEvent(ctx context.Context, message domain.Message) domain.ThreadReplyEvent {
+	event := domain.ThreadReplyEvent{ParentMessageID: *message.ParentMessageID, Message: message}
+	summary, err := s.chat.ThreadSummary(ctx, message.TenantID, message.RoomID, *message.ParentMessageID)
+	if err != nil {
+		commonlog.Errorf("event=chat_thread_summary action=load status=failed tenant_id=%s room_id=%s parent_message_id=%s error=%v", message.TenantID, message.RoomID, *message.ParentMessageID, err)
+		return event
+	}
+	event.Thread = summary
+	return event
+}
+
+// PublishEvent fans an event out to every connection subscribed to the room channel.
+func (s *RealtimeService) PublishEvent(ctx context.Context, tenantID, roomID, userID, eventType string, payload any) error {
+	return s.publishRoomEvent(ctx, tenantID, roomID, userID, eventType, payload, nil)
+}
+
+// publishRoomEvent sends the frame to the room channel and to the user channel
+// of each of notifyIDs, which reaches their connections that are not
+// subscribed to the room.
+func (s *RealtimeService) publishRoomEvent(ctx context.Context, tenantID, roomID, userID, eventType string, payload any, notifyIDs []string) error {
 	redisClient, err := s.tenantRedisRouter.ClientForTenant(ctx, tenantID)
 	if err != nil {
 		return err
 	}
 	env := wsEnvelope{
-		Type:    "message",
+		Type:    eventType,
 		RoomID:  roomID,
 		UserID:  userID,
-		Payload: message,
+		Payload: payload,
 	}
 	b, err := json.Marshal(env)
 	if err != nil {
 		return err
 	}
-	channel := fmt.Sprintf("tenant:%s:room:%s", tenantID, roomID)
-	return redisClient.Publish(ctx, channel, b).Err()
+	if err := redisClient.Publish(ctx, roomChannel(tenantID, roomID), b).Err(); err != nil {
+		return err
+	}
+	for _, notifyID := range notifyIDs {
+		if err := redisClient.Publish(ctx, userChannel(tenantID, notifyID), b).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishMembership announces a membership change to the room. A user who
+// joined is told on their own channel too, since none of their connections
+// are subscribed to the room yet. For MembershipLeft and MembershipRemoved the
+// user's open connections to the room are evicted.
+func (s *RealtimeService) PublishMembership(ctx context.Context, tenantID, roomID, actorID, userID, action string) error {
+	eventType := EventMemberLeft
+	var notifyIDs []string
+	if action == domain.MembershipJoined {
+		eventType = EventMemberJoined
+		notifyIDs = []string{userID}
+	}
+	return s.publishRoomEvent(ctx, tenantID, roomID, actorID, eventType, domain.MembershipChange{
+		RoomID:    roomID,
+		UserID:    userID,
+		Action:    action,
+		ChangedAt: time.Now().UTC(),
+	}, notifyIDs)
+}
+
+// PublishRoomDeleted announces the deletion to the room and to every former
+// member's channel, so their connections that are not subscribed to the room
+// drop it as well.
+func (s *RealtimeService) PublishRoomDeleted(ctx context.Context, tenantID, roomID, actorID string, memberIDs []string) error {
+	return s.publishRoomEvent(ctx, tenantID, roomID, actorID, EventRoomDeleted, gin.H{"room_id": roomID, "member_ids": memberIDs}, memberIDs)
+}
+
+// PublishRoleChange announces a member's new role; connections of that member
+// re-read their role for the post check.
+func (s *RealtimeService) PublishRoleChange(ctx context.Context, tenantID, actorID string, member domain.RoomMember) error {
+	return s.PublishEvent(ctx, tenantID, member.RoomID, actorID, EventMemberRoleChanged, member)
 }
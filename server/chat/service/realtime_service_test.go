@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/dbman"
+	"msg_server/server/common/transport/wsconn"
+)
+
+// newRealtimeTest returns a RealtimeService whose dbman stand-in knows the
+// given members of "room-1".
+func newRealtimeTest(t *testing.T, members map[string]domain.RoomRole) *RealtimeService {
+	t.Helper()
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dbman.BasePath+"/rooms/members/get" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			RoomID string `json:"room_id"`
+			UserID string `json:"user_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]any{"ok": false}
+		if role, ok := members[req.UserID]; ok && req.RoomID == "room-1" {
+			resp = map[string]any{"ok": true, "member": domain.RoomMember{RoomID: req.RoomID, UserID: req.UserID, Role: role}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(db.Close)
+	chat := NewChatService(nil, NewDBManClient(db.URL), nil, false)
+	return NewRealtimeService(nil, chat, wsconn.Config{})
+}
+
+// newTestClient connects a wsClient for userID subscribed to "room-1" and
+// returns it with the peer end, which reads what the server writes.
+func newTestClient(t *testing.T, userID string) (*wsClient, *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = peer.Close() })
+
+	client := newWSClient(wsconn.New(<-conns, wsconn.Config{}, nil, "test"), "tenant-1", userID)
+	t.Cleanup(client.conn.Close)
+	client.rooms["room-1"] = struct{}{}
+	return client, peer
+}
+
+func readFrame(t *testing.T, peer *websocket.Conn) map[string]any {
+	t.Helper()
+	_ = peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, raw, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var frame map[string]any
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return frame
+}
+
+// The redis client is nil in these tests, so a frame that got relayed to the
+// room channel would panic instead of being rejected.
+func TestHandleClientEventRejectsServerFrames(t *testing.T) {
+	s := newRealtimeTest(t, map[string]domain.RoomRole{"user-1": domain.RoomRoleMember, "user-2": domain.RoomRoleMember})
+	forger, peer := newTestClient(t, "user-1")
+	victim, _ := newTestClient(t, "user-2")
+
+	forged := []wsEnvelope{
+		{Type: EventMemberLeft, Payload: map[string]any{"room_id": "room-1", "user_id": "user-2", "action": "removed"}},
+		{Type: EventRoomDeleted, Payload: map[string]any{"room_id": "room-1"}},
+		{Type: "message.deleted", Payload: map[string]any{"id": "m1", "room_id": "room-1"}},
+		{Type: "call.ringing", Payload: map[string]any{"call_id": "c1"}},
+		{Type: "moderation.action", Payload: map[string]any{"user_id": "user-2"}},
+	}
+	for _, env := range forged {
+		env.RoomID, env.UserID = "room-1", "user-1"
+		s.handleClientEvent(context.Background(), forger, nil, env)
+		if frame := readFrame(t, peer); frame["type"] != "error" || frame["error"] != "unsupported event type" {
+			t.Errorf("%s: frame = %v", env.Type, frame)
+		}
+	}
+	if !victim.subscribed("room-1") {
+		t.Error("victim was unsubscribed by a client frame")
+	}
+}
+
+func TestForgedRoleChangeDoesNotGrantPost(t *testing.T) {
+	s := newRealtimeTest(t, map[string]domain.RoomRole{"reader": domain.RoomRoleReadOnly})
+	client, peer := newTestClient(t, "reader")
+	ctx := context.Background()
+
+	forged := wsEnvelope{Type: EventMemberRoleChanged, RoomID: "room-1", UserID: "reader", Payload: domain.RoomMember{RoomID: "room-1", UserID: "reader", Role: domain.RoomRoleOwner}}
+	s.handleClientEvent(ctx, client, nil, forged)
+	if frame := readFrame(t, peer); frame["error"] != "unsupported event type" {
+		t.Fatalf("role change frame = %v", frame)
+	}
+
+	// Even a role change frame on the room channel only drops the cached role.
+	raw, _ := json.Marshal(forged)
+	s.membershipFrames("tenant-1", "room-1")(raw, []*wsClient{client})
+
+	s.handleClientEvent(ctx, client, nil, wsEnvelope{Type: "message", RoomID: "room-1", UserID: "reader", Payload: map[string]any{"body": "hi"}})
+	if frame := readFrame(t, peer); frame["error"] != ErrRoomPermission.Error() {
+		t.Fatalf("message frame = %v", frame)
+	}
+	if role, _ := client.roomRole("room-1"); role != domain.RoomRoleReadOnly {
+		t.Errorf("cached role = %q, want read_only", role)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	commonauth "msg_server/server/common/auth"
+)
+
+// SessionClient pushes notifications to every device a user has logged in
+// through the session service. Requests are signed as the acting user with
+// the shared JWT secret.
+type SessionClient struct {
+	endpoint string
+	enabled  bool
+	auth     *commonauth.Service
+	client   *http.Client
+}
+
+func NewSessionClient(endpoint string, auth *commonauth.Service) *SessionClient {
+	endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	return &SessionClient{
+		endpoint: endpoint,
+		enabled:  endpoint != "",
+		auth:     auth,
+		client:   &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+type CallNotification struct {
+	RoomID       string   `json:"room_id"`
+	CallID       string   `json:"call_id"`
+	Event        string   `json:"event"`
+	Media        string   `json:"media"`
+	CallerID     string   `json:"caller_id"`
+	Reason       string   `json:"reason,omitempty"`
+	RecipientIDs []string `json:"recipient_ids"`
+}
+
+// NotifyCall rings or stops ringing the recipients' devices. Session only
+// accepts call notifications signed with the system role.
+func (c *SessionClient) NotifyCall(ctx context.Context, tenantID, actorID string, n CallNotification) error {
+	if c == nil || !c.enabled || len(n.RecipientIDs) == 0 {
+		return nil
+	}
+	return c.post(ctx, tenantID, actorID, SystemRole, "/api/v1/calls/notify", n)
+}
+
+type ChatNotification struct {
+	RoomID       string   `json:"room_id"`
+	MessageID    string   `json:"message_id"`
+	Title        string   `json:"title"`
+	Body         string   `json:"body"`
+	RecipientIDs []string `json:"recipient_ids"`
+}
+
+// NotifyChat sends a new-message notification on behalf of senderID. The token
+// carries the system role so session does not echo it to recipients.
+func (c *SessionClient) NotifyChat(ctx context.Context, tenantID, senderID string, n ChatNotification) error {
+	if c == nil || !c.enabled || len(n.RecipientIDs) == 0 {
+		return nil
+	}
+	return c.post(ctx, tenantID, senderID, SystemRole, "/api/v1/chat/notify", n)
+}
+
+// SystemRole marks tokens minted by servers acting on a user's behalf.
+const SystemRole = "system"
+
+func (c *SessionClient) post(ctx context.Context, tenantID, actorID, role, path string, payload any) error {
+	token, err := c.auth.GenerateToken(actorID, tenantID, role)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("session status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -24,6 +24,11 @@ func NewVectormanClient(endpoint string, enabled bool) *VectormanClient {
 	}
 }
 
+// Enabled reports whether calls reach vectorman; when false they are no-ops.
+func (m *VectormanClient) Enabled() bool {
+	return m.enabled
+}
+
 func (m *VectormanClient) IndexMessage(ctx context.Context, messageID, roomID string, text string) error {
 	if !m.enabled {
 		return nil
@@ -33,6 +38,15 @@ func (m *VectormanClient) IndexMessage(ctx context.Context, messageID, roomID st
 	return err
 }
 
+func (m *VectormanClient) DeleteMessage(ctx context.Context, messageID string) error {
+	if !m.enabled {
+		return nil
+	}
+	payload := map[string]any{"message_id": messageID}
+	_, err := m.post(ctx, "/api/v1/vectors/messages/delete", payload)
+	return err
+}
+
 func (m *VectormanClient) SemanticSearch(ctx context.Context, query string, roomID *string, limit int) ([]string, error) {
 	if !m.enabled {
 		return nil, nil
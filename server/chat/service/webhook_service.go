@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/events"
+	"msg_server/server/common/netguard"
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrInvalidWebhookURL    = errors.New("target_url must be an absolute http or https URL")
+	ErrWebhookURLNotPublic  = errors.New("target_url must resolve to a public address")
+	ErrInvalidWebhookEvents = errors.New("event_types must list known event types or *")
+	ErrInvalidWebhookSecret = errors.New("secret must be at least 16 characters")
+)
+
+const minWebhookSecretLen = 16
+
+// WebhookService manages a tenant's outgoing webhook subscriptions. Delivery
+// itself runs in chatworker.
+type WebhookService struct {
+	dbman        *DBManClient
+	allowPrivate bool
+}
+
+func NewWebhookService(dbman *DBManClient) *WebhookService {
+	return &WebhookService{dbman: dbman}
+}
+
+// AllowPrivateTargets permits target URLs on loopback, private and link-local
+// addresses. Only for deployments whose receivers live on an internal network.
+func (s *WebhookService) AllowPrivateTargets(allow bool) {
+	s.allowPrivate = allow
+}
+
+// CreateWebhook registers a subscription. An empty secret is generated; the
+// secret is only ever returned here and by RotateWebhookSecret.
+func (s *WebhookService) CreateWebhook(ctx context.Context, tenantID, actorID, targetURL string, eventTypes []string, description, secret string) (domain.Webhook, error) {
+	targetURL, err := s.checkWebhookURL(ctx, targetURL)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	eventTypes, err = normalizeWebhookEvents(eventTypes)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	if secret, err = webhookSecret(secret); err != nil {
+		return domain.Webhook{}, err
+	}
+	return s.dbman.CreateWebhook(ctx, domain.Webhook{
+		TenantID:    tenantID,
+		TargetURL:   targetURL,
+		EventTypes:  eventTypes,
+		Secret:      secret,
+		Description: strings.TrimSpace(description),
+		CreatedBy:   actorID,
+	})
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, tenantID string) ([]domain.Webhook, error) {
+	return s.dbman.ListWebhooks(ctx, tenantID)
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, tenantID, webhookID string) (domain.Webhook, error) {
+	item, ok, err := s.dbman.GetWebhook(ctx, tenantID, webhookID)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	if !ok {
+		return domain.Webhook{}, ErrWebhookNotFound
+	}
+	return item, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, tenantID, webhookID string, targetURL *string, eventTypes []string, description *string, isActive *bool) (domain.Webhook, error) {
+	if targetURL != nil {
+		normalized, err := s.checkWebhookURL(ctx, *targetURL)
+		if err != nil {
+			return domain.Webhook{}, err
+		}
+		targetURL = &normalized
+	}
+	if eventTypes != nil {
+		normalized, err := normalizeWebhookEvents(eventTypes)
+		if err != nil {
+			return domain.Webhook{}, err
+		}
+		eventTypes = normalized
+	}
+	if description != nil {
+		trimmed := strings.TrimSpace(*description)
+		description = &trimmed
+	}
+	item, ok, err := s.dbman.UpdateWebhook(ctx, tenantID, webhookID, targetURL, eventTypes, description, isActive)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	if !ok {
+		return domain.Webhook{}, ErrWebhookNotFound
+	}
+	return item, nil
+}
+
+func (s *WebhookService) RotateWebhookSecret(ctx context.Context, tenantID, webhookID, secret string) (domain.Webhook, error) {
+	secret, err := webhookSecret(secret)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	item, ok, err := s.dbman.RotateWebhookSecret(ctx, tenantID, webhookID, secret)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	if !ok {
+		return domain.Webhook{}, ErrWebhookNotFound
+	}
+	return item, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, tenantID, webhookID string) error {
+	ok, err := s.dbman.DeleteWebhook(ctx, tenantID, webhookID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, tenantID, webhookID string, limit int, cursor string) ([]domain.WebhookDelivery, string, error) {
+	if _, err := s.GetWebhook(ctx, tenantID, webhookID); err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 30
+	}
+	var cursorCreatedAt *time.Time
+	var cursorID *string
+	if strings.TrimSpace(cursor) != "" {
+		createdAt, deliveryID, err := decodeRoomCursor(cursor)
+		if err != nil {
+			return nil, "", errors.New("cursor is invalid")
+		}
+		cursorCreatedAt = &createdAt
+		cursorID = &deliveryID
+	}
+	items, err := s.dbman.ListWebhookDeliveries(ctx, tenantID, webhookID, limit+1, cursorCreatedAt, cursorID)
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		nextCursor = encodeRoomCursor(last.CreatedAt.UTC(), last.ID)
+	}
+	return items, nextCursor, nil
+}
+
+// checkWebhookURL normalizes raw and, unless private targets are allowed,
+// rejects hosts that resolve to a non-public address. chatworker checks the
+// address again when it dials.
+func (s *WebhookService) checkWebhookURL(ctx context.Context, raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", ErrInvalidWebhookURL
+	}
+	if !s.allowPrivate {
+		if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrWebhookURLNotPublic, err)
+		}
+	}
+	return u.String(), nil
+}
+
+func normalizeWebhookEvents(eventTypes []string) ([]string, error) {
+	known := events.Types()
+	out := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if eventType != "*" && !slices.Contains(known, eventType) {
+			return nil, ErrInvalidWebhookEvents
+		}
+		if !slices.Contains(out, eventType) {
+			out = append(out, eventType)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+	return out, nil
+}
+
+func webhookSecret(secret string) (string, error) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		return "whsec_" + hex.EncodeToString(b[:]), nil
+	}
+	if len(secret) < minWebhookSecretLen {
+		return "", ErrInvalidWebhookSecret
+	}
+	return secret, nil
+}
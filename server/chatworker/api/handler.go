@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	chatdomain "msg_server/server/chat/domain"
+	workerservice "msg_server/server/chatworker/service"
+	commonauth "msg_server/server/common/auth"
+	"msg_server/server/common/middleware"
+	"msg_server/server/common/transport/httpresp"
+)
+
+type Handler struct {
+	manager *workerservice.Manager
+	auth    *commonauth.Service
+}
+
+func NewHandler(manager *workerservice.Manager, auth *commonauth.Service) *Handler {
+	return &Handler{manager: manager, auth: auth}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	// tenant_id selects a dedicated tenant's broker; omit it for the shared one.
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.AuthRequired(h.auth), middleware.RequireRoles(string(chatdomain.UserRoleAdmin)))
+	{
+		admin.GET("/dlq", h.listDLQ)
+		admin.GET("/dlq/:queue/messages", h.peekDLQ)
+		admin.POST("/dlq/:queue/replay", h.replayDLQ)
+	}
+}
+
+func dlqErrorStatus(err error) int {
+	if errors.Is(err, workerservice.ErrUnknownQueue) {
+		return http.StatusNotFound
+	}
+	return http.StatusServiceUnavailable
+}
+
+func (h *Handler) listDLQ(c *gin.Context) {
+	items, err := h.manager.BrokerFor(c.Query("tenant_id")).DLQStats()
+	if err != nil {
+		c.JSON(dlqErrorStatus(err), httpresp.NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) peekDLQ(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 200 {
+		limit = 20
+	}
+	items, err := h.manager.BrokerFor(c.Query("tenant_id")).PeekDLQ(c.Param("queue"), limit)
+	if err != nil {
+		c.JSON(dlqErrorStatus(err), httpresp.NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) replayDLQ(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id"`
+		Limit    int    `json:"limit"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
+			return
+		}
+	}
+	if req.Limit <= 0 || req.Limit > 1000 {
+		req.Limit = 100
+	}
+	moved, err := h.manager.BrokerFor(req.TenantID).ReplayDLQ(c.Request.Context(), c.Param("queue"), req.Limit)
+	if err != nil {
+		c.JSON(dlqErrorStatus(err), gin.H{"error": err.Error(), "replayed": moved})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": moved})
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	chatservice "msg_server/server/chat/service"
+	workerapi "msg_server/server/chatworker/api"
+	workerservice "msg_server/server/chatworker/service"
+	commonauth "msg_server/server/common/auth"
+	"msg_server/server/common/infra/cache"
+)
+
+type Config struct {
+	Port              string
+	RedisAddr         string
+	LavinMQURL        string
+	JWTSecret         string
+	JWTTTLMinutes     int
+	DBManEndpoints    []string
+	VectormanEndpoint string
+	VectorEnabled     bool
+	SessionEndpoint   string
+	Consumer          workerservice.Config
+	Webhooks          workerservice.WebhookConfig
+}
+
+type Server struct {
+	HTTPServer *http.Server
+	Redis      *redis.Client
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewServer(cfg Config) (*Server, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	redisClient := cache.NewClient(cfg.RedisAddr)
+	if err := cache.Ping(ctx, redisClient); err != nil {
+		return nil, fmt.Errorf("ping redis: %w", err)
+	}
+
+	auth := commonauth.NewService(cfg.JWTSecret, cfg.JWTTTLMinutes)
+	dbClient := chatservice.NewDBManClient(cfg.DBManEndpoints...)
+	handlers := []workerservice.Handler{
+		workerservice.NewIndexHandler(dbClient, chatservice.NewVectormanClient(cfg.VectormanEndpoint, cfg.VectorEnabled)),
+		workerservice.NewNotifyHandler(dbClient, chatservice.NewSessionClient(cfg.SessionEndpoint, commonauth.NewService(cfg.JWTSecret, 1))),
+		workerservice.NewWebhookHandler(dbClient),
+	}
+	manager := workerservice.NewManager(cfg.LavinMQURL, dbClient, handlers, cfg.Consumer, redisClient)
+	dispatcher := workerservice.NewWebhookDispatcher(dbClient, cfg.Webhooks)
+
+	h := workerapi.NewHandler(manager, auth)
+	r := gin.Default()
+	h.RegisterRoutes(r)
+
+	httpServer := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      r,
+		ReadTimeout:  20 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	s := &Server{HTTPServer: httpServer, Redis: redisClient, cancel: runCancel, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dispatcher.Run(runCtx)
+		}()
+		manager.Run(runCtx)
+		wg.Wait()
+	}()
+	return s, nil
+}
+
+// Shutdown stops consuming and webhook dispatch first; unacked deliveries return to their queues.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+	}
+	err := s.HTTPServer.Shutdown(ctx)
+	if s.Redis != nil {
+		_ = s.Redis.Close()
+	}
+	return err
+}
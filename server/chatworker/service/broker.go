@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
+
+	commonlog "msg_server/server/common/log"
+)
+
+const (
+	eventsExchange = "chat.events"
+
+	headerAttempts    = "x-attempts"
+	headerRoutingKey  = "x-original-routing-key"
+	headerLastError   = "x-last-error"
+	headerFailedAt    = "x-failed-at"
+	reconnectInterval = 5 * time.Second
+)
+
+type Config struct {
+	RetryDelays    []time.Duration
+	Prefetch       int
+	HandlerTimeout time.Duration
+	DedupeTTL      time.Duration
+}
+
+func (c Config) normalized() Config {
+	if len(c.RetryDelays) == 0 {
+		c.RetryDelays = []time.Duration{time.Second, 10 * time.Second, time.Minute, 5 * time.Minute}
+	}
+	if c.Prefetch <= 0 {
+		c.Prefetch = 32
+	}
+	if c.HandlerTimeout <= 0 {
+		c.HandlerTimeout = 10 * time.Second
+	}
+	if c.DedupeTTL <= 0 {
+		c.DedupeTTL = 24 * time.Hour
+	}
+	return c
+}
+
+// binding is one consumer queue: a handler subscribed to one event type.
+type binding struct {
+	handler   Handler
+	eventType string
+	queue     string
+}
+
+func (b binding) retryQueue(attempt int) string {
+	return b.queue + ".retry." + strconv.Itoa(attempt)
+}
+
+func (b binding) dlq() string {
+	return b.queue + ".dlq"
+}
+
+func queueName(handlerName, eventType string) string {
+	return "chatworker." + handlerName + "." + eventType
+}
+
+// Broker consumes chat.events on one LavinMQ (the shared one or a tenant's
+// dedicated one). Each handler/event pair gets its own queue, a retry queue per
+// backoff step that dead-letters back into it, and a DLQ for events that
+// exhausted their retries.
+type Broker struct {
+	name     string
+	url      string
+	bindings []binding
+	cfg      Config
+	redis    *redis.Client
+
+	mu   sync.RWMutex
+	conn *amqp.Connection
+}
+
+func NewBroker(name, url string, handlers []Handler, cfg Config, redisClient *redis.Client) *Broker {
+	b := &Broker{name: name, url: url, cfg: cfg.normalized(), redis: redisClient}
+	for _, h := range handlers {
+		for _, eventType := range h.EventTypes() {
+			b.bindings = append(b.bindings, binding{handler: h, eventType: eventType, queue: queueName(h.Name(), eventType)})
+		}
+	}
+	return b
+}
+
+// Run keeps the broker connected and consuming until ctx is done.
+func (b *Broker) Run(ctx context.Context) {
+	for {
+		err := b.runOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		commonlog.Warnf("event=chatworker_broker action=reconnect status=pending broker=%s error=%v", b.name, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+func (b *Broker) runOnce(ctx context.Context) error {
+	conn, err := amqp.Dial(b.url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	setup, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := b.declare(setup); err != nil {
+		_ = setup.Close()
+		return err
+	}
+	_ = setup.Close()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, bd := range b.bindings {
+		ch, err := conn.Channel()
+		if err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+		if err := ch.Qos(b.cfg.Prefetch, 0, false); err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+		deliveries, err := ch.ConsumeWithContext(runCtx, bd.queue, "", false, false, false, false, nil)
+		if err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+		wg.Add(1)
+		go func(bd binding, ch *amqp.Channel, deliveries <-chan amqp.Delivery) {
+			defer wg.Done()
+			defer ch.Close()
+			for d := range deliveries {
+				b.process(runCtx, ch, bd, d)
+			}
+		}(bd, ch, deliveries)
+	}
+
+	b.mu.Lock()
+	b.conn = conn
+	b.mu.Unlock()
+	commonlog.Infof("event=chatworker_broker action=connect status=ok broker=%s queues=%d", b.name, len(b.bindings))
+	defer func() {
+		b.mu.Lock()
+		b.conn = nil
+		b.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		cancel()
+		wg.Wait()
+		return nil
+	case amqpErr := <-closed:
+		cancel()
+		wg.Wait()
+		if amqpErr == nil {
+			return errors.New("connection closed")
+		}
+		return amqpErr
+	}
+}
+
+func (b *Broker) declare(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(eventsExchange, "topic", true, false, false, false, nil); err != nil {
+		return err
+	}
+	for _, bd := range b.bindings {
+		if _, err := ch.QueueDeclare(bd.queue, true, false, false, false, nil); err != nil {
+			return err
+		}
+		if err := ch.QueueBind(bd.queue, "#."+bd.eventType, eventsExchange, false, nil); err != nil {
+			return err
+		}
+		for i, delay := range b.cfg.RetryDelays {
+			if _, err := ch.QueueDeclare(bd.retryQueue(i), true, false, false, false, amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": bd.queue,
+			}); err != nil {
+				return err
+			}
+		}
+		if _, err := ch.QueueDeclare(bd.dlq(), true, false, false, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Broker) process(ctx context.Context, ch *amqp.Channel, bd binding, d amqp.Delivery) {
+	routingKey := d.RoutingKey
+	if v, ok := d.Headers[headerRoutingKey].(string); ok && v != "" {
+		routingKey = v
+	}
+	evt := Event{
+		ID:       d.MessageId,
+		TenantID: tenantFromRoutingKey(routingKey, bd.eventType),
+		Type:     bd.eventType,
+		Body:     d.Body,
+	}
+	if b.seen(ctx, bd.queue, evt) {
+		_ = d.Ack(false)
+		return
+	}
+
+	handleCtx, cancel := context.WithTimeout(ctx, b.cfg.HandlerTimeout)
+	err := bd.handler.Handle(handleCtx, evt)
+	cancel()
+	if err == nil {
+		b.markDone(ctx, bd.queue, evt)
+		_ = d.Ack(false)
+		return
+	}
+
+	attempts := headerInt(d.Headers, headerAttempts)
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerAttempts] = int32(attempts + 1)
+	headers[headerRoutingKey] = routingKey
+	headers[headerLastError] = truncate(err.Error(), 512)
+
+	target := bd.dlq()
+	if !errors.Is(err, ErrDropEvent) && attempts < len(b.cfg.RetryDelays) {
+		target = bd.retryQueue(attempts)
+	} else {
+		headers[headerFailedAt] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if pubErr := ch.PublishWithContext(ctx, "", target, false, false, amqp.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Headers:      headers,
+		Body:         d.Body,
+	}); pubErr != nil {
+		commonlog.Errorf("event=chatworker_consume action=reroute status=failed broker=%s queue=%s target=%s message_id=%s error=%v", b.name, bd.queue, target, d.MessageId, pubErr)
+		_ = d.Nack(false, true)
+		return
+	}
+	if target == bd.dlq() {
+		commonlog.Errorf("event=chatworker_consume action=dead_letter status=ok broker=%s queue=%s tenant_id=%s message_id=%s attempts=%d error=%v", b.name, bd.queue, evt.TenantID, d.MessageId, attempts+1, err)
+	} else {
+		commonlog.Warnf("event=chatworker_consume action=retry status=ok broker=%s queue=%s tenant_id=%s message_id=%s attempts=%d delay_ms=%d error=%v", b.name, bd.queue, evt.TenantID, d.MessageId, attempts+1, b.cfg.RetryDelays[attempts].Milliseconds(), err)
+	}
+	_ = d.Ack(false)
+}
+
+func (b *Broker) seen(ctx context.Context, queue string, evt Event) bool {
+	if b.redis == nil || evt.ID == "" {
+		return false
+	}
+	n, err := b.redis.Exists(ctx, dedupeKey(queue, evt)).Result()
+	return err == nil && n > 0
+}
+
+func (b *Broker) markDone(ctx context.Context, queue string, evt Event) {
+	if b.redis == nil || evt.ID == "" {
+		return
+	}
+	_ = b.redis.Set(ctx, dedupeKey(queue, evt), 1, b.cfg.DedupeTTL).Err()
+}
+
+// dedupeKey scopes the message id to the tenant: ids are only unique per
+// publisher, and dedicated tenant databases number their outbox rows
+// independently of the shared one.
+func dedupeKey(queue string, evt Event) string {
+	return "chatworker:done:" + queue + ":" + evt.TenantID + ":" + evt.ID
+}
+
+func tenantFromRoutingKey(routingKey, eventType string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(routingKey, eventType), ".")
+}
+
+func headerInt(headers amqp.Table, key string) int {
+	switch v := headers[key].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func (b *Broker) channel() (*amqp.Channel, error) {
+	b.mu.RLock()
+	conn := b.conn
+	b.mu.RUnlock()
+	if conn == nil || conn.IsClosed() {
+		return nil, fmt.Errorf("broker %s is not connected", b.name)
+	}
+	return conn.Channel()
+}
+
+func (b *Broker) binding(queue string) (binding, bool) {
+	for _, bd := range b.bindings {
+		if bd.queue == queue {
+			return bd, true
+		}
+	}
+	return binding{}, false
+}
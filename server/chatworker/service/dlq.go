@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrUnknownQueue = errors.New("unknown queue")
+
+type DLQStat struct {
+	Queue    string `json:"queue"`
+	DLQ      string `json:"dlq"`
+	Messages int    `json:"messages"`
+}
+
+type DeadLetter struct {
+	MessageID  string    `json:"message_id"`
+	RoutingKey string    `json:"routing_key"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error"`
+	FailedAt   string    `json:"failed_at"`
+	Timestamp  time.Time `json:"timestamp"`
+	Body       string    `json:"body"`
+}
+
+func (b *Broker) DLQStats() ([]DLQStat, error) {
+	ch, err := b.channel()
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+	items := make([]DLQStat, 0, len(b.bindings))
+	for _, bd := range b.bindings {
+		q, err := ch.QueueDeclarePassive(bd.dlq(), true, false, false, false, nil)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, DLQStat{Queue: bd.queue, DLQ: bd.dlq(), Messages: q.Messages})
+	}
+	return items, nil
+}
+
+// PeekDLQ returns up to limit dead letters without removing them; they are
+// requeued when the channel closes.
+func (b *Broker) PeekDLQ(queue string, limit int) ([]DeadLetter, error) {
+	bd, ok := b.binding(queue)
+	if !ok {
+		return nil, ErrUnknownQueue
+	}
+	ch, err := b.channel()
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+	items := make([]DeadLetter, 0, limit)
+	for len(items) < limit {
+		d, ok, err := ch.Get(bd.dlq(), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		items = append(items, deadLetterFrom(d))
+	}
+	return items, nil
+}
+
+// ReplayDLQ moves up to limit dead letters back onto their work queue with a
+// fresh retry budget and returns how many were moved.
+func (b *Broker) ReplayDLQ(ctx context.Context, queue string, limit int) (int, error) {
+	bd, ok := b.binding(queue)
+	if !ok {
+		return 0, ErrUnknownQueue
+	}
+	ch, err := b.channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return 0, err
+	}
+	moved := 0
+	for moved < limit {
+		d, ok, err := ch.Get(bd.dlq(), false)
+		if err != nil {
+			return moved, err
+		}
+		if !ok {
+			break
+		}
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		delete(headers, headerAttempts)
+		delete(headers, headerLastError)
+		delete(headers, headerFailedAt)
+		confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", bd.queue, false, false, amqp.Publishing{
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    d.MessageId,
+			Timestamp:    d.Timestamp,
+			Headers:      headers,
+			Body:         d.Body,
+		})
+		if err != nil {
+			_ = d.Nack(false, true)
+			return moved, err
+		}
+		if acked, err := confirm.WaitContext(ctx); err != nil || !acked {
+			_ = d.Nack(false, true)
+			if err == nil {
+				err = errors.New("replay publish was not confirmed")
+			}
+			return moved, err
+		}
+		if err := d.Ack(false); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+func deadLetterFrom(d amqp.Delivery) DeadLetter {
+	item := DeadLetter{
+		MessageID:  d.MessageId,
+		RoutingKey: d.RoutingKey,
+		Attempts:   headerInt(d.Headers, headerAttempts),
+		Timestamp:  d.Timestamp,
+		Body:       string(d.Body),
+	}
+	if v, ok := d.Headers[headerRoutingKey].(string); ok {
+		item.RoutingKey = v
+	}
+	if v, ok := d.Headers[headerLastError].(string); ok {
+		item.LastError = v
+	}
+	if v, ok := d.Headers[headerFailedAt].(string); ok {
+		item.FailedAt = v
+	}
+	return item
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	chatdomain "msg_server/server/chat/domain"
+	chatservice "msg_server/server/chat/service"
+	"msg_server/server/common/events"
+	"msg_server/server/common/trace"
+)
+
+// Event is one chat.events delivery as seen by a handler.
+type Event struct {
+	ID       string
+	TenantID string
+	Type     string
+	Body     []byte
+}
+
+// Handler processes one kind of side effect. Handle must be safe to repeat:
+// deliveries are at-least-once and dedupe is best effort.
+type Handler interface {
+	Name() string
+	EventTypes() []string
+	Handle(ctx context.Context, evt Event) error
+}
+
+// ErrDropEvent marks an event that can never succeed; it goes straight to the DLQ.
+var ErrDropEvent = errors.New("event cannot be processed")
+
+// messageEvent is the part of every message.* payload the handlers use.
+type messageEvent struct {
+	MessageID string
+	RoomID    string
+	SenderID  string
+	Body      string
+	System    bool
+}
+
+// decodeMessageEvent unwraps the envelope and returns its traceparent along
+// with the payload. Malformed or unknown events are dropped.
+func decodeMessageEvent(body []byte) (messageEvent, string, error) {
+	env, err := events.Decode(body)
+	if err != nil {
+		return messageEvent{}, "", fmt.Errorf("%w: %v", ErrDropEvent, err)
+	}
+	var msg messageEvent
+	switch env.Type {
+	case events.TypeMessageCreated:
+		var data events.MessageCreated
+		err = env.DecodeData(&data)
+		msg = messageEvent{MessageID: data.MessageID, RoomID: data.RoomID, SenderID: data.SenderID, Body: data.Body, System: data.System}
+	case events.TypeMessageUpdated:
+		var data events.MessageUpdated
+		err = env.DecodeData(&data)
+		msg = messageEvent{MessageID: data.MessageID, RoomID: data.RoomID, SenderID: data.SenderID, Body: data.Body}
+	case events.TypeMessageDeleted:
+		var data events.MessageDeleted
+		err = env.DecodeData(&data)
+		msg = messageEvent{MessageID: data.MessageID, RoomID: data.RoomID, SenderID: data.SenderID}
+	default:
+		err = fmt.Errorf("unexpected event type %s", env.Type)
+	}
+	if err != nil {
+		return messageEvent{}, "", fmt.Errorf("%w: %v", ErrDropEvent, err)
+	}
+	if strings.TrimSpace(msg.MessageID) == "" {
+		return messageEvent{}, "", fmt.Errorf("%w: missing message_id", ErrDropEvent)
+	}
+	return msg, env.TraceParent, nil
+}
+
+// IndexHandler keeps the vector index in step with message changes. Events
+// can arrive late, e.g. a created event redelivered after the delete, so
+// created and updated events index the message as dbman has it now and
+// remove it from the index once it is deleted.
+type IndexHandler struct {
+	dbman  *chatservice.DBManClient
+	vector *chatservice.VectormanClient
+}
+
+func NewIndexHandler(dbman *chatservice.DBManClient, vector *chatservice.VectormanClient) *IndexHandler {
+	return &IndexHandler{dbman: dbman, vector: vector}
+}
+
+func (h *IndexHandler) Name() string { return "index" }
+
+func (h *IndexHandler) EventTypes() []string {
+	return []string{"message.created", "message.updated", "message.deleted"}
+}
+
+func (h *IndexHandler) Handle(ctx context.Context, evt Event) error {
+	msg, _, err := decodeMessageEvent(evt.Body)
+	if err != nil {
+		return err
+	}
+	if !h.vector.Enabled() {
+		return nil
+	}
+	if evt.Type == "message.deleted" {
+		return h.vector.DeleteMessage(ctx, msg.MessageID)
+	}
+	current, live, err := h.liveMessage(ctx, evt.TenantID, msg)
+	if err != nil || !live {
+		return err
+	}
+	if err := h.vector.IndexMessage(ctx, current.ID, current.RoomID, current.Body); err != nil {
+		return err
+	}
+	// A delete that committed while we were writing may have had its event
+	// handled already, so look once more and undo the write if it did.
+	_, _, err = h.liveMessage(ctx, evt.TenantID, msg)
+	return err
+}
+
+// liveMessage reads the message from dbman. When it is gone or deleted it is
+// removed from the index and live is false.
+func (h *IndexHandler) liveMessage(ctx context.Context, tenantID string, msg messageEvent) (chatdomain.Message, bool, error) {
+	current, ok, err := h.dbman.GetMessage(ctx, tenantID, msg.RoomID, msg.MessageID)
+	if err != nil {
+		return chatdomain.Message{}, false, err
+	}
+	if !ok || current.DeletedAt != nil {
+		return chatdomain.Message{}, false, h.vector.DeleteMessage(ctx, msg.MessageID)
+	}
+	return current, true, nil
+}
+
+// NotifyHandler pushes new messages to every other room member's devices
+// through the session service. System messages are skipped: whatever posted
+// them has notified the devices already.
+type NotifyHandler struct {
+	dbman   *chatservice.DBManClient
+	session *chatservice.SessionClient
+}
+
+func NewNotifyHandler(dbman *chatservice.DBManClient, session *chatservice.SessionClient) *NotifyHandler {
+	return &NotifyHandler{dbman: dbman, session: session}
+}
+
+func (h *NotifyHandler) Name() string { return "notify" }
+
+func (h *NotifyHandler) EventTypes() []string {
+	return []string{"message.created"}
+}
+
+func (h *NotifyHandler) Handle(ctx context.Context, evt Event) error {
+	msg, traceParent, err := decodeMessageEvent(evt.Body)
+	if err != nil {
+		return err
+	}
+	if msg.System {
+		return nil
+	}
+	if traceParent != "" {
+		ctx = trace.WithParent(ctx, traceParent)
+	}
+	members, err := h.dbman.ListRoomMemberIDs(ctx, evt.TenantID, msg.RoomID)
+	if err != nil {
+		return err
+	}
+	recipients := make([]string, 0, len(members))
+	for _, userID := range members {
+		if userID != msg.SenderID {
+			recipients = append(recipients, userID)
+		}
+	}
+	return h.session.NotifyChat(ctx, evt.TenantID, msg.SenderID, chatservice.ChatNotification{
+		RoomID:       msg.RoomID,
+		MessageID:    msg.MessageID,
+		Body:         msg.Body,
+		RecipientIDs: recipients,
+	})
+}
+
+// WebhookHandler queues every event for the tenant's matching webhooks; the
+// WebhookDispatcher sends them.
+type WebhookHandler struct {
+	dbman *chatservice.DBManClient
+}
+
+func NewWebhookHandler(dbman *chatservice.DBManClient) *WebhookHandler {
+	return &WebhookHandler{dbman: dbman}
+}
+
+func (h *WebhookHandler) Name() string { return "webhook" }
+
+func (h *WebhookHandler) EventTypes() []string {
+	return events.Types()
+}
+
+func (h *WebhookHandler) Handle(ctx context.Context, evt Event) error {
+	env, err := events.Decode(evt.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDropEvent, err)
+	}
+	tenantID := evt.TenantID
+	if tenantID == "" {
+		tenantID = env.TenantID
+	}
+	if tenantID == "" {
+		return nil
+	}
+	// Legacy bodies have no envelope id; the outbox id is stable as well.
+	eventID := env.ID
+	if eventID == "" {
+		eventID = evt.ID
+	}
+	if eventID == "" {
+		return fmt.Errorf("%w: missing event id", ErrDropEvent)
+	}
+	_, err = h.dbman.EnqueueWebhookDeliveries(ctx, tenantID, eventID, evt.Type, evt.Body)
+	return err
+}
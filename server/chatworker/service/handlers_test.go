@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chatdomain "msg_server/server/chat/domain"
+	chatservice "msg_server/server/chat/service"
+	commonauth "msg_server/server/common/auth"
+	"msg_server/server/common/events"
+	"msg_server/server/common/infra/dbman"
+)
+
+func TestNotifyHandlerSkipsSystemMessages(t *testing.T) {
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dbman.BasePath+"/rooms/members/list" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]string{"caller", "callee"})
+	}))
+	defer db.Close()
+	var pushes atomic.Int32
+	session := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer session.Close()
+	h := NewNotifyHandler(chatservice.NewDBManClient(db.URL), chatservice.NewSessionClient(session.URL, commonauth.NewService("test-secret", 5)))
+
+	for _, system := range []bool{true, false} {
+		env, err := events.New("tenant-1", events.MessageCreated{MessageID: "m1", RoomID: "room-1", SenderID: "caller", Body: "missed call", System: system}, "")
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		body, _ := json.Marshal(env)
+		if err := h.Handle(context.Background(), Event{ID: env.ID, TenantID: "tenant-1", Type: env.Type, Body: body}); err != nil {
+			t.Fatalf("system=%t: handle: %v", system, err)
+		}
+	}
+	if got := pushes.Load(); got != 1 {
+		t.Errorf("session pushes = %d, want 1 for the user message only", got)
+	}
+}
+
+// indexTest serves one message from a dbman stand-in and records the calls
+// that reach a vectorman stand-in as "index:<text>" or "delete".
+type indexTest struct {
+	mu      sync.Mutex
+	message chatdomain.Message
+	found   bool
+	// deleteOnIndex deletes the message as the index write lands, like a
+	// delete committing while the handler is writing.
+	deleteOnIndex bool
+	calls         []string
+}
+
+func newIndexTest(t *testing.T, it *indexTest) *IndexHandler {
+	t.Helper()
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dbman.BasePath+"/messages/get" {
+			http.NotFound(w, r)
+			return
+		}
+		it.mu.Lock()
+		defer it.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": it.found, "message": it.message})
+	}))
+	t.Cleanup(db.Close)
+	vector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		it.mu.Lock()
+		switch r.URL.Path {
+		case "/api/v1/vectors/messages/index":
+			it.calls = append(it.calls, "index:"+req.Text)
+			if it.deleteOnIndex {
+				now := time.Now()
+				it.message.DeletedAt = &now
+			}
+		case "/api/v1/vectors/messages/delete":
+			it.calls = append(it.calls, "delete")
+		}
+		it.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	}))
+	t.Cleanup(vector.Close)
+	return NewIndexHandler(chatservice.NewDBManClient(db.URL), chatservice.NewVectormanClient(vector.URL, true))
+}
+
+func TestIndexHandlerSkipsStaleWrites(t *testing.T) {
+	deleted := time.Now()
+	cases := []struct {
+		name string
+		it   *indexTest
+		want []string
+	}{
+		{"deleted before the event", &indexTest{message: chatdomain.Message{ID: "m1", RoomID: "room-1", DeletedAt: &deleted}, found: true}, []string{"delete"}},
+		{"gone", &indexTest{}, []string{"delete"}},
+		{"edited since the event", &indexTest{message: chatdomain.Message{ID: "m1", RoomID: "room-1", Body: "edited"}, found: true}, []string{"index:edited"}},
+		{"deleted during the write", &indexTest{message: chatdomain.Message{ID: "m1", RoomID: "room-1", Body: "hello"}, found: true, deleteOnIndex: true}, []string{"index:hello", "delete"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newIndexTest(t, tc.it)
+			env, err := events.New("tenant-1", events.MessageCreated{MessageID: "m1", RoomID: "room-1", SenderID: "user-1", Body: "hello"}, "")
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+			body, _ := json.Marshal(env)
+			if err := h.Handle(context.Background(), Event{ID: env.ID, TenantID: "tenant-1", Type: env.Type, Body: body}); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+			tc.it.mu.Lock()
+			defer tc.it.mu.Unlock()
+			if len(tc.it.calls) != len(tc.want) {
+				t.Fatalf("vectorman calls = %v, want %v", tc.it.calls, tc.want)
+			}
+			for i := range tc.want {
+				if tc.it.calls[i] != tc.want[i] {
+					t.Fatalf("vectorman calls = %v, want %v", tc.it.calls, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	chatservice "msg_server/server/chat/service"
+	commonlog "msg_server/server/common/log"
+)
+
+const tenantRefreshInterval = time.Minute
+
+// Manager runs one Broker for the shared LavinMQ and one per distinct
+// dedicated LavinMQ URL, picking up newly configured tenants periodically.
+type Manager struct {
+	dbman    *chatservice.DBManClient
+	handlers []Handler
+	cfg      Config
+	redis    *redis.Client
+
+	mu        sync.RWMutex
+	shared    *Broker
+	dedicated map[string]*Broker
+	tenantURL map[string]string
+	wg        sync.WaitGroup
+}
+
+func NewManager(sharedURL string, dbman *chatservice.DBManClient, handlers []Handler, cfg Config, redisClient *redis.Client) *Manager {
+	return &Manager{
+		shared:    NewBroker("shared", sharedURL, handlers, cfg, redisClient),
+		dbman:     dbman,
+		handlers:  handlers,
+		cfg:       cfg,
+		redis:     redisClient,
+		dedicated: map[string]*Broker{},
+		tenantURL: map[string]string{},
+	}
+}
+
+func (m *Manager) Run(ctx context.Context) {
+	m.start(ctx, m.shared)
+
+	ticker := time.NewTicker(tenantRefreshInterval)
+	defer ticker.Stop()
+	for {
+		m.refreshTenants(ctx)
+		select {
+		case <-ctx.Done():
+			m.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) start(ctx context.Context, b *Broker) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		b.Run(ctx)
+	}()
+}
+
+func (m *Manager) refreshTenants(ctx context.Context) {
+	tenants, err := m.dbman.ListTenants(ctx)
+	if err != nil {
+		commonlog.Warnf("event=chatworker_tenants action=refresh status=failed error=%v", err)
+		return
+	}
+	tenantURL := map[string]string{}
+	for _, t := range tenants {
+		url := strings.TrimSpace(t.DedicatedLavinMQURL)
+		if !t.IsActive || !strings.EqualFold(t.DeploymentMode, "dedicated") || url == "" {
+			continue
+		}
+		tenantURL[t.TenantID] = url
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tenantURL = tenantURL
+	for tenantID, url := range tenantURL {
+		if _, ok := m.dedicated[url]; ok {
+			continue
+		}
+		b := NewBroker("dedicated:"+tenantID, url, m.handlers, m.cfg, m.redis)
+		m.dedicated[url] = b
+		m.start(ctx, b)
+	}
+}
+
+// BrokerFor returns the broker carrying tenantID's events; an empty tenantID
+// selects the shared broker.
+func (m *Manager) BrokerFor(tenantID string) *Broker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if url, ok := m.tenantURL[strings.TrimSpace(tenantID)]; ok {
+		if b, ok := m.dedicated[url]; ok {
+			return b
+		}
+	}
+	return m.shared
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"msg_server/server/chat/domain"
+	chatservice "msg_server/server/chat/service"
+	"msg_server/server/common/events"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/netguard"
+)
+
+// Headers sent with every webhook delivery. The signature is
+// "v1=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	WebhookHeaderID        = "X-Webhook-Id"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+
+	webhookResponseLimit = 1024
+)
+
+type WebhookConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// Concurrency deliveries of one tenant's batch are sent in parallel.
+	Concurrency int
+	// Lease is raised to cover a whole batch: every round of Concurrency
+	// deliveries may take up to Timeout.
+	Lease       time.Duration
+	Timeout     time.Duration
+	MaxAttempts int
+	MaxBackoff  time.Duration
+	// DisableAfter consecutive failed attempts deactivate the webhook.
+	DisableAfter int
+	// AllowPrivateTargets lets deliveries connect to loopback, private and
+	// link-local addresses.
+	AllowPrivateTargets bool
+}
+
+func (c WebhookConfig) normalized() WebhookConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 50
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 10
+	}
+	if c.Concurrency > c.BatchSize {
+		c.Concurrency = c.BatchSize
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	rounds := (c.BatchSize + c.Concurrency - 1) / c.Concurrency
+	if minLease := time.Duration(rounds+1) * c.Timeout; c.Lease < minLease {
+		c.Lease = minLease
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 8
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = time.Hour
+	}
+	if c.DisableAfter <= 0 {
+		c.DisableAfter = 20
+	}
+	return c
+}
+
+// WebhookDispatcher polls every active tenant for due webhook deliveries,
+// POSTs the CloudEvents envelope to the target and records the outcome.
+// Non-2xx responses and transport errors are retried with exponential backoff
+// until MaxAttempts. Tenants are dispatched independently so one slow
+// endpoint only holds up its own tenant.
+type WebhookDispatcher struct {
+	dbman *chatservice.DBManClient
+	http  *http.Client
+	cfg   WebhookConfig
+
+	mu   sync.Mutex
+	busy map[string]struct{}
+}
+
+func NewWebhookDispatcher(dbman *chatservice.DBManClient, cfg WebhookConfig) *WebhookDispatcher {
+	cfg = cfg.normalized()
+	dialer := netguard.Dialer(cfg.Timeout)
+	if cfg.AllowPrivateTargets {
+		dialer.Control = nil
+	}
+	return &WebhookDispatcher{
+		dbman: dbman,
+		http: &http.Client{
+			Timeout: cfg.Timeout,
+			// Targets are dialed directly, never through a proxy, so the
+			// dialer's address check sees the real destination.
+			Transport: &http.Transport{
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   cfg.Timeout,
+				ResponseHeaderTimeout: cfg.Timeout,
+				MaxIdleConnsPerHost:   2,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			// A redirect is reported as the response instead of being followed.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		cfg:  cfg,
+		busy: map[string]struct{}{},
+	}
+}
+
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tenants, err := d.dbman.ListTenants(ctx)
+		if err != nil {
+			commonlog.Warnf("event=chatworker_webhook action=list_tenants status=failed error=%v", err)
+			continue
+		}
+		for _, tenant := range tenants {
+			if ctx.Err() != nil {
+				return
+			}
+			if !tenant.IsActive || !d.startTenant(tenant.TenantID) {
+				continue
+			}
+			wg.Add(1)
+			go func(tenantID string) {
+				defer wg.Done()
+				defer d.finishTenant(tenantID)
+				d.dispatchTenant(ctx, tenantID)
+			}(tenant.TenantID)
+		}
+	}
+}
+
+// startTenant reports false while a previous tick is still dispatching the
+// tenant.
+func (d *WebhookDispatcher) startTenant(tenantID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.busy[tenantID]; ok {
+		return false
+	}
+	d.busy[tenantID] = struct{}{}
+	return true
+}
+
+func (d *WebhookDispatcher) finishTenant(tenantID string) {
+	d.mu.Lock()
+	delete(d.busy, tenantID)
+	d.mu.Unlock()
+}
+
+// dispatchTenant sends each claimed batch with up to Concurrency requests in
+// flight, so the whole batch finishes within its lease.
+func (d *WebhookDispatcher) dispatchTenant(ctx context.Context, tenantID string) {
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	for {
+		deliveries, err := d.dbman.ClaimWebhookDeliveries(ctx, tenantID, d.cfg.BatchSize, d.cfg.Lease)
+		if err != nil {
+			commonlog.Warnf("event=chatworker_webhook action=claim status=failed tenant_id=%s error=%v", tenantID, err)
+			return
+		}
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(delivery domain.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.deliver(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+		if len(deliveries) < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery domain.WebhookDelivery) {
+	attempt := domain.WebhookAttempt{
+		TenantID:     delivery.TenantID,
+		DeliveryID:   delivery.ID,
+		WebhookID:    delivery.WebhookID,
+		DisableAfter: d.cfg.DisableAfter,
+	}
+	start := time.Now()
+	status, body, err := d.send(ctx, delivery)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	attempt.ResponseStatus = status
+	attempt.ResponseBody = body
+	switch {
+	case err != nil:
+		attempt.Error = err.Error()
+	case status < 200 || status >= 300:
+		attempt.Error = "unexpected status " + strconv.Itoa(status)
+	default:
+		attempt.Succeeded = true
+	}
+
+	switch {
+	case attempt.Succeeded:
+		attempt.Status = domain.WebhookDeliverySucceeded
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		attempt.Status = domain.WebhookDeliveryFailed
+	default:
+		attempt.Status = domain.WebhookDeliveryPending
+		next := time.Now().Add(d.backoff(delivery.Attempts))
+		attempt.NextAttemptAt = &next
+	}
+
+	disabled, err := d.dbman.RecordWebhookAttempt(ctx, attempt)
+	if err != nil {
+		// The lease runs out and the delivery is attempted again.
+		commonlog.Errorf("event=chatworker_webhook action=record status=failed tenant_id=%s webhook_id=%s delivery_id=%s error=%v", delivery.TenantID, delivery.WebhookID, delivery.ID, err)
+		return
+	}
+	if !attempt.Succeeded {
+		commonlog.Warnf("event=chatworker_webhook action=deliver status=failed tenant_id=%s webhook_id=%s delivery_id=%s event_type=%s attempts=%d delivery_status=%s error=%s", delivery.TenantID, delivery.WebhookID, delivery.ID, delivery.EventType, delivery.Attempts, attempt.Status, attempt.Error)
+	}
+	if disabled {
+		commonlog.Warnf("event=chatworker_webhook action=disable status=ok tenant_id=%s webhook_id=%s consecutive_failures=%d", delivery.TenantID, delivery.WebhookID, d.cfg.DisableAfter)
+	}
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, delivery domain.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.TargetURL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", events.ContentType)
+	req.Header.Set("User-Agent", "msg_server-webhooks/1")
+	req.Header.Set(WebhookHeaderID, delivery.WebhookID)
+	req.Header.Set(WebhookHeaderDelivery, delivery.ID)
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, string(body), nil
+}
+
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := 10 * time.Second
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// SignWebhook returns the X-Webhook-Signature value for body sent at
+// timestamp. Receivers recompute it and compare in constant time.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.", timestamp)
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"msg_server/server/chat/domain"
+	chatservice "msg_server/server/chat/service"
+	"msg_server/server/common/infra/dbman"
+)
+
+// webhookDB stands in for the dbman claim and attempt endpoints with a single
+// webhook and delivery, following the repository's rules: a claim counts the
+// attempt, and DisableAfter consecutive failures disable the webhook.
+type webhookDB struct {
+	mu       sync.Mutex
+	delivery domain.WebhookDelivery
+	active   bool
+	failures int
+	attempts []domain.WebhookAttempt
+}
+
+func newWebhookDB(t *testing.T, targetURL string) (*webhookDB, *chatservice.DBManClient) {
+	t.Helper()
+	db := &webhookDB{
+		active: true,
+		delivery: domain.WebhookDelivery{
+			TenantID:  "tenant-1",
+			ID:        "delivery-1",
+			WebhookID: "webhook-1",
+			EventID:   "event-1",
+			EventType: "message.created",
+			Payload:   json.RawMessage(`{"specversion":"1.0","id":"event-1","type":"message.created"}`),
+			Status:    domain.WebhookDeliveryPending,
+			TargetURL: targetURL,
+			Secret:    "whsec-test",
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(db.serve))
+	t.Cleanup(srv.Close)
+	return db, chatservice.NewDBManClient(srv.URL)
+}
+
+func (db *webhookDB) serve(w http.ResponseWriter, r *http.Request) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case dbman.BasePath + "/webhooks/deliveries/claim":
+		items := []domain.WebhookDelivery{}
+		if db.active && db.delivery.Status == domain.WebhookDeliveryPending {
+			db.delivery.Attempts++
+			items = append(items, db.delivery)
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	case dbman.BasePath + "/webhooks/deliveries/attempt":
+		var attempt domain.WebhookAttempt
+		if err := json.NewDecoder(r.Body).Decode(&attempt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		db.attempts = append(db.attempts, attempt)
+		db.delivery.Status = attempt.Status
+		disabled := false
+		if attempt.Succeeded {
+			db.failures = 0
+		} else {
+			db.failures++
+			if db.active && attempt.DisableAfter > 0 && db.failures >= attempt.DisableAfter {
+				db.active = false
+				disabled = true
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]bool{"disabled": disabled})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (db *webhookDB) recorded() []domain.WebhookAttempt {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]domain.WebhookAttempt(nil), db.attempts...)
+}
+
+func TestWebhookDispatcherSignsDelivery(t *testing.T) {
+	var got http.Header
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	db, client := newWebhookDB(t, receiver.URL)
+	d := NewWebhookDispatcher(client, WebhookConfig{AllowPrivateTargets: true})
+	d.dispatchTenant(context.Background(), "tenant-1")
+
+	want := SignWebhook("whsec-test", got.Get(WebhookHeaderTimestamp), db.delivery.Payload)
+	if !hmac.Equal([]byte(got.Get(WebhookHeaderSignature)), []byte(want)) {
+		t.Errorf("signature = %q, want %q", got.Get(WebhookHeaderSignature), want)
+	}
+	if string(body) != string(db.delivery.Payload) {
+		t.Errorf("body = %s", body)
+	}
+	if got.Get(WebhookHeaderDelivery) != "delivery-1" || got.Get(WebhookHeaderEvent) != "message.created" || got.Get(WebhookHeaderID) != "webhook-1" {
+		t.Errorf("headers = %v", got)
+	}
+	attempts := db.recorded()
+	if len(attempts) != 1 || attempts[0].Status != domain.WebhookDeliverySucceeded || attempts[0].ResponseStatus != http.StatusNoContent {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+}
+
+func TestSignWebhookDependsOnSecretAndTimestamp(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	sig := SignWebhook("secret", "1700000000", body)
+	if !strings.HasPrefix(sig, "v1=") {
+		t.Fatalf("signature = %q", sig)
+	}
+	if sig == SignWebhook("other", "1700000000", body) || sig == SignWebhook("secret", "1700000001", body) {
+		t.Error("signature ignores the secret or the timestamp")
+	}
+}
+
+func TestWebhookDispatcherRetriesUntilMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	db, client := newWebhookDB(t, receiver.URL)
+	d := NewWebhookDispatcher(client, WebhookConfig{AllowPrivateTargets: true, MaxAttempts: 3, DisableAfter: 10})
+	for i := 0; i < 5; i++ {
+		d.dispatchTenant(context.Background(), "tenant-1")
+	}
+
+	attempts := db.recorded()
+	if len(attempts) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(attempts))
+	}
+	for i, wantDelay := range []time.Duration{10 * time.Second, 20 * time.Second} {
+		a := attempts[i]
+		if a.Status != domain.WebhookDeliveryPending || a.ResponseStatus != http.StatusInternalServerError || a.NextAttemptAt == nil {
+			t.Fatalf("attempt %d = %+v", i+1, a)
+		}
+		if delay := time.Until(*a.NextAttemptAt); delay <= wantDelay-5*time.Second || delay > wantDelay {
+			t.Errorf("attempt %d retries in %v, want about %v", i+1, delay, wantDelay)
+		}
+	}
+	if last := attempts[2]; last.Status != domain.WebhookDeliveryFailed || last.NextAttemptAt != nil {
+		t.Errorf("last attempt = %+v", last)
+	}
+}
+
+func TestWebhookDispatcherDisablesAfterConsecutiveFailures(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	db, client := newWebhookDB(t, receiver.URL)
+	d := NewWebhookDispatcher(client, WebhookConfig{AllowPrivateTargets: true, MaxAttempts: 8, DisableAfter: 2})
+	for i := 0; i < 4; i++ {
+		d.dispatchTenant(context.Background(), "tenant-1")
+	}
+
+	if calls != 2 {
+		t.Errorf("receiver calls = %d, want 2", calls)
+	}
+	for _, a := range db.recorded() {
+		if a.DisableAfter != 2 {
+			t.Errorf("disable_after = %d, want 2", a.DisableAfter)
+		}
+	}
+	if db.active {
+		t.Error("webhook still active")
+	}
+}
+
+func TestWebhookDispatcherRefusesPrivateTargets(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	db, client := newWebhookDB(t, receiver.URL)
+	d := NewWebhookDispatcher(client, WebhookConfig{})
+	d.dispatchTenant(context.Background(), "tenant-1")
+
+	if called {
+		t.Error("dispatcher connected to a loopback target")
+	}
+	attempts := db.recorded()
+	if len(attempts) != 1 || attempts[0].Succeeded || !strings.Contains(attempts[0].Error, "not public") {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+}
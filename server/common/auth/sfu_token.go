@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SFUVideoGrant is the "video" grant of a LiveKit access token.
+type SFUVideoGrant struct {
+	Room              string   `json:"room"`
+	RoomJoin          bool     `json:"roomJoin"`
+	CanPublish        bool     `json:"canPublish"`
+	CanSubscribe      bool     `json:"canSubscribe"`
+	CanPublishData    bool     `json:"canPublishData"`
+	CanPublishSources []string `json:"canPublishSources,omitempty"`
+}
+
+type SFUClaims struct {
+	Name     string        `json:"name,omitempty"`
+	Metadata string        `json:"metadata,omitempty"`
+	Video    SFUVideoGrant `json:"video"`
+	jwt.RegisteredClaims
+}
+
+// MintSFUToken signs a LiveKit-compatible access token: HS256 with the API
+// key as issuer and the participant identity as subject.
+func MintSFUToken(apiKey, apiSecret, identity string, grant SFUVideoGrant, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(ttl)
+	claims := SFUClaims{
+		Video: grant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    apiKey,
+			Subject:   identity,
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := t.SignedString([]byte(apiSecret))
+	return signed, expiresAt, err
+}
+
+// ParseSFUToken verifies a token minted by MintSFUToken the way the SFU does.
+func ParseSFUToken(token, apiKey, apiSecret string) (*SFUClaims, error) {
+	parsed, err := jwt.ParseWithClaims(token, &SFUClaims{}, func(t *jwt.Token) (any, error) {
+		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(apiSecret), nil
+	}, jwt.WithIssuer(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := parsed.Claims.(*SFUClaims)
+	if !ok || !parsed.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSFUTokenRoundTrip(t *testing.T) {
+	grant := SFUVideoGrant{
+		Room:              "tenant-1:call:call-1",
+		RoomJoin:          true,
+		CanPublish:        true,
+		CanSubscribe:      true,
+		CanPublishData:    true,
+		CanPublishSources: []string{"microphone"},
+	}
+	token, expiresAt, err := MintSFUToken("api-key", "api-secret", "user-1", grant, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("mint: %v", err)
+	}
+
+	claims, err := ParseSFUToken(token, "api-key", "api-secret")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if claims.Subject != "user-1" {
+		t.Errorf("subject = %q, want user-1", claims.Subject)
+	}
+	if claims.Issuer != "api-key" {
+		t.Errorf("issuer = %q, want api-key", claims.Issuer)
+	}
+	if !claims.ExpiresAt.Time.Equal(expiresAt) {
+		t.Errorf("expires_at = %v, want %v", claims.ExpiresAt.Time, expiresAt)
+	}
+	if !reflect.DeepEqual(claims.Video, grant) {
+		t.Errorf("video grant = %+v, want %+v", claims.Video, grant)
+	}
+}
+
+func TestParseSFUTokenRejects(t *testing.T) {
+	token, _, err := MintSFUToken("api-key", "api-secret", "user-1", SFUVideoGrant{Room: "r", RoomJoin: true}, time.Minute)
+	if err != nil {
+		t.Fatalf("mint: %v", err)
+	}
+	expired, _, err := MintSFUToken("api-key", "api-secret", "user-1", SFUVideoGrant{Room: "r", RoomJoin: true}, -time.Minute)
+	if err != nil {
+		t.Fatalf("mint: %v", err)
+	}
+	cases := []struct {
+		name, token, key, secret string
+	}{
+		{"wrong secret", token, "api-key", "other-secret"},
+		{"wrong issuer", token, "other-key", "api-secret"},
+		{"expired", expired, "api-key", "api-secret"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseSFUToken(tc.token, tc.key, tc.secret); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+// Call events are typed call.ringing, call.updated, or call.<status> once the
+// call reaches a terminal status.
+const (
+	TypeCallRinging  = "call.ringing"
+	TypeCallUpdated  = "call.updated"
+	TypeCallEnded    = "call.ended"
+	TypeCallDeclined = "call.declined"
+	TypeCallMissed   = "call.missed"
+	callTypePrefix   = "call."
+)
+
+const CallVersion = 1
+
+// Call is the payload of every call.* event. Event holds the concrete type and
+// is filled from the envelope on decode.
+type Call struct {
+	Event       string     `json:"-"`
+	CallID      string     `json:"call_id"`
+	RoomID      string     `json:"room_id"`
+	CallerID    string     `json:"caller_id"`
+	UserID      string     `json:"user_id"`
+	Media       string     `json:"media"`
+	Status      string     `json:"status"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	DurationSec int64      `json:"duration_sec"`
+}
+
+func (d Call) EventType() string { return d.Event }
+func (Call) SchemaVersion() int  { return CallVersion }
+func (d Call) Subject() string   { return "rooms/" + d.RoomID + "/calls/" + d.CallID }
+
+func (d *Call) setEventType(eventType string) {
+	if strings.HasPrefix(eventType, callTypePrefix) {
+		d.Event = eventType
+	}
+}
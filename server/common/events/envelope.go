@@ -0,0 +1,139 @@
+// Package events defines the CloudEvents 1.0 envelope and the typed payloads
+// published on the chat.events exchange, plus the decoder consumers share.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	SpecVersion = "1.0"
+	// ContentType marks a structured-mode CloudEvent on the AMQP message.
+	ContentType = "application/cloudevents+json"
+	Source      = "/msg_server/chat"
+)
+
+var ErrInvalidEnvelope = errors.New("invalid event envelope")
+
+// Types lists every event type published on chat.events.
+func Types() []string {
+	return []string{
+		TypeMessageCreated, TypeMessageUpdated, TypeMessageDeleted,
+		TypeReactionAdded, TypeReactionRemoved,
+		TypeCallRinging, TypeCallUpdated, TypeCallEnded, TypeCallDeclined, TypeCallMissed,
+		TypeRoomCreated, TypeRoomUpdated, TypeRoomDeleted,
+		TypeMemberJoined, TypeMemberLeft, TypeMemberRoleChanged,
+	}
+}
+
+// Data is implemented by every typed event payload.
+type Data interface {
+	EventType() string
+	SchemaVersion() int
+	Subject() string
+}
+
+// multiType is implemented by payloads shared by several event types.
+type multiType interface {
+	setEventType(eventType string)
+}
+
+// Envelope is a CloudEvents 1.0 event in structured JSON mode. tenantid,
+// schemaversion and traceparent are extension attributes.
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	SchemaVersion   int             `json:"schemaversion"`
+	TenantID        string          `json:"tenantid,omitempty"`
+	TraceParent     string          `json:"traceparent,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+func New(tenantID string, data Data, traceParent string) (Envelope, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              newID(),
+		Source:          Source,
+		Type:            data.EventType(),
+		Subject:         data.Subject(),
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		DataSchema:      DataSchema(data.EventType(), data.SchemaVersion()),
+		SchemaVersion:   data.SchemaVersion(),
+		TenantID:        tenantID,
+		TraceParent:     traceParent,
+		Data:            body,
+	}, nil
+}
+
+// DataSchema names the schema of one event type version, e.g.
+// urn:msg_server:events:message.created:v1.
+func DataSchema(eventType string, version int) string {
+	return fmt.Sprintf("urn:msg_server:events:%s:v%d", eventType, version)
+}
+
+// Decode parses a chat.events body. Bodies written before the envelope
+// existed are bare payloads with an "event" field; they decode as schema
+// version 0 with the whole body as data.
+func Decode(body []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return Envelope{}, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	if env.SpecVersion == "" {
+		var legacy struct {
+			Event string `json:"event"`
+		}
+		_ = json.Unmarshal(body, &legacy)
+		if legacy.Event == "" {
+			return Envelope{}, fmt.Errorf("%w: missing specversion", ErrInvalidEnvelope)
+		}
+		return Envelope{Type: legacy.Event, DataContentType: "application/json", Data: body}, nil
+	}
+	if env.SpecVersion != SpecVersion || env.ID == "" || env.Type == "" {
+		return Envelope{}, fmt.Errorf("%w: specversion=%q id=%q type=%q", ErrInvalidEnvelope, env.SpecVersion, env.ID, env.Type)
+	}
+	return env, nil
+}
+
+// DecodeData unmarshals the payload into v, which must match e.Type.
+func (e Envelope) DecodeData(v Data) error {
+	if mt, ok := v.(multiType); ok {
+		mt.setEventType(e.Type)
+	}
+	if v.EventType() != e.Type {
+		return fmt.Errorf("%w: event type %s decoded as %s", ErrInvalidEnvelope, e.Type, v.EventType())
+	}
+	if e.SchemaVersion > v.SchemaVersion() {
+		return fmt.Errorf("%w: %s schema version %d is newer than supported %d", ErrInvalidEnvelope, e.Type, e.SchemaVersion, v.SchemaVersion())
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	return nil
+}
+
+// newID returns a random UUIDv4.
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
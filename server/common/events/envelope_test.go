@@ -0,0 +1,126 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	created := MessageCreated{
+		MessageID: "m1",
+		RoomID:    "r1",
+		Seq:       7,
+		SenderID:  "u1",
+		Body:      "hi",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	env, err := New("tenant-1", created, "00-trace-span-01")
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	body, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	got, err := Decode(body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.ID != env.ID || got.ID == "" {
+		t.Errorf("id = %q, want %q", got.ID, env.ID)
+	}
+	if got.Type != TypeMessageCreated || got.TenantID != "tenant-1" || got.SchemaVersion != MessageCreatedVersion {
+		t.Errorf("attributes = %+v", got)
+	}
+	if got.Subject != "rooms/r1/messages/m1" {
+		t.Errorf("subject = %q", got.Subject)
+	}
+	if got.DataSchema != "urn:msg_server:events:message.created:v1" {
+		t.Errorf("dataschema = %q", got.DataSchema)
+	}
+
+	var data MessageCreated
+	if err := got.DecodeData(&data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data != created {
+		t.Errorf("data = %+v, want %+v", data, created)
+	}
+}
+
+func TestNewAssignsUniqueIDs(t *testing.T) {
+	a, _ := New("t", MessageDeleted{MessageID: "m", RoomID: "r"}, "")
+	b, _ := New("t", MessageDeleted{MessageID: "m", RoomID: "r"}, "")
+	if a.ID == b.ID {
+		t.Fatalf("ids collide: %s", a.ID)
+	}
+}
+
+func TestDecodeLegacyBody(t *testing.T) {
+	body := []byte(`{"event":"message.deleted","message_id":"m1","room_id":"r1"}`)
+	env, err := Decode(body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if env.Type != TypeMessageDeleted || env.SchemaVersion != 0 || env.ID != "" {
+		t.Errorf("legacy envelope = %+v", env)
+	}
+	var data MessageDeleted
+	if err := env.DecodeData(&data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data.MessageID != "m1" || data.RoomID != "r1" {
+		t.Errorf("data = %+v", data)
+	}
+}
+
+func TestDecodeCallSetsEventType(t *testing.T) {
+	env, err := New("t", Call{Event: TypeCallMissed, CallID: "c1", RoomID: "r1"}, "")
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	body, _ := json.Marshal(env)
+	got, err := Decode(body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var data Call
+	if err := got.DecodeData(&data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data.Event != TypeCallMissed || data.CallID != "c1" {
+		t.Errorf("data = %+v", data)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	cases := map[string]string{
+		"not json":          `{`,
+		"no specversion":    `{"id":"1","type":"message.created"}`,
+		"wrong specversion": `{"specversion":"0.3","id":"1","type":"message.created","data":{}}`,
+		"missing id":        `{"specversion":"1.0","type":"message.created","data":{}}`,
+		"missing type":      `{"specversion":"1.0","id":"1","data":{}}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode([]byte(body)); !errors.Is(err, ErrInvalidEnvelope) {
+				t.Fatalf("err = %v, want ErrInvalidEnvelope", err)
+			}
+		})
+	}
+}
+
+func TestDecodeDataRejects(t *testing.T) {
+	env := Envelope{SpecVersion: SpecVersion, ID: "1", Type: TypeMessageCreated, SchemaVersion: MessageCreatedVersion, Data: json.RawMessage(`{}`)}
+	if err := env.DecodeData(&MessageDeleted{}); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("type mismatch: err = %v", err)
+	}
+
+	env.SchemaVersion = MessageCreatedVersion + 1
+	if err := env.DecodeData(&MessageCreated{}); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("newer schema: err = %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package events
+
+import "time"
+
+const (
+	TypeMessageCreated = "message.created"
+	TypeMessageUpdated = "message.updated"
+	TypeMessageDeleted = "message.deleted"
+)
+
+// Schema versions only grow. Adding an optional field keeps the version;
+// renaming, removing or changing the meaning of one bumps it.
+const (
+	MessageCreatedVersion = 1
+	MessageUpdatedVersion = 1
+	MessageDeletedVersion = 1
+)
+
+type MessageCreated struct {
+	MessageID       string    `json:"message_id"`
+	RoomID          string    `json:"room_id"`
+	Seq             int64     `json:"seq"`
+	SenderID        string    `json:"sender_id"`
+	Body            string    `json:"body"`
+	ParentMessageID *string   `json:"parent_message_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	// System marks messages the server posts, such as a missed call, whose
+	// recipients are already notified by other means.
+	System bool `json:"system,omitempty"`
+}
+
+func (MessageCreated) EventType() string  { return TypeMessageCreated }
+func (MessageCreated) SchemaVersion() int { return MessageCreatedVersion }
+func (d MessageCreated) Subject() string  { return messageSubject(d.RoomID, d.MessageID) }
+
+type MessageUpdated struct {
+	MessageID string     `json:"message_id"`
+	RoomID    string     `json:"room_id"`
+	SenderID  string     `json:"sender_id"`
+	EditorID  string     `json:"editor_id"`
+	Body      string     `json:"body"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+}
+
+func (MessageUpdated) EventType() string  { return TypeMessageUpdated }
+func (MessageUpdated) SchemaVersion() int { return MessageUpdatedVersion }
+func (d MessageUpdated) Subject() string  { return messageSubject(d.RoomID, d.MessageID) }
+
+type MessageDeleted struct {
+	MessageID string     `json:"message_id"`
+	RoomID    string     `json:"room_id"`
+	SenderID  string     `json:"sender_id"`
+	DeletedBy string     `json:"deleted_by"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (MessageDeleted) EventType() string  { return TypeMessageDeleted }
+func (MessageDeleted) SchemaVersion() int { return MessageDeletedVersion }
+func (d MessageDeleted) Subject() string  { return messageSubject(d.RoomID, d.MessageID) }
+
+func messageSubject(roomID, messageID string) string {
+	return "rooms/" + roomID + "/messages/" + messageID
+}
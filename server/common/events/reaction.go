@@ -0,0 +1,30 @@
+package events
+
+const (
+	TypeReactionAdded   = "reaction.added"
+	TypeReactionRemoved = "reaction.removed"
+)
+
+const ReactionVersion = 1
+
+// Reaction is the payload shared by reaction.added and reaction.removed. Count
+// is the emoji's total on the message after the change.
+type Reaction struct {
+	MessageID string `json:"message_id"`
+	RoomID    string `json:"room_id"`
+	UserID    string `json:"user_id"`
+	Emoji     string `json:"emoji"`
+	Count     int64  `json:"count"`
+}
+
+type ReactionAdded struct{ Reaction }
+
+func (ReactionAdded) EventType() string  { return TypeReactionAdded }
+func (ReactionAdded) SchemaVersion() int { return ReactionVersion }
+func (d ReactionAdded) Subject() string  { return messageSubject(d.RoomID, d.MessageID) }
+
+type ReactionRemoved struct{ Reaction }
+
+func (ReactionRemoved) EventType() string  { return TypeReactionRemoved }
+func (ReactionRemoved) SchemaVersion() int { return ReactionVersion }
+func (d ReactionRemoved) Subject() string  { return messageSubject(d.RoomID, d.MessageID) }
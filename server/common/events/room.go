@@ -0,0 +1,86 @@
+package events
+
+import "time"
+
+const (
+	TypeRoomCreated       = "room.created"
+	TypeRoomUpdated       = "room.updated"
+	TypeRoomDeleted       = "room.deleted"
+	TypeMemberJoined      = "member.joined"
+	TypeMemberLeft        = "member.left"
+	TypeMemberRoleChanged = "member.role_changed"
+)
+
+const (
+	RoomVersion   = 1
+	MemberVersion = 1
+)
+
+// Room is the payload shared by room.created and room.updated. MemberIDs is
+// only set on room.created.
+type Room struct {
+	RoomID     string     `json:"room_id"`
+	Name       string     `json:"name"`
+	RoomType   string     `json:"room_type"`
+	CreatedBy  string     `json:"created_by"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	MemberIDs  []string   `json:"member_ids,omitempty"`
+}
+
+type RoomCreated struct{ Room }
+
+func (RoomCreated) EventType() string  { return TypeRoomCreated }
+func (RoomCreated) SchemaVersion() int { return RoomVersion }
+func (d RoomCreated) Subject() string  { return roomSubject(d.RoomID) }
+
+type RoomUpdated struct{ Room }
+
+func (RoomUpdated) EventType() string  { return TypeRoomUpdated }
+func (RoomUpdated) SchemaVersion() int { return RoomVersion }
+func (d RoomUpdated) Subject() string  { return roomSubject(d.RoomID) }
+
+// RoomDeleted lists the members the room had when it was deleted.
+type RoomDeleted struct {
+	RoomID    string   `json:"room_id"`
+	MemberIDs []string `json:"member_ids"`
+}
+
+func (RoomDeleted) EventType() string  { return TypeRoomDeleted }
+func (RoomDeleted) SchemaVersion() int { return RoomVersion }
+func (d RoomDeleted) Subject() string  { return roomSubject(d.RoomID) }
+
+// Member is the payload shared by member.* events. Action is joined, left or
+// removed; Role is the member's role after the change and empty once they are
+// gone.
+type Member struct {
+	RoomID string `json:"room_id"`
+	UserID string `json:"user_id"`
+	Action string `json:"action,omitempty"`
+	Role   string `json:"role,omitempty"`
+}
+
+type MemberJoined struct{ Member }
+
+func (MemberJoined) EventType() string  { return TypeMemberJoined }
+func (MemberJoined) SchemaVersion() int { return MemberVersion }
+func (d MemberJoined) Subject() string  { return memberSubject(d.RoomID, d.UserID) }
+
+type MemberLeft struct{ Member }
+
+func (MemberLeft) EventType() string  { return TypeMemberLeft }
+func (MemberLeft) SchemaVersion() int { return MemberVersion }
+func (d MemberLeft) Subject() string  { return memberSubject(d.RoomID, d.UserID) }
+
+type MemberRoleChanged struct{ Member }
+
+func (MemberRoleChanged) EventType() string  { return TypeMemberRoleChanged }
+func (MemberRoleChanged) SchemaVersion() int { return MemberVersion }
+func (d MemberRoleChanged) Subject() string  { return memberSubject(d.RoomID, d.UserID) }
+
+func roomSubject(roomID string) string {
+	return "rooms/" + roomID
+}
+
+func memberSubject(roomID, userID string) string {
+	return "rooms/" + roomID + "/members/" + userID
+}
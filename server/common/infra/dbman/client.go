@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,10 +13,16 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"msg_server/server/common/trace"
 )
 
 const BasePath = "/api/internal/v1/db"
 
+// ErrNotFound wraps 404 responses so callers can map them to their own
+// not-found errors.
+var ErrNotFound = errors.New("dbman: not found")
+
 const (
 	defaultHTTPTimeout      = 5 * time.Second
 	defaultFailThreshold    = 3
@@ -81,6 +88,9 @@ func (c *Client) Post(ctx context.Context, path string, payload any, out any) er
 			continue
 		}
 		req.Header.Set("Content-Type", "application/json")
+		if traceParent := trace.ParentFromContext(ctx); traceParent != "" {
+			req.Header.Set(trace.Header, traceParent)
+		}
 
 		resp, doErr := c.http.Do(req)
 		if doErr != nil {
@@ -95,6 +105,11 @@ func (c *Client) Post(ctx context.Context, path string, payload any, out any) er
 			c.onFailure(endpoint, time.Now())
 			continue
 		}
+		if resp.StatusCode == http.StatusNotFound {
+			_ = resp.Body.Close()
+			c.onSuccess(endpoint)
+			return fmt.Errorf("%w endpoint=%s", ErrNotFound, endpoint)
+		}
 		if resp.StatusCode >= 300 {
 			_ = resp.Body.Close()
 			return fmt.Errorf("dbman status %d endpoint=%s", resp.StatusCode, endpoint)
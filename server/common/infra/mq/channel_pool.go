@@ -0,0 +1,296 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	commonlog "msg_server/server/common/log"
+)
+
+const eventsExchange = "chat.events"
+
+var (
+	ErrNotConnected = errors.New("broker not connected")
+	ErrNacked       = errors.New("broker rejected publish")
+)
+
+type PublisherConfig struct {
+	// ChannelPoolSize confirm-mode channels are opened per connection; an
+	// amqp.Channel must not be used by two goroutines at once.
+	ChannelPoolSize int
+	// ConfirmTimeout bounds waiting for a free channel plus the broker ack.
+	ConfirmTimeout time.Duration
+	ReconnectMin   time.Duration
+	ReconnectMax   time.Duration
+}
+
+func DefaultPublisherConfig() PublisherConfig {
+	return PublisherConfig{
+		ChannelPoolSize: 8,
+		ConfirmTimeout:  5 * time.Second,
+		ReconnectMin:    time.Second,
+		ReconnectMax:    30 * time.Second,
+	}
+}
+
+func (c PublisherConfig) normalized() PublisherConfig {
+	def := DefaultPublisherConfig()
+	if c.ChannelPoolSize <= 0 {
+		c.ChannelPoolSize = def.ChannelPoolSize
+	}
+	if c.ConfirmTimeout <= 0 {
+		c.ConfirmTimeout = def.ConfirmTimeout
+	}
+	if c.ReconnectMin <= 0 {
+		c.ReconnectMin = def.ReconnectMin
+	}
+	if c.ReconnectMax < c.ReconnectMin {
+		c.ReconnectMax = def.ReconnectMax
+	}
+	return c
+}
+
+type BrokerHealth struct {
+	Name         string     `json:"name"`
+	Connected    bool       `json:"connected"`
+	IdleChannels int        `json:"idle_channels"`
+	Reconnects   int64      `json:"reconnects"`
+	ConnectedAt  *time.Time `json:"connected_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// brokerConn is one AMQP connection with a pool of confirm channels. When the
+// connection drops it is re-dialed in the background with exponential backoff;
+// publishes fail fast with ErrNotConnected until then.
+type brokerConn struct {
+	name string
+	url  string
+	cfg  PublisherConfig
+
+	mu          sync.RWMutex
+	conn        *amqp.Connection
+	channels    chan *amqp.Channel
+	connectedAt time.Time
+	reconnects  int64
+	lastErr     error
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func dialBroker(name, url string, cfg PublisherConfig) (*brokerConn, error) {
+	b := &brokerConn{name: name, url: url, cfg: cfg, closed: make(chan struct{})}
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *brokerConn) connect() error {
+	conn, err := amqp.Dial(b.url)
+	if err != nil {
+		return err
+	}
+	channels := make(chan *amqp.Channel, b.cfg.ChannelPoolSize)
+	for i := 0; i < b.cfg.ChannelPoolSize; i++ {
+		ch, err := openConfirmChannel(conn)
+		if err == nil && i == 0 {
+			err = ch.ExchangeDeclare(eventsExchange, "topic", true, false, false, false, nil)
+		}
+		if err != nil {
+			_ = conn.Close()
+			return err
+		}
+		channels <- ch
+	}
+	notify := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	b.mu.Lock()
+	select {
+	case <-b.closed:
+		b.mu.Unlock()
+		_ = conn.Close()
+		return ErrNotConnected
+	default:
+	}
+	b.conn = conn
+	b.channels = channels
+	b.connectedAt = time.Now().UTC()
+	b.lastErr = nil
+	b.mu.Unlock()
+
+	go b.watch(notify)
+	return nil
+}
+
+func openConfirmChannel(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (b *brokerConn) watch(notify <-chan *amqp.Error) {
+	var amqpErr *amqp.Error
+	select {
+	case <-b.closed:
+		return
+	case amqpErr = <-notify:
+	}
+	err := ErrNotConnected
+	if amqpErr != nil {
+		err = amqpErr
+	}
+	b.mu.Lock()
+	b.conn = nil
+	b.channels = nil
+	b.lastErr = err
+	b.mu.Unlock()
+	commonlog.Warnf("event=mq_connection action=lost status=failed broker=%s error=%v", b.name, err)
+
+	delay := b.cfg.ReconnectMin
+	for {
+		select {
+		case <-b.closed:
+			return
+		case <-time.After(delay):
+		}
+		err := b.connect()
+		if err == nil {
+			b.mu.Lock()
+			b.reconnects++
+			b.mu.Unlock()
+			commonlog.Infof("event=mq_connection action=reconnect status=ok broker=%s", b.name)
+			return
+		}
+		if errors.Is(err, ErrNotConnected) {
+			return
+		}
+		b.mu.Lock()
+		b.lastErr = err
+		b.mu.Unlock()
+		commonlog.Warnf("event=mq_connection action=reconnect status=failed broker=%s retry_in=%s error=%v", b.name, delay, err)
+		if delay *= 2; delay > b.cfg.ReconnectMax {
+			delay = b.cfg.ReconnectMax
+		}
+	}
+}
+
+// publish waits until the broker acks the message or ConfirmTimeout elapses.
+func (b *brokerConn) publish(ctx context.Context, key string, msg amqp.Publishing) error {
+	b.mu.RLock()
+	conn, channels := b.conn, b.channels
+	b.mu.RUnlock()
+	if channels == nil {
+		return fmt.Errorf("%w: %s", ErrNotConnected, b.name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.ConfirmTimeout)
+	defer cancel()
+	var ch *amqp.Channel
+	select {
+	case ch = <-channels:
+	case <-ctx.Done():
+		return fmt.Errorf("acquire channel: %w", ctx.Err())
+	}
+	defer b.release(conn, channels, ch)
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, eventsExchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("wait publisher confirm: %w", err)
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// release returns ch to the pool it came from. A channel closed by a
+// channel-level error is replaced while its connection is still up; after a
+// connection loss the reconnect builds a fresh pool instead.
+func (b *brokerConn) release(conn *amqp.Connection, channels chan *amqp.Channel, ch *amqp.Channel) {
+	if !ch.IsClosed() {
+		channels <- ch
+		return
+	}
+	if conn == nil || conn.IsClosed() {
+		return
+	}
+	fresh, err := openConfirmChannel(conn)
+	if err != nil {
+		commonlog.Warnf("event=mq_channel action=replace status=failed broker=%s error=%v", b.name, err)
+		go b.replaceChannel(conn, channels)
+		return
+	}
+	channels <- fresh
+}
+
+// replaceChannel keeps retrying to open a channel for a pool slot whose
+// replacement failed, so the pool does not shrink for the life of the
+// connection. It gives up once the connection or the broker is closed.
+func (b *brokerConn) replaceChannel(conn *amqp.Connection, channels chan *amqp.Channel) {
+	delay := b.cfg.ReconnectMin
+	for {
+		select {
+		case <-b.closed:
+			return
+		case <-time.After(delay):
+		}
+		if conn.IsClosed() {
+			return
+		}
+		fresh, err := openConfirmChannel(conn)
+		if err == nil {
+			channels <- fresh
+			commonlog.Infof("event=mq_channel action=replace status=ok broker=%s", b.name)
+			return
+		}
+		commonlog.Warnf("event=mq_channel action=replace status=failed broker=%s retry_in=%s error=%v", b.name, delay, err)
+		if delay *= 2; delay > b.cfg.ReconnectMax {
+			delay = b.cfg.ReconnectMax
+		}
+	}
+}
+
+func (b *brokerConn) health() BrokerHealth {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	h := BrokerHealth{Name: b.name, Connected: b.conn != nil && !b.conn.IsClosed(), Reconnects: b.reconnects}
+	if h.Connected {
+		connectedAt := b.connectedAt
+		h.ConnectedAt = &connectedAt
+		h.IdleChannels = len(b.channels)
+	}
+	if b.lastErr != nil {
+		h.LastError = b.lastErr.Error()
+	}
+	return h
+}
+
+func (b *brokerConn) Close() {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		b.mu.Lock()
+		conn := b.conn
+		b.conn = nil
+		b.channels = nil
+		b.mu.Unlock()
+		if conn != nil {
+			_ = conn.Close()
+		}
+	})
+}
@@ -1,14 +1,18 @@
-package service
+package mq
 
 import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+
+	"msg_server/server/common/events"
+	"msg_server/server/common/trace"
 )
 
 type TenantMQMeta struct {
@@ -21,16 +25,12 @@ type TenantMQMetaProvider interface {
 	GetTenantMQMeta(ctx context.Context, tenantID string) (TenantMQMeta, error)
 }
 
-type tenantPublisher struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-}
-
 type AMQPPublisher struct {
 	provider  TenantMQMetaProvider
-	shared    *tenantPublisher
+	cfg       PublisherConfig
+	shared    *brokerConn
 	mu        sync.RWMutex
-	dedicated map[string]*tenantPublisher
+	dedicated map[string]*brokerConn
 	metaCache map[string]cachedMQMeta
 	cacheTTL  time.Duration
 }
@@ -46,29 +46,49 @@ type cachedMQMeta struct {
 	fetchedAt time.Time
 }
 
-func NewAMQPPublisher(conn *amqp.Connection, provider TenantMQMetaProvider) (*AMQPPublisher, error) {
-	ch, err := conn.Channel()
+type PublisherHealth struct {
+	Ready     bool           `json:"ready"`
+	Shared    BrokerHealth   `json:"shared"`
+	Dedicated []BrokerHealth `json:"dedicated,omitempty"`
+}
+
+// NewAMQPPublisher dials the shared broker up front; dedicated tenant brokers
+// are dialed on first publish.
+func NewAMQPPublisher(url string, provider TenantMQMetaProvider, cfg PublisherConfig) (*AMQPPublisher, error) {
+	cfg = cfg.normalized()
+	shared, err := dialBroker("shared", url, cfg)
 	if err != nil {
 		return nil, err
 	}
-	if err := ch.ExchangeDeclare("chat.events", "topic", true, false, false, false, nil); err != nil {
-		return nil, err
-	}
 	return &AMQPPublisher{
 		provider:  provider,
-		shared:    &tenantPublisher{conn: conn, channel: ch},
-		dedicated: map[string]*tenantPublisher{},
+		cfg:       cfg,
+		shared:    shared,
+		dedicated: map[string]*brokerConn{},
 		metaCache: map[string]cachedMQMeta{},
 		cacheTTL:  30 * time.Second,
 	}, nil
 }
 
-func (p *AMQPPublisher) Publish(ctx context.Context, tenantID, key string, payload any) error {
-	publisher, err := p.publisherForTenant(ctx, tenantID)
+// Publish wraps data in a CloudEvents envelope routed by its event type. The
+// envelope id doubles as the AMQP message id.
+func (p *AMQPPublisher) Publish(ctx context.Context, tenantID string, data events.Data) error {
+	env, err := events.New(tenantID, data, trace.ParentFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(env)
 	if err != nil {
 		return err
 	}
-	body, err := json.Marshal(payload)
+	return p.PublishRaw(ctx, tenantID, env.Type, env.ID, body)
+}
+
+// PublishRaw publishes an already encoded CloudEvents envelope and returns
+// once the broker confirmed it. messageID, when set, is carried as the AMQP
+// message id so consumers can deduplicate redeliveries.
+func (p *AMQPPublisher) PublishRaw(ctx context.Context, tenantID, key, messageID string, body []byte) error {
+	broker, err := p.brokerForTenant(ctx, tenantID)
 	if err != nil {
 		return err
 	}
@@ -76,25 +96,38 @@ func (p *AMQPPublisher) Publish(ctx context.Context, tenantID, key string, paylo
 	if strings.TrimSpace(tenantID) != "" {
 		routingKey = tenantID + "." + key
 	}
-	return publisher.channel.PublishWithContext(ctx, "chat.events", routingKey, false, false, amqp.Publishing{
-		ContentType: "application/json",
-		Body:        body,
-		Timestamp:   time.Now(),
+	return broker.publish(ctx, routingKey, amqp.Publishing{
+		ContentType:  events.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Body:         body,
+		Timestamp:    time.Now(),
 	})
 }
 
+// Health reports every broker connection. Ready only tracks the shared
+// broker so one tenant's dedicated LavinMQ cannot take the service out of
+// rotation.
+func (p *AMQPPublisher) Health() PublisherHealth {
+	h := PublisherHealth{Shared: p.shared.health()}
+	h.Ready = h.Shared.Connected
+	p.mu.RLock()
+	for _, broker := range p.dedicated {
+		h.Dedicated = append(h.Dedicated, broker.health())
+	}
+	p.mu.RUnlock()
+	sort.Slice(h.Dedicated, func(i, j int) bool { return h.Dedicated[i].Name < h.Dedicated[j].Name })
+	return h
+}
+
 func (p *AMQPPublisher) Close() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	for tenantID, pub := range p.dedicated {
-		if pub.channel != nil {
-			_ = pub.channel.Close()
-		}
-		if pub.conn != nil {
-			_ = pub.conn.Close()
-		}
+	for tenantID, broker := range p.dedicated {
+		broker.Close()
 		delete(p.dedicated, tenantID)
 	}
+	p.shared.Close()
 }
 
 func (p *AMQPPublisher) InvalidateTenant(tenantID string) {
@@ -106,18 +139,13 @@ func (p *AMQPPublisher) InvalidateTenant(tenantID string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	delete(p.metaCache, tenantID)
-	if pub, ok := p.dedicated[tenantID]; ok {
-		if pub.channel != nil {
-			_ = pub.channel.Close()
-		}
-		if pub.conn != nil {
-			_ = pub.conn.Close()
-		}
+	if broker, ok := p.dedicated[tenantID]; ok {
+		broker.Close()
 		delete(p.dedicated, tenantID)
 	}
 }
 
-func (p *AMQPPublisher) publisherForTenant(ctx context.Context, tenantID string) (*tenantPublisher, error) {
+func (p *AMQPPublisher) brokerForTenant(ctx context.Context, tenantID string) (*brokerConn, error) {
 	if strings.TrimSpace(tenantID) == "" {
 		return p.shared, nil
 	}
@@ -144,24 +172,12 @@ func (p *AMQPPublisher) publisherForTenant(ctx context.Context, tenantID string)
 	if existing, ok := p.dedicated[tenantID]; ok {
 		return existing, nil
 	}
-
-	conn, err := amqp.Dial(meta.url)
+	broker, err := dialBroker(tenantID, meta.url, p.cfg)
 	if err != nil {
 		return nil, err
 	}
-	ch, err := conn.Channel()
-	if err != nil {
-		_ = conn.Close()
-		return nil, err
-	}
-	if err := ch.ExchangeDeclare("chat.events", "topic", true, false, false, false, nil); err != nil {
-		_ = ch.Close()
-		_ = conn.Close()
-		return nil, err
-	}
-	pub := &tenantPublisher{conn: conn, channel: ch}
-	p.dedicated[tenantID] = pub
-	return pub, nil
+	p.dedicated[tenantID] = broker
+	return broker, nil
 }
 
 func (p *AMQPPublisher) loadMeta(ctx context.Context, tenantID string) (mqMeta, error) {
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/transport/httpresp"
+)
+
+// Limit is a token bucket that refills PerMinute tokens evenly over a minute
+// and holds at most Burst. PerMinute 0 means unlimited; Burst 0 defaults to
+// PerMinute.
+type Limit struct {
+	PerMinute int `json:"per_minute"`
+	Burst     int `json:"burst"`
+}
+
+func (l Limit) Unlimited() bool {
+	return l.PerMinute <= 0
+}
+
+func (l Limit) burst() int {
+	if l.Burst <= 0 {
+		return l.PerMinute
+	}
+	return l.Burst
+}
+
+// Bucket is one counter a request draws from, e.g. "api:user:<id>".
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// RedisProvider picks the Redis that holds a tenant's counters, so dedicated
+// tenants are limited on their own Redis. cache.TenantRedisRouter satisfies it.
+type RedisProvider interface {
+	ClientForTenant(ctx context.Context, tenantID string) (*redis.Client, error)
+}
+
+// RateLimiter keeps token buckets in Redis so limits hold across instances.
+type RateLimiter struct {
+	redis RedisProvider
+}
+
+func NewRateLimiter(redis RedisProvider) *RateLimiter {
+	return &RateLimiter{redis: redis}
+}
+
+// takeTokens refills every bucket from Redis TIME and takes one token from
+// each only if all of them have one. It returns {1, 0} or {0, wait_ms}.
+// ARGV holds rate-per-ms and burst pairs in KEYS order.
+var takeTokens = redis.NewScript(`
+local now_t = redis.call('TIME')
+local now = tonumber(now_t[1]) * 1000 + math.floor(tonumber(now_t[2]) / 1000)
+local tokens = {}
+local wait = 0
+for i = 1, #KEYS do
+	local rate = tonumber(ARGV[2 * i - 1])
+	local burst = tonumber(ARGV[2 * i])
+	local state = redis.call('HMGET', KEYS[i], 't', 'ts')
+	local t = tonumber(state[1])
+	local ts = tonumber(state[2])
+	if t == nil or ts == nil then
+		t = burst
+		ts = now
+	end
+	t = math.min(burst, t + math.max(0, now - ts) * rate)
+	tokens[i] = t
+	if t < 1 then
+		local w = math.ceil((1 - t) / rate)
+		if w > wait then
+			wait = w
+		end
+	end
+end
+if wait > 0 then
+	return {0, wait}
+end
+for i = 1, #KEYS do
+	local rate = tonumber(ARGV[2 * i - 1])
+	local burst = tonumber(ARGV[2 * i])
+	redis.call('HSET', KEYS[i], 't', tostring(tokens[i] - 1), 'ts', now)
+	redis.call('PEXPIRE', KEYS[i], math.ceil(burst / rate) + 1000)
+end
+return {1, 0}
+`)
+
+// Allow takes one token from every limited bucket, or none when any of them
+// is empty. retryAfter is when the emptiest bucket has a token again.
+func (l *RateLimiter) Allow(ctx context.Context, tenantID string, buckets []Bucket) (bool, time.Duration, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]any, 0, 2*len(buckets))
+	for _, b := range buckets {
+		if b.Limit.Unlimited() {
+			continue
+		}
+		keys = append(keys, fmt.Sprintf("tenant:%s:ratelimit:%s", tenantID, b.Key))
+		args = append(args, float64(b.Limit.PerMinute)/60000, b.Limit.burst())
+	}
+	if len(keys) == 0 {
+		return true, 0, nil
+	}
+	client, err := l.redis.ClientForTenant(ctx, tenantID)
+	if err != nil {
+		return false, 0, err
+	}
+	res, err := takeTokens.Run(ctx, client, keys, args...).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// RateLimitResolver returns the buckets a request draws from. ok=false skips
+// limiting, e.g. when the caller is not authenticated.
+type RateLimitResolver func(c *gin.Context) (tenantID string, buckets []Bucket, ok bool)
+
+// RateLimit rejects requests with 429 and Retry-After once any resolved
+// bucket is empty. Redis failures are logged and let the request through.
+func RateLimit(limiter *RateLimiter, resolve RateLimitResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, buckets, ok := resolve(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		allowed, retryAfter, err := limiter.Allow(c.Request.Context(), tenantID, buckets)
+		if err != nil {
+			commonlog.Warnf("event=rate_limit action=check status=failed tenant_id=%s path=%s error=%v", tenantID, c.FullPath(), err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			AbortRateLimited(c, retryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RetryAfterSeconds rounds up to whole seconds, at least 1.
+func RetryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
+
+func AbortRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := RetryAfterSeconds(retryAfter)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, httpresp.NewRateLimitedResponse(seconds))
+}
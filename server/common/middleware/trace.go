@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/common/trace"
+)
+
+// TraceContext keeps the caller's traceparent, or starts a new trace, and
+// stores it in the request context for downstream calls and events.
+func TraceContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceParent := c.GetHeader(trace.Header)
+		if !trace.Valid(traceParent) {
+			traceParent = trace.NewParent()
+		}
+		c.Request = c.Request.WithContext(trace.WithParent(c.Request.Context(), traceParent))
+		c.Header(trace.Header, traceParent)
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package moderation
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditMessageDelete AuditAction = "message.delete"
+	AuditMessageReport AuditAction = "message.report"
+	AuditMemberRemove  AuditAction = "member.remove"
+	AuditMemberBan     AuditAction = "member.ban"
+	AuditMemberUnban   AuditAction = "member.unban"
+	AuditFlagApprove   AuditAction = "flag.approve"
+	AuditFlagRemove    AuditAction = "flag.remove"
+)
+
+// AuditEntry records one moderation action. The ids that do not apply to the
+// action are empty.
+type AuditEntry struct {
+	TenantID     string      `json:"tenant_id"`
+	ID           string      `json:"id"`
+	RoomID       string      `json:"room_id,omitempty"`
+	ActorID      string      `json:"actor_id"`
+	Action       AuditAction `json:"action"`
+	TargetUserID string      `json:"target_user_id,omitempty"`
+	MessageID    string      `json:"message_id,omitempty"`
+	FlagID       string      `json:"flag_id,omitempty"`
+	Reason       string      `json:"reason,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type RoomBan struct {
+	TenantID  string    `json:"tenant_id"`
+	RoomID    string    `json:"room_id"`
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason"`
+	BannedBy  string    `json:"banned_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,269 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	commonlog "msg_server/server/common/log"
+)
+
+type Action string
+
+const (
+	ActionBlock Action = "block"
+	ActionMask  Action = "mask"
+	ActionFlag  Action = "flag"
+)
+
+type Kind string
+
+const (
+	KindWords      Kind = "words"
+	KindRegex      Kind = "regex"
+	KindNationalID Kind = "national_id"
+	KindCardNumber Kind = "card_number"
+	KindMaxLinks   Kind = "max_links"
+)
+
+var ErrInvalidRule = errors.New("invalid moderation rule")
+
+// Rule is a tenant-configured check. Words and Pattern apply to the words and
+// regex kinds, MaxLinks to max_links; the built-in kinds need neither.
+type Rule struct {
+	TenantID  string    `json:"tenant_id"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Kind      Kind      `json:"kind"`
+	Words     []string  `json:"words,omitempty"`
+	Pattern   string    `json:"pattern,omitempty"`
+	MaxLinks  int       `json:"max_links,omitempty"`
+	Action    Action    `json:"action"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedBy string    `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate reports why the rule cannot be compiled, wrapping ErrInvalidRule.
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	switch r.Action {
+	case ActionBlock, ActionMask, ActionFlag:
+	default:
+		return fmt.Errorf("%w: action must be block, mask or flag", ErrInvalidRule)
+	}
+	_, err := compile(r)
+	return err
+}
+
+type Source string
+
+const (
+	SourceMessage Source = "message"
+	SourceNote    Source = "note"
+)
+
+type FlagStatus string
+
+const (
+	FlagPending  FlagStatus = "pending"
+	FlagApproved FlagStatus = "approved"
+	FlagRemoved  FlagStatus = "removed"
+)
+
+// Flag queues stored content for review, either matched by flag rules or
+// reported by a member (ReporterID set). SubjectID is the message or note id;
+// Body is the content as stored, i.e. after masking.
+type Flag struct {
+	TenantID   string     `json:"tenant_id"`
+	ID         string     `json:"id"`
+	Source     Source     `json:"source"`
+	RoomID     string     `json:"room_id,omitempty"`
+	SubjectID  string     `json:"subject_id"`
+	SenderID   string     `json:"sender_id"`
+	Body       string     `json:"body"`
+	Rules      []string   `json:"rules"`
+	ReporterID string     `json:"reporter_id,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	Status     FlagStatus `json:"status"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// BlockedError rejects content matched by a block rule.
+type BlockedError struct {
+	Rule string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("content blocked by moderation rule %q", e.Rule)
+}
+
+var (
+	// Korean resident registration numbers: YYMMDD-Gxxxxxx.
+	nationalIDPattern = regexp.MustCompile(`\b\d{6}-?[1-8]\d{6}\b`)
+	cardNumberPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	linkPattern       = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+)
+
+const maxWords = 1000
+
+type compiledRule struct {
+	rule  Rule
+	match func(text string) [][]int
+}
+
+func compile(r Rule) (compiledRule, error) {
+	c := compiledRule{rule: r}
+	switch r.Kind {
+	case KindWords:
+		words := make([]string, 0, len(r.Words))
+		for _, w := range r.Words {
+			if w = strings.TrimSpace(w); w != "" {
+				words = append(words, regexp.QuoteMeta(w))
+			}
+		}
+		if len(words) == 0 || len(words) > maxWords {
+			return c, fmt.Errorf("%w: words must hold 1 to %d entries", ErrInvalidRule, maxWords)
+		}
+		re := regexp.MustCompile(`(?i)(?:` + strings.Join(words, "|") + `)`)
+		c.match = func(text string) [][]int { return re.FindAllStringIndex(text, -1) }
+	case KindRegex:
+		if strings.TrimSpace(r.Pattern) == "" {
+			return c, fmt.Errorf("%w: pattern is required", ErrInvalidRule)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return c, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		c.match = func(text string) [][]int { return re.FindAllStringIndex(text, -1) }
+	case KindNationalID:
+		c.match = func(text string) [][]int { return nationalIDPattern.FindAllStringIndex(text, -1) }
+	case KindCardNumber:
+		c.match = matchCardNumbers
+	case KindMaxLinks:
+		if r.MaxLinks < 0 {
+			return c, fmt.Errorf("%w: max_links must not be negative", ErrInvalidRule)
+		}
+		// Only the links past the limit match, so masking keeps the first ones.
+		c.match = func(text string) [][]int {
+			links := linkPattern.FindAllStringIndex(text, -1)
+			if len(links) <= r.MaxLinks {
+				return nil
+			}
+			return links[r.MaxLinks:]
+		}
+	default:
+		return c, fmt.Errorf("%w: kind must be words, regex, national_id, card_number or max_links", ErrInvalidRule)
+	}
+	return c, nil
+}
+
+// matchCardNumbers keeps 13 to 19 digit runs that pass the Luhn check.
+func matchCardNumbers(text string) [][]int {
+	var out [][]int
+	for _, span := range cardNumberPattern.FindAllStringIndex(text, -1) {
+		if luhnValid(text[span[0]:span[1]]) {
+			out = append(out, span)
+		}
+	}
+	return out
+}
+
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// Pipeline runs a tenant's enabled rules over a piece of content.
+type Pipeline struct {
+	rules []compiledRule
+}
+
+// NewPipeline compiles the enabled rules. Rules that fail to compile are
+// logged and skipped; the API validates them before they are stored.
+func NewPipeline(rules []Rule) *Pipeline {
+	p := &Pipeline{rules: make([]compiledRule, 0, len(rules))}
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		c, err := compile(r)
+		if err != nil {
+			commonlog.Warnf("event=moderation action=compile status=skipped tenant_id=%s rule_id=%s error=%v", r.TenantID, r.ID, err)
+			continue
+		}
+		p.rules = append(p.rules, c)
+	}
+	return p
+}
+
+// Result is what a pipeline decided. Text is the content to store; Blocked
+// names the rule that rejected it, and Flagged the flag rules that matched.
+type Result struct {
+	Text    string
+	Blocked string
+	Masked  bool
+	Flagged []string
+}
+
+// Apply checks block rules first, so a block wins regardless of rule order.
+// Mask rules then rewrite matches to asterisks in order, and flag rules see
+// the masked text.
+func (p *Pipeline) Apply(text string) Result {
+	res := Result{Text: text}
+	for _, c := range p.rules {
+		if c.rule.Action == ActionBlock && len(c.match(text)) > 0 {
+			res.Blocked = c.rule.Name
+			return res
+		}
+	}
+	for _, c := range p.rules {
+		if c.rule.Action != ActionMask {
+			continue
+		}
+		if spans := c.match(res.Text); len(spans) > 0 {
+			res.Text = mask(res.Text, spans)
+			res.Masked = true
+		}
+	}
+	for _, c := range p.rules {
+		if c.rule.Action == ActionFlag && len(c.match(res.Text)) > 0 {
+			res.Flagged = append(res.Flagged, c.rule.Name)
+		}
+	}
+	return res
+}
+
+func mask(text string, spans [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(text[last:span[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[span[0]:span[1]])))
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package moderation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const ruleCacheTTL = 30 * time.Second
+
+// RuleSource loads a tenant's rules; the dbman clients satisfy it.
+type RuleSource interface {
+	ListModerationRules(ctx context.Context, tenantID string) ([]Rule, error)
+}
+
+type cachedPipeline struct {
+	pipeline  *Pipeline
+	fetchedAt time.Time
+}
+
+// Scanner runs content through the tenant's pipeline, caching compiled rules
+// briefly so edits made through another instance apply within the TTL.
+type Scanner struct {
+	source RuleSource
+
+	mu    sync.Mutex
+	cache map[string]cachedPipeline
+}
+
+func NewScanner(source RuleSource) *Scanner {
+	return &Scanner{source: source, cache: map[string]cachedPipeline{}}
+}
+
+// Scan serves a stale pipeline when the source is unreachable and fails only
+// when it has never loaded the tenant's rules, so content is never stored
+// unchecked.
+func (s *Scanner) Scan(ctx context.Context, tenantID, text string) (Result, error) {
+	p, err := s.pipeline(ctx, tenantID)
+	if err != nil {
+		return Result{}, err
+	}
+	return p.Apply(text), nil
+}
+
+func (s *Scanner) pipeline(ctx context.Context, tenantID string) (*Pipeline, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < ruleCacheTTL {
+		return cached.pipeline, nil
+	}
+	rules, err := s.source.ListModerationRules(ctx, tenantID)
+	if err != nil {
+		if ok {
+			return cached.pipeline, nil
+		}
+		return nil, err
+	}
+	p := NewPipeline(rules)
+	s.mu.Lock()
+	s.cache[tenantID] = cachedPipeline{pipeline: p, fetchedAt: time.Now()}
+	s.mu.Unlock()
+	return p, nil
+}
+
+func (s *Scanner) Invalidate(tenantID string) {
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+}
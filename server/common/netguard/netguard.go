@@ -0,0 +1,85 @@
+// Package netguard keeps server-side requests to user-supplied URLs away from
+// internal addresses: loopback, private and link-local ranges, including cloud
+// metadata endpoints.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// blocked are special-purpose ranges that netip's predicates do not cover.
+var blocked = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// PublicAddr reports whether addr is routable on the public internet.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves host and fails unless every address it resolves to is
+// public.
+func CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !PublicAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%w: %s resolves to no address", ErrNonPublicAddress, host)
+	}
+	for _, addr := range addrs {
+		if !PublicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrNonPublicAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control hook that refuses connections to non-public
+// addresses. It sees the resolved address, so DNS rebinding after CheckHost
+// cannot reach an internal host.
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+	}
+	return nil
+}
+
+// Dialer returns a dialer that only connects to public addresses.
+func Dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: Control}
+}
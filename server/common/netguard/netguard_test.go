@@ -0,0 +1,54 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestPublicAddr(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+		"224.0.0.1":        false,
+	}
+	for raw, want := range cases {
+		if got := PublicAddr(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("PublicAddr(%s) = %t, want %t", raw, got, want)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp4", "127.0.0.1:8082", nil); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("loopback: err = %v", err)
+	}
+	if err := Control("tcp6", "[fd00::1]:443", nil); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("ula: err = %v", err)
+	}
+	if err := Control("tcp4", "8.8.8.8:443", nil); err != nil {
+		t.Errorf("public: err = %v", err)
+	}
+}
+
+func TestCheckHostLiteral(t *testing.T) {
+	if err := CheckHost(context.Background(), "169.254.169.254"); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("metadata: err = %v", err)
+	}
+	if err := CheckHost(context.Background(), "localhost"); err == nil {
+		t.Error("localhost: expected an error")
+	}
+}
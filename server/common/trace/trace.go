@@ -0,0 +1,53 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// Header is the W3C Trace Context header. Services forward it on internal
+// calls and dbman copies it into event envelopes.
+const Header = "traceparent"
+
+type parentKey struct{}
+
+func WithParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, parentKey{}, traceParent)
+}
+
+func ParentFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(parentKey{}).(string)
+	return v
+}
+
+// NewParent starts a sampled trace: version 00, random trace and span ids.
+func NewParent() string {
+	var b [24]byte
+	_, _ = rand.Read(b[:])
+	return "00-" + hex.EncodeToString(b[:16]) + "-" + hex.EncodeToString(b[16:]) + "-01"
+}
+
+// Valid reports whether v is a well-formed version 00 traceparent.
+func Valid(v string) bool {
+	parts := strings.Split(v, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return false
+	}
+	for i, n := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != n || !isLowerHex(parts[i]) {
+			return false
+		}
+	}
+	return strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
+
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -10,12 +10,21 @@ const (
 	ErrInvalidToken               = "invalid token"
 	ErrForbidden                  = "forbidden"
 	ErrInsufficientRole           = "insufficient permissions"
+	ErrRoomAccessDenied           = "room access denied"
+	ErrRateLimited                = "rate limit exceeded"
 )
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// RateLimitedResponse accompanies 429 responses; RetryAfter is in seconds and
+// matches the Retry-After header.
+type RateLimitedResponse struct {
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retry_after"`
+}
+
 type OKResponse struct {
 	OK bool `json:"ok"`
 }
@@ -39,6 +48,10 @@ func NewErrorResponse(message string) ErrorResponse {
 	return ErrorResponse{Error: message}
 }
 
+func NewRateLimitedResponse(retryAfter int) RateLimitedResponse {
+	return RateLimitedResponse{Error: ErrRateLimited, RetryAfter: retryAfter}
+}
+
 func NewOKResponse() OKResponse {
 	return OKResponse{OK: true}
 }
@@ -0,0 +1,271 @@
+package wsconn
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	commonlog "msg_server/server/common/log"
+)
+
+// Policy decides what happens when a connection's outbound queue is full.
+type Policy string
+
+const (
+	PolicyDropOldest Policy = "drop_oldest"
+	PolicyDisconnect Policy = "disconnect"
+)
+
+type Config struct {
+	QueueSize    int
+	WriteTimeout time.Duration
+	Policy       Policy
+
+	// PingInterval must be shorter than PongWait; the peer is considered gone
+	// when nothing (pong or message) arrives within PongWait.
+	PingInterval time.Duration
+	PongWait     time.Duration
+
+	// On drain each client is told to reconnect after DrainReconnectAfter plus
+	// a jitter, and sockets are closed spread across DrainWindow.
+	DrainReconnectAfter time.Duration
+	DrainWindow         time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		QueueSize:           256,
+		WriteTimeout:        5 * time.Second,
+		Policy:              PolicyDisconnect,
+		PingInterval:        25 * time.Second,
+		PongWait:            60 * time.Second,
+		DrainReconnectAfter: 2 * time.Second,
+		DrainWindow:         5 * time.Second,
+	}
+}
+
+func ParsePolicy(v string) Policy {
+	switch Policy(strings.ToLower(strings.TrimSpace(v))) {
+	case PolicyDropOldest:
+		return PolicyDropOldest
+	default:
+		return PolicyDisconnect
+	}
+}
+
+func (c Config) normalized() Config {
+	def := DefaultConfig()
+	if c.QueueSize <= 0 {
+		c.QueueSize = def.QueueSize
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = def.WriteTimeout
+	}
+	if c.Policy != PolicyDropOldest {
+		c.Policy = PolicyDisconnect
+	}
+	if c.PongWait <= 0 {
+		c.PongWait = def.PongWait
+	}
+	if c.PingInterval <= 0 || c.PingInterval >= c.PongWait {
+		c.PingInterval = c.PongWait * 9 / 10
+	}
+	if c.DrainReconnectAfter <= 0 {
+		c.DrainReconnectAfter = def.DrainReconnectAfter
+	}
+	if c.DrainWindow <= 0 {
+		c.DrainWindow = def.DrainWindow
+	}
+	return c
+}
+
+// Conn owns the write side of a websocket. Send never blocks: frames are
+// queued and written by a dedicated goroutine, so one slow client cannot
+// stall fan-out to others.
+type Conn struct {
+	ws       *websocket.Conn
+	cfg      Config
+	metrics  *Metrics
+	registry *Registry
+	logTag   string
+
+	mu      sync.Mutex
+	out     chan []byte
+	closing bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func New(ws *websocket.Conn, cfg Config, metrics *Metrics, logTag string) *Conn {
+	cfg = cfg.normalized()
+	if metrics == nil {
+		metrics = NewMetrics("")
+	}
+	c := &Conn{
+		ws:      ws,
+		cfg:     cfg,
+		metrics: metrics,
+		logTag:  logTag,
+		out:     make(chan []byte, cfg.QueueSize),
+		done:    make(chan struct{}),
+	}
+	metrics.connections.Add(1)
+	go c.writeLoop()
+	return c
+}
+
+// WS exposes the underlying connection for the read side.
+func (c *Conn) WS() *websocket.Conn {
+	return c.ws
+}
+
+// StartKeepalive arms the read deadline and extends it on every pong. Call it
+// once from the reading goroutine before the read loop.
+func (c *Conn) StartKeepalive() {
+	c.Touch()
+	c.ws.SetPongHandler(func(string) error {
+		c.Touch()
+		return nil
+	})
+}
+
+// Touch extends the read deadline; call it after each message read.
+func (c *Conn) Touch() {
+	_ = c.ws.SetReadDeadline(time.Now().Add(c.cfg.PongWait))
+}
+
+func (c *Conn) Send(b []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return false
+	}
+	select {
+	case c.out <- b:
+		c.metrics.enqueued(len(c.out))
+		return true
+	default:
+	}
+
+	if c.cfg.Policy == PolicyDropOldest {
+		select {
+		case <-c.out:
+			c.metrics.queued.Add(-1)
+			c.metrics.dropped.Add(1)
+		default:
+		}
+		select {
+		case c.out <- b:
+			c.metrics.enqueued(len(c.out))
+			return true
+		default:
+			c.metrics.dropped.Add(1)
+			return false
+		}
+	}
+
+	c.closing = true
+	c.metrics.evicted.Add(1)
+	commonlog.Warnf("event=ws_send_queue action=evict status=ok service=%s %s queue_size=%d reason=slow_consumer", c.metrics.name, c.logTag, c.cfg.QueueSize)
+	go c.CloseWithReason(websocket.ClosePolicyViolation, "slow consumer")
+	return false
+}
+
+// CloseWithReason sends a close frame (best effort) and closes the socket.
+func (c *Conn) CloseWithReason(code int, reason string) {
+	_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.Close()
+}
+
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closing = true
+		c.mu.Unlock()
+		close(c.done)
+		_ = c.ws.Close()
+		c.metrics.connections.Add(-1)
+		if c.registry != nil {
+			c.registry.remove(c)
+		}
+	})
+}
+
+func (c *Conn) writeLoop() {
+	ping := time.NewTicker(c.cfg.PingInterval)
+	defer func() {
+		ping.Stop()
+		c.metrics.queued.Add(-int64(len(c.out)))
+	}()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ping.C:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
+				return
+			}
+		case b := <-c.out:
+			c.metrics.queued.Add(-1)
+			_ = c.ws.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := c.ws.WriteMessage(websocket.TextMessage, b); err != nil {
+				c.Close()
+				return
+			}
+			c.metrics.sent.Add(1)
+		}
+	}
+}
+
+type Metrics struct {
+	name           string
+	connections    atomic.Int64
+	queued         atomic.Int64
+	peakQueueDepth atomic.Int64
+	sent           atomic.Int64
+	dropped        atomic.Int64
+	evicted        atomic.Int64
+}
+
+type MetricsSnapshot struct {
+	Service        string `json:"service"`
+	Connections    int64  `json:"connections"`
+	QueuedMessages int64  `json:"queued_messages"`
+	PeakQueueDepth int64  `json:"peak_queue_depth"`
+	Sent           int64  `json:"sent_total"`
+	Dropped        int64  `json:"dropped_total"`
+	Evicted        int64  `json:"evicted_total"`
+}
+
+func NewMetrics(name string) *Metrics {
+	return &Metrics{name: name}
+}
+
+func (m *Metrics) enqueued(depth int) {
+	m.queued.Add(1)
+	d := int64(depth)
+	for {
+		peak := m.peakQueueDepth.Load()
+		if d <= peak || m.peakQueueDepth.CompareAndSwap(peak, d) {
+			return
+		}
+	}
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		Service:        m.name,
+		Connections:    m.connections.Load(),
+		QueuedMessages: m.queued.Load(),
+		PeakQueueDepth: m.peakQueueDepth.Load(),
+		Sent:           m.sent.Load(),
+		Dropped:        m.dropped.Load(),
+		Evicted:        m.evicted.Load(),
+	}
+}
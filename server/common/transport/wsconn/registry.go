@@ -0,0 +1,89 @@
+package wsconn
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	commonlog "msg_server/server/common/log"
+)
+
+// drainFlushGrace gives writer goroutines time to flush the draining frame
+// before the first socket is closed.
+const drainFlushGrace = 250 * time.Millisecond
+
+// Registry tracks live connections so they can be drained on shutdown.
+type Registry struct {
+	name     string
+	mu       sync.Mutex
+	conns    map[*Conn]struct{}
+	draining atomic.Bool
+}
+
+func NewRegistry(name string) *Registry {
+	return &Registry{name: name, conns: map[*Conn]struct{}{}}
+}
+
+func (r *Registry) Add(c *Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.registry = r
+	r.conns[c] = struct{}{}
+}
+
+func (r *Registry) remove(c *Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, c)
+}
+
+// Draining reports whether new upgrades should be refused.
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Drain stops accepting connections, sends every client a server.draining
+// frame with a jittered reconnect hint and closes the sockets spread across
+// cfg.DrainWindow. Remaining sockets are closed at once if ctx ends first.
+func (r *Registry) Drain(ctx context.Context, cfg Config) {
+	cfg = cfg.normalized()
+	r.draining.Store(true)
+
+	r.mu.Lock()
+	conns := make([]*Conn, 0, len(r.conns))
+	for c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.mu.Unlock()
+	if len(conns) == 0 {
+		return
+	}
+	commonlog.Infof("event=ws_drain action=start service=%s connections=%d window_ms=%d", r.name, len(conns), cfg.DrainWindow.Milliseconds())
+
+	for _, c := range conns {
+		reconnectAfter := cfg.DrainReconnectAfter + time.Duration(rand.Int64N(int64(cfg.DrainWindow)))
+		b, _ := json.Marshal(map[string]any{
+			"type":               "server.draining",
+			"reconnect_after_ms": reconnectAfter.Milliseconds(),
+		})
+		c.Send(b)
+	}
+
+	step := cfg.DrainWindow / time.Duration(len(conns))
+	wait := drainFlushGrace
+	for _, c := range conns {
+		select {
+		case <-ctx.Done():
+			wait = 0
+		case <-time.After(wait):
+			wait = step
+		}
+		c.CloseWithReason(websocket.CloseGoingAway, "server draining")
+	}
+	commonlog.Infof("event=ws_drain action=done service=%s connections=%d", r.name, len(conns))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -16,16 +17,20 @@ import (
 )
 
 type Handler struct {
-	fileRepo   *repository.FileRepository
-	chatSvc    *dbservice.ChatService
-	userSvc    *dbservice.UserService
-	sessionSvc *dbservice.SessionService
-	tenantSvc  *dbservice.TenantService
-	readyCheck func(context.Context) error
+	fileRepo      *repository.FileRepository
+	chatSvc       *dbservice.ChatService
+	callSvc       *dbservice.CallService
+	userSvc       *dbservice.UserService
+	sessionSvc    *dbservice.SessionService
+	tenantSvc     *dbservice.TenantService
+	webhookSvc    *dbservice.WebhookService
+	rateLimitSvc  *dbservice.RateLimitService
+	moderationSvc *dbservice.ModerationService
+	readyCheck    func(context.Context) error
 }
 
-func NewHandler(fileRepo *repository.FileRepository, chatSvc *dbservice.ChatService, userSvc *dbservice.UserService, sessionSvc *dbservice.SessionService, tenantSvc *dbservice.TenantService, readyCheck func(context.Context) error) *Handler {
-	return &Handler{fileRepo: fileRepo, chatSvc: chatSvc, userSvc: userSvc, sessionSvc: sessionSvc, tenantSvc: tenantSvc, readyCheck: readyCheck}
+func NewHandler(fileRepo *repository.FileRepository, chatSvc *dbservice.ChatService, callSvc *dbservice.CallService, userSvc *dbservice.UserService, sessionSvc *dbservice.SessionService, tenantSvc *dbservice.TenantService, webhookSvc *dbservice.WebhookService, rateLimitSvc *dbservice.RateLimitService, moderationSvc *dbservice.ModerationService, readyCheck func(context.Context) error) *Handler {
+	return &Handler{fileRepo: fileRepo, chatSvc: chatSvc, callSvc: callSvc, userSvc: userSvc, sessionSvc: sessionSvc, tenantSvc: tenantSvc, webhookSvc: webhookSvc, rateLimitSvc: rateLimitSvc, moderationSvc: moderationSvc, readyCheck: readyCheck}
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
@@ -76,7 +81,29 @@ func (h *Handler) registerDBRoutes(api *gin.RouterGroup) {
 	api.POST("/rooms", h.createRoom)
 	api.POST("/rooms/members", h.addMember)
 	api.POST("/rooms/members/check", h.checkRoomMember)
+	api.POST("/rooms/members/get", h.getRoomMember)
+	api.POST("/rooms/ids", h.listMemberRoomIDs)
+	api.POST("/rooms/members/list", h.listRoomMemberIDs)
+	api.POST("/rooms/members/remove", h.removeMember)
+	api.POST("/rooms/members/role", h.setMemberRole)
+	api.POST("/rooms/transfer-ownership", h.transferRoomOwnership)
+	api.POST("/rooms/get", h.getRoom)
+	api.POST("/rooms/rename", h.renameRoom)
+	api.POST("/rooms/archive", h.setRoomArchived)
+	api.POST("/rooms/delete", h.deleteRoom)
 	api.POST("/messages", h.createMessage)
+	api.POST("/messages/system", h.createSystemMessage)
+	api.POST("/messages/get", h.getMessage)
+	api.POST("/messages/update", h.updateMessage)
+	api.POST("/messages/delete", h.deleteMessage)
+	api.POST("/messages/revisions", h.listMessageRevisions)
+	api.POST("/messages/pin", h.pinMessage)
+	api.POST("/messages/unpin", h.unpinMessage)
+	api.POST("/messages/pins", h.listPinnedMessages)
+	api.POST("/messages/thread", h.listThreadReplies)
+	api.POST("/messages/thread/summary", h.threadSummary)
+	api.POST("/messages/reactions/add", h.addReaction)
+	api.POST("/messages/reactions/remove", h.removeReaction)
 	api.POST("/messages/read", h.markReadUpTo)
 	api.POST("/messages/list", h.listMessages)
 	api.POST("/messages/search", h.searchMessages)
@@ -85,6 +112,44 @@ func (h *Handler) registerDBRoutes(api *gin.RouterGroup) {
 	api.POST("/messages/unread-count", h.unreadCount)
 	api.POST("/messages/unread-counts", h.unreadCounts)
 	api.POST("/rooms/list", h.listMyRooms)
+	api.POST("/sync/changes", h.listSyncChanges)
+	api.POST("/calls/create", h.createCall)
+	api.POST("/calls/get", h.getCall)
+	api.POST("/calls/live", h.getLiveCall)
+	api.POST("/calls/transition", h.transitionCall)
+	api.POST("/calls/list", h.listCalls)
+	api.POST("/calls/expired", h.listExpiredCalls)
+	api.POST("/calls/turn-audit", h.recordTurnCredential)
+	api.POST("/webhooks/create", h.createWebhook)
+	api.POST("/webhooks/list", h.listWebhooks)
+	api.POST("/webhooks/get", h.getWebhook)
+	api.POST("/webhooks/update", h.updateWebhook)
+	api.POST("/webhooks/rotate-secret", h.rotateWebhookSecret)
+	api.POST("/webhooks/delete", h.deleteWebhook)
+	api.POST("/webhooks/deliveries/enqueue", h.enqueueWebhookDeliveries)
+	api.POST("/webhooks/deliveries/claim", h.claimWebhookDeliveries)
+	api.POST("/webhooks/deliveries/attempt", h.recordWebhookAttempt)
+	api.POST("/webhooks/deliveries/list", h.listWebhookDeliveries)
+	api.POST("/incoming-webhooks/create", h.createIncomingWebhook)
+	api.POST("/incoming-webhooks/list", h.listIncomingWebhooks)
+	api.POST("/incoming-webhooks/revoke", h.revokeIncomingWebhook)
+	api.POST("/incoming-webhooks/authenticate", h.authenticateIncomingWebhook)
+	api.POST("/rate-limits/list", h.listRateLimitPolicies)
+	api.POST("/rate-limits/upsert", h.upsertRateLimitPolicy)
+	api.POST("/rate-limits/delete", h.deleteRateLimitPolicy)
+	api.POST("/moderation/rules/list", h.listModerationRules)
+	api.POST("/moderation/rules/create", h.createModerationRule)
+	api.POST("/moderation/rules/update", h.updateModerationRule)
+	api.POST("/moderation/rules/delete", h.deleteModerationRule)
+	api.POST("/moderation/flags/create", h.createModerationFlag)
+	api.POST("/moderation/flags/list", h.listModerationFlags)
+	api.POST("/moderation/flags/resolve", h.resolveModerationFlag)
+	api.POST("/moderation/reports/create", h.reportMessage)
+	api.POST("/moderation/messages/delete", h.moderatorDeleteMessage)
+	api.POST("/moderation/members/remove", h.moderatorRemoveMember)
+	api.POST("/moderation/members/unban", h.moderatorUnbanMember)
+	api.POST("/moderation/bans/list", h.listRoomBans)
+	api.POST("/moderation/audit/list", h.listModerationAudit)
 
 	api.POST("/session/device/login", h.upsertDeviceSession)
 	api.POST("/session/device/validate", h.validateSession)
@@ -491,11 +556,13 @@ func (h *Handler) searchFilesByRoom(c *gin.Context) {
 
 func (h *Handler) searchMessages(c *gin.Context) {
 	var req struct {
-		TenantID string  `json:"tenant_id" binding:"required"`
-		Q        string  `json:"q" binding:"required"`
-		RoomID   *string `json:"room_id"`
-		Limit    int     `json:"limit"`
-		CursorID *string `json:"cursor_id"`
+		TenantID        string     `json:"tenant_id" binding:"required"`
+		UserID          string     `json:"user_id" binding:"required"`
+		Q               string     `json:"q" binding:"required"`
+		RoomID          *string    `json:"room_id"`
+		Limit           int        `json:"limit"`
+		CursorCreatedAt *time.Time `json:"cursor_created_at"`
+		CursorID        *string    `json:"cursor_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -504,7 +571,7 @@ func (h *Handler) searchMessages(c *gin.Context) {
 	if req.Limit <= 0 || req.Limit > 200 {
 		req.Limit = 30
 	}
-	items, err := h.chatSvc.SearchMessages(c.Request.Context(), req.TenantID, req.Q, req.RoomID, req.Limit, req.CursorID)
+	items, err := h.chatSvc.SearchMessages(c.Request.Context(), req.TenantID, req.UserID, req.Q, req.RoomID, req.Limit, req.CursorCreatedAt, req.CursorID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -535,6 +602,52 @@ func (h *Handler) createRoom(c *gin.Context) {
 }
 
 func (h *Handler) addMember(c *gin.Context) {
+	var req struct {
+		TenantID string              `json:"tenant_id" binding:"required"`
+		RoomID   string              `json:"room_id" binding:"required"`
+		UserID   string              `json:"user_id" binding:"required"`
+		Role     chatdomain.RoomRole `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role != "" && (!req.Role.Valid() || req.Role == chatdomain.RoomRoleOwner) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be admin, member or read_only"})
+		return
+	}
+	added, ok, err := h.chatSvc.AddMember(c.Request.Context(), req.TenantID, req.RoomID, req.UserID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "added": added})
+}
+
+func (h *Handler) setMemberRole(c *gin.Context) {
+	var req struct {
+		TenantID string              `json:"tenant_id" binding:"required"`
+		RoomID   string              `json:"room_id" binding:"required"`
+		UserID   string              `json:"user_id" binding:"required"`
+		Role     chatdomain.RoomRole `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Role.Valid() || req.Role == chatdomain.RoomRoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be admin, member or read_only"})
+		return
+	}
+	member, ok, err := h.chatSvc.SetMemberRole(c.Request.Context(), req.TenantID, req.RoomID, req.UserID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "member": member})
+}
+
+func (h *Handler) transferRoomOwnership(c *gin.Context) {
 	var req struct {
 		TenantID string `json:"tenant_id" binding:"required"`
 		RoomID   string `json:"room_id" binding:"required"`
@@ -544,11 +657,100 @@ func (h *Handler) addMember(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.chatSvc.AddMember(c.Request.Context(), req.TenantID, req.RoomID, req.UserID); err != nil {
+	changed, ok, err := h.chatSvc.TransferOwnership(c.Request.Context(), req.TenantID, req.RoomID, req.UserID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "members": changed})
+}
+
+func (h *Handler) removeMember(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		UserID   string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ok, err := h.chatSvc.RemoveMember(c.Request.Context(), req.TenantID, req.RoomID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok})
+}
+
+func (h *Handler) getRoom(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	room, ok, err := h.chatSvc.GetRoom(c.Request.Context(), req.TenantID, req.RoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "room": room})
+}
+
+func (h *Handler) renameRoom(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		Name     string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	room, ok, err := h.chatSvc.RenameRoom(c.Request.Context(), req.TenantID, req.RoomID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "room": room})
+}
+
+func (h *Handler) setRoomArchived(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		Archived bool   `json:"archived"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	room, ok, err := h.chatSvc.SetRoomArchived(c.Request.Context(), req.TenantID, req.RoomID, req.Archived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "room": room})
+}
+
+func (h *Handler) deleteRoom(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	memberIDs, ok, err := h.chatSvc.DeleteRoom(c.Request.Context(), req.TenantID, req.RoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "member_ids": memberIDs})
 }
 
 func (h *Handler) checkRoomMember(c *gin.Context) {
@@ -569,7 +771,81 @@ func (h *Handler) checkRoomMember(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": ok})
 }
 
+func (h *Handler) getRoomMember(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		UserID   string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	member, ok, err := h.chatSvc.GetRoomMember(c.Request.Context(), req.TenantID, req.RoomID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "member": member})
+}
+
+func (h *Handler) listMemberRoomIDs(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		UserID   string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.chatSvc.ListMemberRoomIDs(c.Request.Context(), req.TenantID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) listRoomMemberIDs(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.chatSvc.ListRoomMemberIDs(c.Request.Context(), req.TenantID, req.RoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
 func (h *Handler) createMessage(c *gin.Context) {
+	var req struct {
+		chatdomain.Message
+		// FlagRules names the moderation rules that flagged the message.
+		FlagRules []string `json:"flag_rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.RoomID == "" || req.SenderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, room_id, sender_id are required"})
+		return
+	}
+	created, ok, err := h.chatSvc.CreateMessage(c.Request.Context(), req.Message, req.FlagRules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ok": ok, "message": created})
+}
+
+func (h *Handler) createSystemMessage(c *gin.Context) {
 	var req chatdomain.Message
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -579,7 +855,7 @@ func (h *Handler) createMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, room_id, sender_id are required"})
 		return
 	}
-	created, err := h.chatSvc.CreateMessage(c.Request.Context(), req)
+	created, err := h.chatSvc.CreateSystemMessage(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -587,6 +863,164 @@ func (h *Handler) createMessage(c *gin.Context) {
 	c.JSON(http.StatusCreated, created)
 }
 
+func (h *Handler) getMessage(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok, err := h.chatSvc.GetMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "message": item})
+}
+
+func (h *Handler) updateMessage(c *gin.Context) {
+	var req struct {
+		TenantID  string   `json:"tenant_id" binding:"required"`
+		RoomID    string   `json:"room_id" binding:"required"`
+		MessageID string   `json:"message_id" binding:"required"`
+		EditorID  string   `json:"editor_id" binding:"required"`
+		Body      string   `json:"body" binding:"required"`
+		FlagRules []string `json:"flag_rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := h.chatSvc.UpdateMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.EditorID, req.Body, req.FlagRules)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *Handler) deleteMessage(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+		ActorID   string `json:"actor_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deleted, err := h.chatSvc.DeleteMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.ActorID)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deleted)
+}
+
+func (h *Handler) listMessageRevisions(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.chatSvc.ListMessageRevisions(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) listThreadReplies(c *gin.Context) {
+	var req struct {
+		TenantID        string `json:"tenant_id" binding:"required"`
+		RoomID          string `json:"room_id" binding:"required"`
+		ParentMessageID string `json:"parent_message_id" binding:"required"`
+		UserID          string `json:"user_id"`
+		Limit           int    `json:"limit"`
+		CursorSeq       *int64 `json:"cursor_seq"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 200 {
+		req.Limit = 50
+	}
+	items, err := h.chatSvc.ListThreadReplies(c.Request.Context(), req.TenantID, req.RoomID, req.ParentMessageID, req.UserID, req.Limit, req.CursorSeq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) threadSummary(c *gin.Context) {
+	var req struct {
+		TenantID        string `json:"tenant_id" binding:"required"`
+		RoomID          string `json:"room_id" binding:"required"`
+		ParentMessageID string `json:"parent_message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.chatSvc.GetThreadSummary(c.Request.Context(), req.TenantID, req.RoomID, req.ParentMessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) addReaction(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+		UserID    string `json:"user_id" binding:"required"`
+		Emoji     string `json:"emoji" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, changed, err := h.chatSvc.AddReaction(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.UserID, req.Emoji)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed, "reaction": item})
+}
+
+func (h *Handler) removeReaction(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+		UserID    string `json:"user_id" binding:"required"`
+		Emoji     string `json:"emoji" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, changed, err := h.chatSvc.RemoveReaction(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.UserID, req.Emoji)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed, "reaction": item})
+}
+
 func (h *Handler) markReadUpTo(c *gin.Context) {
 	var req struct {
 		TenantID  string `json:"tenant_id" binding:"required"`
@@ -599,7 +1033,7 @@ func (h *Handler) markReadUpTo(c *gin.Context) {
 		return
 	}
 	if err := h.chatSvc.MarkReadUpTo(c.Request.Context(), req.TenantID, req.RoomID, req.UserID, req.MessageID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(messageErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
@@ -607,10 +1041,11 @@ func (h *Handler) markReadUpTo(c *gin.Context) {
 
 func (h *Handler) listMessages(c *gin.Context) {
 	var req struct {
-		TenantID string  `json:"tenant_id" binding:"required"`
-		RoomID   string  `json:"room_id" binding:"required"`
-		Limit    int     `json:"limit"`
-		CursorID *string `json:"cursor_id"`
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		UserID    string `json:"user_id"`
+		Limit     int    `json:"limit"`
+		CursorSeq *int64 `json:"cursor_seq"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -619,7 +1054,7 @@ func (h *Handler) listMessages(c *gin.Context) {
 	if req.Limit <= 0 || req.Limit > 200 {
 		req.Limit = 50
 	}
-	items, err := h.chatSvc.ListMessages(c.Request.Context(), req.TenantID, req.RoomID, req.Limit, req.CursorID)
+	items, err := h.chatSvc.ListMessages(c.Request.Context(), req.TenantID, req.RoomID, req.UserID, req.Limit, req.CursorSeq)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -720,3 +1155,168 @@ func (h *Handler) listMyRooms(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, items)
 }
+
+func (h *Handler) listSyncChanges(c *gin.Context) {
+	var req struct {
+		TenantID string                 `json:"tenant_id" binding:"required"`
+		UserID   string                 `json:"user_id" binding:"required"`
+		Since    *chatdomain.SyncCursor `json:"since"`
+		Limit    int                    `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	batch, err := h.chatSvc.ListSyncChanges(c.Request.Context(), req.TenantID, req.UserID, req.Since, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *Handler) createCall(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		CallerID string `json:"caller_id" binding:"required"`
+		Media    string `json:"media" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	call, created, err := h.callSvc.CreateCall(c.Request.Context(), chatdomain.Call{
+		TenantID: req.TenantID,
+		RoomID:   req.RoomID,
+		CallerID: req.CallerID,
+		Media:    req.Media,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"created": created, "call": call})
+}
+
+func (h *Handler) getCall(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		CallID   string `json:"call_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	call, ok, err := h.callSvc.GetCall(c.Request.Context(), req.TenantID, req.RoomID, req.CallID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "call": call})
+}
+
+func (h *Handler) getLiveCall(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	call, ok, err := h.callSvc.GetLiveCall(c.Request.Context(), req.TenantID, req.RoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "call": call})
+}
+
+func (h *Handler) transitionCall(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		CallID   string `json:"call_id" binding:"required"`
+		UserID   string `json:"user_id"`
+		Action   string `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	call, changed, err := h.callSvc.TransitionCall(c.Request.Context(), req.TenantID, req.RoomID, req.CallID, req.UserID, chatdomain.CallAction(req.Action))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrCallNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed, "call": call})
+}
+
+func (h *Handler) listCalls(c *gin.Context) {
+	var req struct {
+		TenantID        string     `json:"tenant_id" binding:"required"`
+		RoomID          string     `json:"room_id" binding:"required"`
+		Limit           int        `json:"limit"`
+		CursorCreatedAt *time.Time `json:"cursor_created_at"`
+		CursorID        *string    `json:"cursor_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.callSvc.ListCalls(c.Request.Context(), req.TenantID, req.RoomID, req.Limit, req.CursorCreatedAt, req.CursorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) listExpiredCalls(c *gin.Context) {
+	var req struct {
+		TenantID      string    `json:"tenant_id" binding:"required"`
+		CreatedBefore time.Time `json:"created_before" binding:"required"`
+		Limit         int       `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.callSvc.ListExpiredRingingCalls(c.Request.Context(), req.TenantID, req.CreatedBefore, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) recordTurnCredential(c *gin.Context) {
+	var req chatdomain.TurnCredentialAudit
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.UserID == "" || req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, user_id and username are required"})
+		return
+	}
+	item, err := h.callSvc.RecordTurnCredential(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func messageErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
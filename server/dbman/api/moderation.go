@@ -0,0 +1,256 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"msg_server/server/common/moderation"
+)
+
+func (h *Handler) listModerationRules(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.moderationSvc.ListRules(c.Request.Context(), req.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) createModerationRule(c *gin.Context) {
+	var req moderation.Rule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.Name == "" || req.Kind == "" || req.Action == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, name, kind and action are required"})
+		return
+	}
+	item, err := h.moderationSvc.CreateRule(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) updateModerationRule(c *gin.Context) {
+	var req moderation.Rule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.ID == "" || req.Name == "" || req.Kind == "" || req.Action == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, id, name, kind and action are required"})
+		return
+	}
+	item, ok, err := h.moderationSvc.UpdateRule(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "rule": item})
+}
+
+func (h *Handler) deleteModerationRule(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RuleID   string `json:"rule_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ok, err := h.moderationSvc.DeleteRule(c.Request.Context(), req.TenantID, req.RuleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok})
+}
+
+func (h *Handler) createModerationFlag(c *gin.Context) {
+	var req moderation.Flag
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.Source == "" || req.SubjectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, source and subject_id are required"})
+		return
+	}
+	item, err := h.moderationSvc.CreateFlag(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) listModerationFlags(c *gin.Context) {
+	var req struct {
+		TenantID string                `json:"tenant_id" binding:"required"`
+		Status   moderation.FlagStatus `json:"status"`
+		Limit    int                   `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.moderationSvc.ListFlags(c.Request.Context(), req.TenantID, req.Status, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) resolveModerationFlag(c *gin.Context) {
+	var req struct {
+		TenantID   string                `json:"tenant_id" binding:"required"`
+		FlagID     string                `json:"flag_id" binding:"required"`
+		ReviewerID string                `json:"reviewer_id" binding:"required"`
+		Status     moderation.FlagStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status != moderation.FlagApproved && req.Status != moderation.FlagRemoved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be approved or removed"})
+		return
+	}
+	flag, removed, ok, err := h.moderationSvc.ResolveFlag(c.Request.Context(), req.TenantID, req.FlagID, req.ReviewerID, req.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "flag": flag, "removed_message": removed})
+}
+
+func (h *Handler) reportMessage(c *gin.Context) {
+	var req struct {
+		TenantID   string `json:"tenant_id" binding:"required"`
+		RoomID     string `json:"room_id" binding:"required"`
+		MessageID  string `json:"message_id" binding:"required"`
+		ReporterID string `json:"reporter_id" binding:"required"`
+		Reason     string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	flag, ok, err := h.moderationSvc.ReportMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.ReporterID, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "flag": flag})
+}
+
+func (h *Handler) moderatorDeleteMessage(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+		ActorID   string `json:"actor_id" binding:"required"`
+		Reason    string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	msg, entry, ok, err := h.moderationSvc.DeleteMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.ActorID, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "message": msg, "audit": entry})
+}
+
+func (h *Handler) moderatorRemoveMember(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		UserID   string `json:"user_id" binding:"required"`
+		ActorID  string `json:"actor_id" binding:"required"`
+		Reason   string `json:"reason"`
+		Ban      bool   `json:"ban"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry, removed, ok, err := h.moderationSvc.RemoveMember(c.Request.Context(), req.TenantID, req.RoomID, req.UserID, req.ActorID, req.Reason, req.Ban)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "removed": removed, "audit": entry})
+}
+
+func (h *Handler) moderatorUnbanMember(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		UserID   string `json:"user_id" binding:"required"`
+		ActorID  string `json:"actor_id" binding:"required"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry, ok, err := h.moderationSvc.UnbanMember(c.Request.Context(), req.TenantID, req.RoomID, req.UserID, req.ActorID, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "audit": entry})
+}
+
+func (h *Handler) listRoomBans(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.moderationSvc.ListBans(c.Request.Context(), req.TenantID, req.RoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) listModerationAudit(c *gin.Context) {
+	var req struct {
+		TenantID        string     `json:"tenant_id" binding:"required"`
+		RoomID          string     `json:"room_id"`
+		Limit           int        `json:"limit"`
+		CursorCreatedAt *time.Time `json:"cursor_created_at"`
+		CursorID        *string    `json:"cursor_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.moderationSvc.ListAudit(c.Request.Context(), req.TenantID, req.RoomID, req.Limit, req.CursorCreatedAt, req.CursorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
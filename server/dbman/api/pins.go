@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) pinMessage(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+		UserID    string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.chatSvc.PinMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID, req.UserID)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) unpinMessage(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		RoomID    string `json:"room_id" binding:"required"`
+		MessageID string `json:"message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changed, err := h.chatSvc.UnpinMessage(c.Request.Context(), req.TenantID, req.RoomID, req.MessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed})
+}
+
+func (h *Handler) listPinnedMessages(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		Limit    int    `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.chatSvc.ListPinnedMessages(c.Request.Context(), req.TenantID, req.RoomID, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
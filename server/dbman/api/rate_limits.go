@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	chatdomain "msg_server/server/chat/domain"
+)
+
+func (h *Handler) listRateLimitPolicies(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.rateLimitSvc.ListPolicies(c.Request.Context(), req.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) upsertRateLimitPolicy(c *gin.Context) {
+	var req chatdomain.RateLimitPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.Scope == "" || req.Action == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, scope and action are required"})
+		return
+	}
+	if !req.Scope.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be tenant, room or user"})
+		return
+	}
+	if !req.Action.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be message or api"})
+		return
+	}
+	if req.PerMinute < 0 || req.Burst < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "per_minute and burst must not be negative"})
+		return
+	}
+	item, err := h.rateLimitSvc.UpsertPolicy(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) deleteRateLimitPolicy(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		PolicyID string `json:"policy_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ok, err := h.rateLimitSvc.DeletePolicy(c.Request.Context(), req.TenantID, req.PolicyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok})
+}
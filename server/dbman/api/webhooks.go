@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	chatdomain "msg_server/server/chat/domain"
+)
+
+func (h *Handler) createWebhook(c *gin.Context) {
+	var req chatdomain.Webhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.TargetURL == "" || req.Secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, target_url and secret are required"})
+		return
+	}
+	item, err := h.webhookSvc.CreateWebhook(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) listWebhooks(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.webhookSvc.ListWebhooks(c.Request.Context(), req.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) getWebhook(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		WebhookID string `json:"webhook_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok, err := h.webhookSvc.GetWebhook(c.Request.Context(), req.TenantID, req.WebhookID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "webhook": item})
+}
+
+func (h *Handler) updateWebhook(c *gin.Context) {
+	var req struct {
+		TenantID    string   `json:"tenant_id" binding:"required"`
+		WebhookID   string   `json:"webhook_id" binding:"required"`
+		TargetURL   *string  `json:"target_url"`
+		EventTypes  []string `json:"event_types"`
+		Description *string  `json:"description"`
+		IsActive    *bool    `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok, err := h.webhookSvc.UpdateWebhook(c.Request.Context(), req.TenantID, req.WebhookID, req.TargetURL, req.EventTypes, req.Description, req.IsActive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "webhook": item})
+}
+
+func (h *Handler) rotateWebhookSecret(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		WebhookID string `json:"webhook_id" binding:"required"`
+		Secret    string `json:"secret" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok, err := h.webhookSvc.RotateWebhookSecret(c.Request.Context(), req.TenantID, req.WebhookID, req.Secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "webhook": item})
+}
+
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		WebhookID string `json:"webhook_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ok, err := h.webhookSvc.DeleteWebhook(c.Request.Context(), req.TenantID, req.WebhookID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok})
+}
+
+func (h *Handler) enqueueWebhookDeliveries(c *gin.Context) {
+	var req struct {
+		TenantID  string          `json:"tenant_id" binding:"required"`
+		EventID   string          `json:"event_id" binding:"required"`
+		EventType string          `json:"event_type" binding:"required"`
+		Payload   json.RawMessage `json:"payload" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	queued, err := h.webhookSvc.EnqueueDeliveries(c.Request.Context(), req.TenantID, req.EventID, req.EventType, req.Payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"queued": queued})
+}
+
+func (h *Handler) claimWebhookDeliveries(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		Limit    int    `json:"limit"`
+		LeaseSec int    `json:"lease_sec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.webhookSvc.ClaimDeliveries(c.Request.Context(), req.TenantID, req.Limit, time.Duration(req.LeaseSec)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) recordWebhookAttempt(c *gin.Context) {
+	var req chatdomain.WebhookAttempt
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.DeliveryID == "" || req.WebhookID == "" || req.Status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, delivery_id, webhook_id and status are required"})
+		return
+	}
+	disabled, err := h.webhookSvc.RecordAttempt(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"disabled": disabled})
+}
+
+func (h *Handler) listWebhookDeliveries(c *gin.Context) {
+	var req struct {
+		TenantID        string     `json:"tenant_id" binding:"required"`
+		WebhookID       string     `json:"webhook_id" binding:"required"`
+		Limit           int        `json:"limit"`
+		CursorCreatedAt *time.Time `json:"cursor_created_at"`
+		CursorID        *string    `json:"cursor_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.webhookSvc.ListDeliveries(c.Request.Context(), req.TenantID, req.WebhookID, req.Limit, req.CursorCreatedAt, req.CursorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) createIncomingWebhook(c *gin.Context) {
+	var req chatdomain.IncomingWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TenantID == "" || req.RoomID == "" || req.Name == "" || req.TokenHash == "" || req.CreatedBy == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, room_id, name, token_hash and created_by are required"})
+		return
+	}
+	item, ok, err := h.webhookSvc.CreateIncomingWebhook(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "hook": item})
+}
+
+func (h *Handler) listIncomingWebhooks(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.webhookSvc.ListIncomingWebhooks(c.Request.Context(), req.TenantID, req.RoomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) revokeIncomingWebhook(c *gin.Context) {
+	var req struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		RoomID   string `json:"room_id" binding:"required"`
+		HookID   string `json:"hook_id" binding:"required"`
+		ActorID  string `json:"actor_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok, err := h.webhookSvc.RevokeIncomingWebhook(c.Request.Context(), req.TenantID, req.RoomID, req.HookID, req.ActorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "hook": item})
+}
+
+func (h *Handler) authenticateIncomingWebhook(c *gin.Context) {
+	var req struct {
+		TenantID  string `json:"tenant_id" binding:"required"`
+		HookID    string `json:"hook_id" binding:"required"`
+		TokenHash string `json:"token_hash" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, ok, err := h.webhookSvc.AuthenticateIncomingWebhook(c.Request.Context(), req.TenantID, req.HookID, req.TokenHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "hook": item})
+}
@@ -12,6 +12,9 @@ import (
 
 	"msg_server/server/common/infra/db"
 	commondbman "msg_server/server/common/infra/dbman"
+	"msg_server/server/common/infra/mq"
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/middleware"
 	dbapi "msg_server/server/dbman/api"
 	"msg_server/server/dbman/repository"
 	dbservice "msg_server/server/dbman/service"
@@ -21,12 +24,20 @@ type Config struct {
 	Port          string
 	PostgresDSN   string
 	DBManEndpoint string
+
+	LavinMQURL         string
+	MQPublisher        mq.PublisherConfig
+	OutboxRelayEnabled bool
+	OutboxRelay        dbservice.OutboxRelayConfig
 }
 
 type Server struct {
 	HTTPServer     *http.Server
 	DB             *pgxpool.Pool
 	TenantDBRouter *db.TenantDBRouter
+
+	relayCancel context.CancelFunc
+	relayDone   chan struct{}
 }
 
 func NewServer(cfg Config) (*Server, error) {
@@ -45,16 +56,26 @@ func NewServer(cfg Config) (*Server, error) {
 	tenantDBRouter := db.NewTenantDBRouterWithProvider(dbPool, tenantMetaProvider)
 	fileRepo := repository.NewFileRepository(tenantDBRouter)
 	chatRepo := repository.NewChatRepository(tenantDBRouter)
+	callRepo := repository.NewCallRepository(tenantDBRouter)
+	webhookRepo := repository.NewWebhookRepository(tenantDBRouter)
+	rateLimitRepo := repository.NewRateLimitRepository(tenantDBRouter)
+	moderationRepo := repository.NewModerationRepository(tenantDBRouter)
 	userRepo := repository.NewUserRepository(tenantDBRouter)
 	sessionRepo := repository.NewSessionRepository(tenantDBRouter)
 	tenantRepo := repository.NewTenantRepository(dbPool)
 	chatSvc := dbservice.NewChatService(chatRepo)
+	callSvc := dbservice.NewCallService(callRepo)
+	webhookSvc := dbservice.NewWebhookService(webhookRepo)
+	rateLimitSvc := dbservice.NewRateLimitService(rateLimitRepo)
+	moderationSvc := dbservice.NewModerationService(moderationRepo)
 	userSvc := dbservice.NewUserService(userRepo)
 	sessionSvc := dbservice.NewSessionService(sessionRepo)
 	tenantSvc := dbservice.NewTenantService(tenantRepo, tenantDBRouter)
+	outboxRepo := repository.NewOutboxRepository(tenantDBRouter)
 
-	h := dbapi.NewHandler(fileRepo, chatSvc, userSvc, sessionSvc, tenantSvc, dbPool.Ping)
+	h := dbapi.NewHandler(fileRepo, chatSvc, callSvc, userSvc, sessionSvc, tenantSvc, webhookSvc, rateLimitSvc, moderationSvc, dbPool.Ping)
 	r := gin.Default()
+	r.Use(middleware.TraceContext())
 	h.RegisterRoutes(r)
 
 	httpServer := &http.Server{
@@ -65,10 +86,48 @@ func NewServer(cfg Config) (*Server, error) {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	return &Server{HTTPServer: httpServer, DB: dbPool, TenantDBRouter: tenantDBRouter}, nil
+	s := &Server{HTTPServer: httpServer, DB: dbPool, TenantDBRouter: tenantDBRouter}
+	if cfg.OutboxRelayEnabled {
+		relayCtx, relayCancel := context.WithCancel(context.Background())
+		s.relayCancel = relayCancel
+		s.relayDone = make(chan struct{})
+		go func() {
+			defer close(s.relayDone)
+			runOutboxRelay(relayCtx, cfg, outboxRepo, tenantRepo, tenantSvc)
+		}()
+	}
+	return s, nil
+}
+
+// runOutboxRelay connects to LavinMQ in the background so dbman keeps serving
+// writes while the broker is down; pending events wait in the outbox. Once the
+// first dial succeeds the publisher reconnects on its own.
+func runOutboxRelay(ctx context.Context, cfg Config, outboxRepo *repository.OutboxRepository, tenantRepo *repository.TenantRepository, provider mq.TenantMQMetaProvider) {
+	for {
+		publisher, err := mq.NewAMQPPublisher(cfg.LavinMQURL, provider, cfg.MQPublisher)
+		if err == nil {
+			commonlog.Infof("event=dbman_outbox action=start status=ok")
+			dbservice.NewOutboxRelay(outboxRepo, tenantRepo, publisher, cfg.OutboxRelay).Run(ctx)
+			publisher.Close()
+			return
+		}
+		commonlog.Warnf("event=dbman_outbox action=connect status=failed error=%v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.relayCancel != nil {
+		s.relayCancel()
+		select {
+		case <-s.relayDone:
+		case <-ctx.Done():
+		}
+	}
 	if s.TenantDBRouter != nil {
 		s.TenantDBRouter.Close()
 	}
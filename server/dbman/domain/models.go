@@ -14,3 +14,12 @@ type FileObject struct {
 	OriginalName string    `json:"original_name"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+type OutboxEvent struct {
+	ID        int64     `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	EventType string    `json:"event_type"`
+	Payload   []byte    `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
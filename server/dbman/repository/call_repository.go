@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/db"
+)
+
+// ErrCallNotFound is returned when transitioning an unknown call.
+var ErrCallNotFound = errors.New("call not found")
+
+type CallRepository struct {
+	router *db.TenantDBRouter
+}
+
+func NewCallRepository(router *db.TenantDBRouter) *CallRepository {
+	return &CallRepository{router: router}
+}
+
+const callColumns = `tenant_id, call_id, room_id, caller_id, media, status, created_at, started_at, ended_at, duration_sec`
+
+func scanCall(row pgx.Row) (domain.Call, error) {
+	var c domain.Call
+	err := row.Scan(&c.TenantID, &c.ID, &c.RoomID, &c.CallerID, &c.Media, &c.Status, &c.CreatedAt, &c.StartedAt, &c.EndedAt, &c.DurationSec)
+	return c, err
+}
+
+// CreateCall starts a ringing call and invites every current room member. It
+// returns ok=false with the live call when the room already has one.
+func (r *CallRepository) CreateCall(ctx context.Context, call domain.Call) (domain.Call, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, call.TenantID)
+	if err != nil {
+		return call, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return call, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	created, err := scanCall(tx.QueryRow(ctx, `
+		INSERT INTO calls(tenant_id, room_id, caller_id, media, status)
+		VALUES($1, $2, $3, $4, 'ringing')
+		ON CONFLICT (tenant_id, room_id) WHERE status IN ('ringing', 'active') DO NOTHING
+		RETURNING `+callColumns,
+		call.TenantID, call.RoomID, call.CallerID, call.Media))
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = tx.Rollback(ctx)
+		live, ok, err := r.GetLiveCall(ctx, call.TenantID, call.RoomID)
+		if err != nil {
+			return call, false, err
+		}
+		if !ok {
+			return call, false, fmt.Errorf("call conflict")
+		}
+		return live, false, nil
+	}
+	if err != nil {
+		return call, false, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO call_participants(call_id, tenant_id, user_id, state, invited_at, joined_at)
+		SELECT $1, $2, rm.user_id,
+			CASE WHEN rm.user_id = $4 THEN 'joined' ELSE 'invited' END,
+			$5,
+			CASE WHEN rm.user_id = $4 THEN $5::TIMESTAMPTZ END
+		FROM room_members rm
+		WHERE rm.tenant_id=$2 AND rm.room_id=$3
+	`, created.ID, created.TenantID, created.RoomID, created.CallerID, created.CreatedAt); err != nil {
+		return call, false, err
+	}
+	if created.Participants, err = listCallParticipants(ctx, tx, created.TenantID, created.ID); err != nil {
+		return call, false, err
+	}
+	return created, true, tx.Commit(ctx)
+}
+
+func (r *CallRepository) GetCall(ctx context.Context, tenantID, roomID, callID string) (domain.Call, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Call{}, false, err
+	}
+	call, err := scanCall(pool.QueryRow(ctx, `
+		SELECT `+callColumns+`
+		FROM calls
+		WHERE tenant_id=$1 AND room_id=$2 AND call_id=$3
+	`, tenantID, roomID, callID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Call{}, false, nil
+	}
+	if err != nil {
+		return domain.Call{}, false, err
+	}
+	call.Participants, err = listCallParticipants(ctx, pool, tenantID, call.ID)
+	return call, err == nil, err
+}
+
+func (r *CallRepository) GetLiveCall(ctx context.Context, tenantID, roomID string) (domain.Call, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Call{}, false, err
+	}
+	call, err := scanCall(pool.QueryRow(ctx, `
+		SELECT `+callColumns+`
+		FROM calls
+		WHERE tenant_id=$1 AND room_id=$2 AND status IN ('ringing', 'active')
+	`, tenantID, roomID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Call{}, false, nil
+	}
+	if err != nil {
+		return domain.Call{}, false, err
+	}
+	call.Participants, err = listCallParticipants(ctx, pool, tenantID, call.ID)
+	return call, err == nil, err
+}
+
+// TransitionCall applies action on behalf of userID under a row lock. changed
+// is false when the action is not valid in the call's current state.
+func (r *CallRepository) TransitionCall(ctx context.Context, tenantID, roomID, callID, userID string, action domain.CallAction) (domain.Call, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Call{}, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return domain.Call{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	call, err := scanCall(tx.QueryRow(ctx, `
+		SELECT `+callColumns+`
+		FROM calls
+		WHERE tenant_id=$1 AND room_id=$2 AND call_id=$3
+		FOR UPDATE
+	`, tenantID, roomID, callID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Call{}, false, ErrCallNotFound
+		}
+		return domain.Call{}, false, err
+	}
+	if call.Participants, err = listCallParticipants(ctx, tx, tenantID, callID); err != nil {
+		return domain.Call{}, false, err
+	}
+	if !applyCallAction(&call, userID, action, time.Now().UTC()) {
+		return call, false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE calls
+		SET status=$4, started_at=$5, ended_at=$6, duration_sec=$7
+		WHERE tenant_id=$1 AND room_id=$2 AND call_id=$3
+	`, tenantID, roomID, callID, call.Status, call.StartedAt, call.EndedAt, call.DurationSec); err != nil {
+		return domain.Call{}, false, err
+	}
+	for _, p := range call.Participants {
+		if _, err := tx.Exec(ctx, `
+			UPDATE call_participants
+			SET state=$4, joined_at=$5, left_at=$6
+			WHERE tenant_id=$1 AND call_id=$2 AND user_id=$3
+		`, tenantID, callID, p.UserID, p.State, p.JoinedAt, p.LeftAt); err != nil {
+			return domain.Call{}, false, err
+		}
+	}
+	return call, true, tx.Commit(ctx)
+}
+
+// applyCallAction is the call state machine:
+//
+//	ringing --answer--> active --hangup (<=1 left)--> ended
+//	ringing --decline (nobody left to answer)--> declined
+//	ringing --caller hangup / expire--> missed
+func applyCallAction(call *domain.Call, userID string, action domain.CallAction, now time.Time) bool {
+	if call.Status.Terminal() {
+		return false
+	}
+	idx := -1
+	for i := range call.Participants {
+		if call.Participants[i].UserID == userID {
+			idx = i
+			break
+		}
+	}
+	if action != domain.CallActionExpire && idx < 0 {
+		return false
+	}
+
+	switch action {
+	case domain.CallActionAnswer:
+		p := &call.Participants[idx]
+		if p.State != domain.CallParticipantInvited {
+			return false
+		}
+		p.State = domain.CallParticipantJoined
+		p.JoinedAt = &now
+		if call.Status == domain.CallStatusRinging {
+			call.Status = domain.CallStatusActive
+			call.StartedAt = &now
+		}
+	case domain.CallActionDecline:
+		p := &call.Participants[idx]
+		if p.State != domain.CallParticipantInvited {
+			return false
+		}
+		p.State = domain.CallParticipantDeclined
+		p.LeftAt = &now
+		if call.Status == domain.CallStatusRinging && countCallParticipants(call, domain.CallParticipantInvited) == 0 {
+			finishCall(call, domain.CallStatusDeclined, now)
+		}
+	case domain.CallActionHangup:
+		p := &call.Participants[idx]
+		if p.State != domain.CallParticipantJoined {
+			return false
+		}
+		p.State = domain.CallParticipantLeft
+		p.LeftAt = &now
+		switch {
+		case call.Status == domain.CallStatusRinging:
+			finishCall(call, domain.CallStatusMissed, now)
+		case countCallParticipants(call, domain.CallParticipantJoined) <= 1:
+			finishCall(call, domain.CallStatusEnded, now)
+		}
+	case domain.CallActionExpire:
+		if call.Status != domain.CallStatusRinging {
+			return false
+		}
+		finishCall(call, domain.CallStatusMissed, now)
+	default:
+		return false
+	}
+	return true
+}
+
+func finishCall(call *domain.Call, status domain.CallStatus, now time.Time) {
+	call.Status = status
+	call.EndedAt = &now
+	if call.StartedAt != nil {
+		call.DurationSec = int64(now.Sub(*call.StartedAt).Seconds())
+	}
+	for i := range call.Participants {
+		p := &call.Participants[i]
+		switch p.State {
+		case domain.CallParticipantInvited:
+			p.State = domain.CallParticipantMissed
+		case domain.CallParticipantJoined:
+			p.State = domain.CallParticipantLeft
+			p.LeftAt = &now
+		}
+	}
+}
+
+func countCallParticipants(call *domain.Call, state domain.CallParticipantState) int {
+	n := 0
+	for _, p := range call.Participants {
+		if p.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *CallRepository) ListCalls(ctx context.Context, tenantID, roomID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.Call, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+callColumns+`
+		FROM calls
+		WHERE tenant_id=$1 AND room_id=$2
+		  AND ($3::TIMESTAMPTZ IS NULL OR (created_at, call_id) < ($3, $4))
+		ORDER BY created_at DESC, call_id DESC
+		LIMIT $5
+	`, tenantID, roomID, cursorCreatedAt, cursorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]domain.Call, 0)
+	for rows.Next() {
+		call, err := scanCall(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, call)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].Participants, err = listCallParticipants(ctx, pool, tenantID, items[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// ListExpiredRingingCalls returns calls still ringing that were created
+// before the cutoff, oldest first. Participants are not loaded.
+func (r *CallRepository) ListExpiredRingingCalls(ctx context.Context, tenantID string, createdBefore time.Time, limit int) ([]domain.Call, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+callColumns+`
+		FROM calls
+		WHERE tenant_id=$1 AND status='ringing' AND created_at < $2
+		ORDER BY created_at ASC
+		LIMIT $3
+	`, tenantID, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.Call, 0)
+	for rows.Next() {
+		call, err := scanCall(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, call)
+	}
+	return items, rows.Err()
+}
+
+type callQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func listCallParticipants(ctx context.Context, q callQuerier, tenantID, callID string) ([]domain.CallParticipant, error) {
+	rows, err := q.Query(ctx, `
+		SELECT user_id, state, invited_at, joined_at, left_at
+		FROM call_participants
+		WHERE tenant_id=$1 AND call_id=$2
+		ORDER BY invited_at, user_id
+	`, tenantID, callID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.CallParticipant, 0)
+	for rows.Next() {
+		var p domain.CallParticipant
+		if err := rows.Scan(&p.UserID, &p.State, &p.InvitedAt, &p.JoinedAt, &p.LeftAt); err != nil {
+			return nil, err
+		}
+		items = append(items, p)
+	}
+	return items, rows.Err()
+}
+
+func (r *CallRepository) RecordTurnCredential(ctx context.Context, item domain.TurnCredentialAudit) (domain.TurnCredentialAudit, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return item, err
+	}
+	err = pool.QueryRow(ctx, `
+		INSERT INTO turn_credential_audit(tenant_id, user_id, username, expires_at, ip, user_agent)
+		VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, item.TenantID, item.UserID, item.Username, item.ExpiresAt, item.IP, item.UserAgent).Scan(&item.ID, &item.CreatedAt)
+	return item, err
+}
@@ -2,13 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
 	"msg_server/server/chat/domain"
+	"msg_server/server/common/events"
 	"msg_server/server/common/infra/db"
 )
 
+// ErrMessageNotFound is returned when a message to change is unknown or
+// already deleted.
+var ErrMessageNotFound = errors.New("message not found")
+
 type ChatRepository struct {
 	router *db.TenantDBRouter
 }
@@ -34,14 +43,14 @@ func (r *ChatRepository) CreateRoom(ctx context.Context, tenantID string, room d
 		return "", err
 	}
 
+	if _, err := tx.Exec(ctx, `INSERT INTO room_members(tenant_id, room_id, user_id, role) VALUES($1, $2, $3, 'owner')`, tenantID, roomID, room.CreatedBy); err != nil {
+		return "", err
+	}
 	for _, userID := range memberIDs {
 		if _, err := tx.Exec(ctx, `INSERT INTO room_members(tenant_id, room_id, user_id) VALUES($1, $2, $3) ON CONFLICT DO NOTHING`, tenantID, roomID, userID); err != nil {
 			return "", err
 		}
 	}
-	if _, err := tx.Exec(ctx, `INSERT INTO room_members(tenant_id, room_id, user_id) VALUES($1, $2, $3) ON CONFLICT DO NOTHING`, tenantID, roomID, room.CreatedBy); err != nil {
-		return "", err
-	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return "", err
@@ -49,13 +58,151 @@ func (r *ChatRepository) CreateRoom(ctx context.Context, tenantID string, room d
 	return roomID, nil
 }
 
-func (r *ChatRepository) AddMember(ctx context.Context, tenantID, roomID, userID string) error {
+// AddMember is a no-op for existing members, whose role is kept; added
+// reports whether the user joined just now. ok is false when the user is
+// banned from the room.
+func (r *ChatRepository) AddMember(ctx context.Context, tenantID, roomID, userID string, role domain.RoomRole) (added bool, ok bool, err error) {
 	pool, err := r.router.DBForTenant(ctx, tenantID)
 	if err != nil {
-		return err
+		return false, false, err
+	}
+	var banned bool
+	err = pool.QueryRow(ctx, `
+		WITH ban AS (
+			SELECT EXISTS (
+				SELECT 1 FROM room_bans WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3
+			) AS banned
+		), added AS (
+			INSERT INTO room_members(tenant_id, room_id, user_id, role)
+			SELECT $1, $2, $3, $4 FROM ban WHERE NOT ban.banned
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		SELECT banned, EXISTS (SELECT 1 FROM added) FROM ban
+	`, tenantID, roomID, userID, role).Scan(&banned, &added)
+	if err != nil {
+		return false, false, err
+	}
+	return added, !banned, nil
+}
+
+// RemoveMember reports false when the user was not a member.
+func (r *ChatRepository) RemoveMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	tag, err := pool.Exec(ctx, `DELETE FROM room_members WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3`, tenantID, roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+const chatRoomColumns = `tenant_id, chat_room_id, name, room_type, created_by, created_at, archived_at`
+
+func scanChatRoom(row pgx.Row) (domain.ChatRoom, error) {
+	var room domain.ChatRoom
+	err := row.Scan(&room.TenantID, &room.ID, &room.Name, &room.RoomType, &room.CreatedBy, &room.CreatedAt, &room.ArchivedAt)
+	return room, err
+}
+
+func (r *ChatRepository) GetRoom(ctx context.Context, tenantID, roomID string) (domain.ChatRoom, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	room, err := scanChatRoom(pool.QueryRow(ctx, `SELECT `+chatRoomColumns+` FROM chat_rooms WHERE tenant_id=$1 AND chat_room_id=$2`, tenantID, roomID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ChatRoom{}, false, nil
+	}
+	if err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	return room, true, nil
+}
+
+func (r *ChatRepository) RenameRoom(ctx context.Context, tenantID, roomID, name string) (domain.ChatRoom, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	room, err := scanChatRoom(pool.QueryRow(ctx, `
+		UPDATE chat_rooms SET name=$3
+		WHERE tenant_id=$1 AND chat_room_id=$2
+		RETURNING `+chatRoomColumns, tenantID, roomID, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ChatRoom{}, false, nil
+	}
+	if err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	return room, true, nil
+}
+
+// SetRoomArchived keeps the original archived_at when archiving twice.
+func (r *ChatRepository) SetRoomArchived(ctx context.Context, tenantID, roomID string, archived bool) (domain.ChatRoom, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	room, err := scanChatRoom(pool.QueryRow(ctx, `
+		UPDATE chat_rooms
+		SET archived_at = CASE WHEN $3 THEN COALESCE(archived_at, NOW()) ELSE NULL END
+		WHERE tenant_id=$1 AND chat_room_id=$2
+		RETURNING `+chatRoomColumns, tenantID, roomID, archived))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ChatRoom{}, false, nil
+	}
+	if err != nil {
+		return domain.ChatRoom{}, false, err
+	}
+	return room, true, nil
+}
+
+// DeleteRoom removes the room with its messages and returns who was a member,
+// so the caller can notify them. ok is false when the room does not exist.
+func (r *ChatRepository) DeleteRoom(ctx context.Context, tenantID, roomID string) ([]string, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, false, err
 	}
-	_, err = pool.Exec(ctx, `INSERT INTO room_members(tenant_id, room_id, user_id) VALUES($1, $2, $3) ON CONFLICT DO NOTHING`, tenantID, roomID, userID)
-	return err
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Members are deleted first to collect their ids; the sync trigger
+	// records member.left for each of them.
+	rows, err := tx.Query(ctx, `DELETE FROM room_members WHERE tenant_id=$1 AND room_id=$2 RETURNING user_id`, tenantID, roomID)
+	if err != nil {
+		return nil, false, err
+	}
+	memberIDs := make([]string, 0)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		memberIDs = append(memberIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM chat_rooms WHERE tenant_id=$1 AND chat_room_id=$2`, tenantID, roomID)
+	if err != nil {
+		return nil, false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, false, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return memberIDs, true, nil
 }
 
 func (r *ChatRepository) IsRoomMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
@@ -77,37 +224,657 @@ func (r *ChatRepository) IsRoomMember(ctx context.Context, tenantID, roomID, use
 	return exists, nil
 }
 
-func (r *ChatRepository) CreateMessage(ctx context.Context, message domain.Message) (domain.Message, error) {
+const roomMemberColumns = `tenant_id, room_id, user_id, role, joined_at`
+
+func scanRoomMember(row pgx.Row) (domain.RoomMember, error) {
+	var m domain.RoomMember
+	err := row.Scan(&m.TenantID, &m.RoomID, &m.UserID, &m.Role, &m.JoinedAt)
+	return m, err
+}
+
+func (r *ChatRepository) GetRoomMember(ctx context.Context, tenantID, roomID, userID string) (domain.RoomMember, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.RoomMember{}, false, err
+	}
+	item, err := scanRoomMember(pool.QueryRow(ctx, `
+		SELECT `+roomMemberColumns+`
+		FROM room_members
+		WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3
+	`, tenantID, roomID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.RoomMember{}, false, nil
+		}
+		return domain.RoomMember{}, false, err
+	}
+	return item, true, nil
+}
+
+// SetMemberRole changes a member's role. The owner's role only changes through
+// TransferOwnership, so ok is false for the owner as well as for non-members.
+func (r *ChatRepository) SetMemberRole(ctx context.Context, tenantID, roomID, userID string, role domain.RoomRole) (domain.RoomMember, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.RoomMember{}, false, err
+	}
+	item, err := scanRoomMember(pool.QueryRow(ctx, `
+		UPDATE room_members SET role=$4
+		WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3 AND role <> 'owner'
+		RETURNING `+roomMemberColumns, tenantID, roomID, userID, role))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.RoomMember{}, false, nil
+	}
+	if err != nil {
+		return domain.RoomMember{}, false, err
+	}
+	return item, true, nil
+}
+
+// TransferOwnership makes userID the owner and the previous owner, if any, an
+// admin. It returns the changed members; ok is false when userID is not a
+// member.
+func (r *ChatRepository) TransferOwnership(ctx context.Context, tenantID, roomID, userID string) ([]domain.RoomMember, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var targetRole domain.RoomRole
+	err = tx.QueryRow(ctx, `
+		SELECT role FROM room_members
+		WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3
+		FOR UPDATE
+	`, tenantID, roomID, userID).Scan(&targetRole)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if targetRole == domain.RoomRoleOwner {
+		return []domain.RoomMember{}, true, nil
+	}
+	changed := make([]domain.RoomMember, 0, 2)
+	// The previous owner is demoted first; the unique index allows one owner.
+	previous, err := scanRoomMember(tx.QueryRow(ctx, `
+		UPDATE room_members SET role='admin'
+		WHERE tenant_id=$1 AND room_id=$2 AND role='owner'
+		RETURNING `+roomMemberColumns, tenantID, roomID))
+	if err == nil {
+		changed = append(changed, previous)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+	owner, err := scanRoomMember(tx.QueryRow(ctx, `
+		UPDATE room_members SET role='owner'
+		WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3
+		RETURNING `+roomMemberColumns, tenantID, roomID, userID))
+	if err != nil {
+		return nil, false, err
+	}
+	changed = append(changed, owner)
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return changed, true, nil
+}
+
+func (r *ChatRepository) ListMemberRoomIDs(ctx context.Context, tenantID, userID string) ([]string, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT room_id
+		FROM room_members
+		WHERE tenant_id=$1 AND user_id=$2
+		ORDER BY room_id
+	`, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]string, 0)
+	for rows.Next() {
+		var roomID string
+		if err := rows.Scan(&roomID); err != nil {
+			return nil, err
+		}
+		items = append(items, roomID)
+	}
+	return items, rows.Err()
+}
+
+func (r *ChatRepository) ListRoomMemberIDs(ctx context.Context, tenantID, roomID string) ([]string, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT user_id
+		FROM room_members
+		WHERE tenant_id=$1 AND room_id=$2
+		ORDER BY user_id
+	`, tenantID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]string, 0)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		items = append(items, userID)
+	}
+	return items, rows.Err()
+}
+
+// CreateMessage inserts the message, marks it read for the sender, queues
+// message.created in the outbox and, when flagRules is set, queues the message
+// for moderation review, all in one transaction.
+// CreateMessage reports false when the room is archived.
+func (r *ChatRepository) CreateMessage(ctx context.Context, message domain.Message, flagRules []string) (domain.Message, bool, error) {
+	return r.createMessage(ctx, message, flagRules, false)
+}
+
+// CreateSystemMessage stores a message the server posts on a user's behalf.
+// It goes into archived rooms as well, and its message.created event is
+// marked as a system message.
+func (r *ChatRepository) CreateSystemMessage(ctx context.Context, message domain.Message) (domain.Message, error) {
+	m, _, err := r.createMessage(ctx, message, nil, true)
+	return m, err
+}
+
+func (r *ChatRepository) createMessage(ctx context.Context, message domain.Message, flagRules []string, system bool) (domain.Message, bool, error) {
 	pool, err := r.router.DBForTenant(ctx, message.TenantID)
 	if err != nil {
-		return message, err
+		return message, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return message, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The chat_rooms row lock serializes sequence allocation per room, and
+	// archiving takes the same lock.
+	var archived bool
+	err = tx.QueryRow(ctx, `
+		SELECT archived_at IS NOT NULL
+		FROM chat_rooms
+		WHERE tenant_id=$1 AND chat_room_id=$2
+		FOR UPDATE
+	`, message.TenantID, message.RoomID).Scan(&archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return message, false, fmt.Errorf("room not found")
+	}
+	if err != nil {
+		return message, false, err
+	}
+	if archived && !system {
+		return message, false, nil
+	}
+	err = tx.QueryRow(ctx, `
+		WITH seq AS (
+			UPDATE chat_rooms
+			SET last_message_seq = last_message_seq + 1
+			WHERE tenant_id=$1 AND chat_room_id=$2
+			RETURNING last_message_seq
+		)
+		INSERT INTO messages(tenant_id, room_id, sender_id, body, meta_json, parent_message_id, room_seq)
+		SELECT $1, $2, $3, $4, $5, $6, seq.last_message_seq
+		FROM seq
+		RETURNING message_id, room_seq, created_at
+	`, message.TenantID, message.RoomID, message.SenderID, message.Body, message.MetaJSON, message.ParentMessageID).Scan(&message.ID, &message.Seq, &message.CreatedAt)
+	if err != nil {
+		return message, false, err
+	}
+	if err := markReadUpTo(ctx, tx, message.TenantID, message.RoomID, message.SenderID, message.ID); err != nil && !errors.Is(err, errRoomMemberNotFound) {
+		return message, false, err
+	}
+	if err := insertOutboxEvent(ctx, tx, message.TenantID, events.MessageCreated{
+		MessageID:       message.ID,
+		RoomID:          message.RoomID,
+		Seq:             message.Seq,
+		SenderID:        message.SenderID,
+		Body:            message.Body,
+		ParentMessageID: message.ParentMessageID,
+		CreatedAt:       message.CreatedAt,
+		System:          system,
+	}); err != nil {
+		return message, false, err
+	}
+	if len(flagRules) > 0 {
+		if err := insertMessageFlag(ctx, tx, message, flagRules); err != nil {
+			return message, false, err
+		}
+	}
+	return message, true, tx.Commit(ctx)
+}
+
+const messageColumns = `tenant_id, message_id AS id, room_id, room_seq, sender_id, body, meta_json, created_at, edited_at, deleted_at, parent_message_id`
+
+func scanMessage(row pgx.Row) (domain.Message, error) {
+	var m domain.Message
+	err := row.Scan(&m.TenantID, &m.ID, &m.RoomID, &m.Seq, &m.SenderID, &m.Body, &m.MetaJSON, &m.CreatedAt, &m.EditedAt, &m.DeletedAt, &m.ParentMessageID)
+	return m, err
+}
+
+const threadSummaryJoin = `
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*)::BIGINT AS reply_count
+			FROM messages r
+			WHERE r.tenant_id = m.tenant_id AND r.parent_message_id = m.message_id AND r.deleted_at IS NULL
+		) tc ON true
+		LEFT JOIN LATERAL (
+			SELECT r.message_id, r.sender_id, r.created_at
+			FROM messages r
+			WHERE r.tenant_id = m.tenant_id AND r.parent_message_id = m.message_id AND r.deleted_at IS NULL
+			ORDER BY r.room_seq DESC
+			LIMIT 1
+		) lr ON true`
+
+func (r *ChatRepository) GetThreadSummary(ctx context.Context, tenantID, roomID, parentMessageID string) (domain.ThreadSummary, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ThreadSummary{}, err
+	}
+	var item domain.ThreadSummary
+	err = pool.QueryRow(ctx, `
+		SELECT tc.reply_count, lr.message_id, lr.sender_id, lr.created_at
+		FROM messages m`+threadSummaryJoin+`
+		WHERE m.tenant_id=$1 AND m.room_id=$2 AND m.message_id=$3
+	`, tenantID, roomID, parentMessageID).Scan(&item.ReplyCount, &item.LastReplyID, &item.LastReplySenderID, &item.LastReplyAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ThreadSummary{}, nil
+		}
+		return domain.ThreadSummary{}, err
+	}
+	return item, nil
+}
+
+func (r *ChatRepository) ListThreadReplies(ctx context.Context, tenantID, roomID, parentMessageID, viewerID string, limit int, cursorSeq *int64) ([]domain.Message, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE tenant_id=$1 AND room_id=$2 AND parent_message_id=$3`
+	args := []any{tenantID, roomID, parentMessageID}
+	if cursorSeq != nil {
+		query += ` AND room_seq > $4
+		ORDER BY room_seq ASC
+		LIMIT $5`
+		args = append(args, *cursorSeq, limit)
+	} else {
+		query += `
+		ORDER BY room_seq ASC
+		LIMIT $4`
+		args = append(args, limit)
+	}
+
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.Message, 0)
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return items, attachReactions(ctx, pool, tenantID, viewerID, items)
+}
+
+func (r *ChatRepository) GetMessage(ctx context.Context, tenantID, roomID, messageID string) (domain.Message, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Message{}, false, err
+	}
+	m, err := scanMessage(pool.QueryRow(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3
+	`, tenantID, roomID, messageID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Message{}, false, nil
+		}
+		return domain.Message{}, false, err
+	}
+	return m, true, nil
+}
+
+// UpdateMessage keeps the previous body as a revision and, like CreateMessage,
+// queues the edited message for review when flagRules is set.
+func (r *ChatRepository) UpdateMessage(ctx context.Context, tenantID, roomID, messageID, editorID, body string, flagRules []string) (domain.Message, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	cmd, err := tx.Exec(ctx, `
+		INSERT INTO message_revisions(tenant_id, message_id, room_id, editor_id, body, meta_json)
+		SELECT tenant_id, message_id, room_id, $4, body, meta_json
+		FROM messages
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3 AND deleted_at IS NULL
+		FOR UPDATE
+	`, tenantID, roomID, messageID, editorID)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.Message{}, ErrMessageNotFound
+	}
+	m, err := scanMessage(tx.QueryRow(ctx, `
+		UPDATE messages
+		SET body=$4, edited_at=NOW()
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3
+		RETURNING `+messageColumns+`
+	`, tenantID, roomID, messageID, body))
+	if err != nil {
+		return domain.Message{}, err
+	}
+	if err := insertOutboxEvent(ctx, tx, tenantID, events.MessageUpdated{
+		MessageID: m.ID,
+		RoomID:    m.RoomID,
+		SenderID:  m.SenderID,
+		EditorID:  editorID,
+		Body:      m.Body,
+		EditedAt:  m.EditedAt,
+	}); err != nil {
+		return domain.Message{}, err
+	}
+	if len(flagRules) > 0 {
+		if err := insertMessageFlag(ctx, tx, m, flagRules); err != nil {
+			return domain.Message{}, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Message{}, err
+	}
+	return m, nil
+}
+
+func (r *ChatRepository) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID string) (domain.Message, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	m, err := softDeleteMessage(ctx, tx, tenantID, roomID, messageID, actorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Message{}, ErrMessageNotFound
+		}
+		return domain.Message{}, err
+	}
+	return m, tx.Commit(ctx)
+}
+
+// softDeleteMessage clears the message, drops its edit history and queues
+// message.deleted. It returns pgx.ErrNoRows when the message is unknown or
+// already deleted.
+func softDeleteMessage(ctx context.Context, tx pgx.Tx, tenantID, roomID, messageID, actorID string) (domain.Message, error) {
+	m, err := scanMessage(tx.QueryRow(ctx, `
+		UPDATE messages
+		SET body='', meta_json='{}'::jsonb, deleted_at=NOW(), deleted_by=$4
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3 AND deleted_at IS NULL
+		RETURNING `+messageColumns+`
+	`, tenantID, roomID, messageID, actorID))
+	if err != nil {
+		return domain.Message{}, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM message_revisions WHERE tenant_id=$1 AND message_id=$2`, tenantID, m.ID); err != nil {
+		return domain.Message{}, err
+	}
+	if err := insertOutboxEvent(ctx, tx, tenantID, events.MessageDeleted{
+		MessageID: m.ID,
+		RoomID:    m.RoomID,
+		SenderID:  m.SenderID,
+		DeletedBy: actorID,
+		DeletedAt: m.DeletedAt,
+	}); err != nil {
+		return domain.Message{}, err
+	}
+	return m, nil
+}
+
+func (r *ChatRepository) ListMessageRevisions(ctx context.Context, tenantID, roomID, messageID string) ([]domain.MessageRevision, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT tenant_id, revision_id, message_id, room_id, editor_id, body, meta_json, created_at
+		FROM message_revisions
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3
+		ORDER BY created_at DESC
+	`, tenantID, roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.MessageRevision, 0)
+	for rows.Next() {
+		var item domain.MessageRevision
+		if err := rows.Scan(&item.TenantID, &item.RevisionID, &item.MessageID, &item.RoomID, &item.EditorID, &item.Body, &item.MetaJSON, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// PinMessage pins a live message; pinning it again returns the existing pin.
+func (r *ChatRepository) PinMessage(ctx context.Context, tenantID, roomID, messageID, userID string) (domain.MessagePin, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.MessagePin{}, err
 	}
+	var pin domain.MessagePin
 	err = pool.QueryRow(ctx, `
-		INSERT INTO messages(tenant_id, room_id, sender_id, body, meta_json)
+		WITH target AS (
+			SELECT tenant_id, room_id, message_id
+			FROM messages
+			WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3 AND deleted_at IS NULL
+		), pinned AS (
+			INSERT INTO message_pins(tenant_id, room_id, message_id, pinned_by)
+			SELECT tenant_id, room_id, message_id, $4 FROM target
+			ON CONFLICT (message_id) DO NOTHING
+			RETURNING tenant_id, room_id, message_id, pinned_by, pinned_at
+		)
+		SELECT tenant_id, room_id, message_id, pinned_by, pinned_at FROM pinned
+		UNION ALL
+		SELECT p.tenant_id, p.room_id, p.message_id, p.pinned_by, p.pinned_at
+		FROM message_pins p
+		JOIN target t ON t.message_id = p.message_id
+	`, tenantID, roomID, messageID, userID).Scan(&pin.TenantID, &pin.RoomID, &pin.MessageID, &pin.PinnedBy, &pin.PinnedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.MessagePin{}, ErrMessageNotFound
+	}
+	return pin, err
+}
+
+// UnpinMessage reports false when the message was not pinned.
+func (r *ChatRepository) UnpinMessage(ctx context.Context, tenantID, roomID, messageID string) (bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	cmd, err := pool.Exec(ctx, `DELETE FROM message_pins WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3`, tenantID, roomID, messageID)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() > 0, nil
+}
+
+// ListPinnedMessages returns the room's pinned messages that are not deleted,
+// most recently pinned first.
+func (r *ChatRepository) ListPinnedMessages(ctx context.Context, tenantID, roomID string, limit int) ([]domain.MessagePin, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+messageColumns+`, pinned_by, pinned_at
+		FROM (
+			SELECT m.*, p.pinned_by, p.pinned_at
+			FROM message_pins p
+			JOIN messages m ON m.message_id = p.message_id
+			WHERE p.tenant_id=$1 AND p.room_id=$2 AND m.deleted_at IS NULL
+		) pinned
+		ORDER BY pinned_at DESC, message_id DESC
+		LIMIT $3
+	`, tenantID, roomID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.MessagePin, 0)
+	for rows.Next() {
+		var m domain.Message
+		var pin domain.MessagePin
+		if err := rows.Scan(&m.TenantID, &m.ID, &m.RoomID, &m.Seq, &m.SenderID, &m.Body, &m.MetaJSON, &m.CreatedAt, &m.EditedAt, &m.DeletedAt, &m.ParentMessageID, &pin.PinnedBy, &pin.PinnedAt); err != nil {
+			return nil, err
+		}
+		pin.TenantID, pin.RoomID, pin.MessageID, pin.Message = m.TenantID, m.RoomID, m.ID, &m
+		items = append(items, pin)
+	}
+	return items, rows.Err()
+}
+
+func (r *ChatRepository) AddReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	cmd, err := pool.Exec(ctx, `
+		INSERT INTO message_reactions(tenant_id, message_id, room_id, user_id, emoji)
 		VALUES($1, $2, $3, $4, $5)
-		RETURNING message_id, created_at
-	`, message.TenantID, message.RoomID, message.SenderID, message.Body, message.MetaJSON).Scan(&message.ID, &message.CreatedAt)
-	return message, err
+		ON CONFLICT DO NOTHING
+	`, tenantID, messageID, roomID, userID, emoji)
+	if err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	item, err := reactionEvent(ctx, pool, tenantID, roomID, messageID, userID, emoji)
+	return item, cmd.RowsAffected() > 0, err
+}
+
+func (r *ChatRepository) RemoveReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	cmd, err := pool.Exec(ctx, `
+		DELETE FROM message_reactions
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3 AND user_id=$4 AND emoji=$5
+	`, tenantID, roomID, messageID, userID, emoji)
+	if err != nil {
+		return domain.ReactionEvent{}, false, err
+	}
+	item, err := reactionEvent(ctx, pool, tenantID, roomID, messageID, userID, emoji)
+	return item, cmd.RowsAffected() > 0, err
+}
+
+func reactionEvent(ctx context.Context, pool *pgxpool.Pool, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, error) {
+	item := domain.ReactionEvent{MessageID: messageID, RoomID: roomID, UserID: userID, Emoji: emoji}
+	err := pool.QueryRow(ctx, `
+		SELECT COUNT(*)::BIGINT
+		FROM message_reactions
+		WHERE tenant_id=$1 AND message_id=$2 AND emoji=$3
+	`, tenantID, messageID, emoji).Scan(&item.Count)
+	return item, err
+}
+
+// attachReactions fills per-emoji reaction counts for a page of messages in one query.
+func attachReactions(ctx context.Context, pool *pgxpool.Pool, tenantID, viewerID string, items []domain.Message) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(items))
+	index := make(map[string]int, len(items))
+	for i, m := range items {
+		ids = append(ids, m.ID)
+		index[m.ID] = i
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT message_id, emoji, COUNT(*)::BIGINT, BOOL_OR(user_id = $3)
+		FROM message_reactions
+		WHERE tenant_id=$1 AND message_id = ANY($2)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at), emoji
+	`, tenantID, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID string
+		var item domain.ReactionSummary
+		if err := rows.Scan(&messageID, &item.Emoji, &item.Count, &item.ReactedByMe); err != nil {
+			return err
+		}
+		if i, ok := index[messageID]; ok {
+			items[i].Reactions = append(items[i].Reactions, item)
+		}
+	}
+	return rows.Err()
 }
 
-func (r *ChatRepository) ListMessages(ctx context.Context, tenantID, roomID string, limit int, cursorID *string) ([]domain.Message, error) {
+func (r *ChatRepository) ListMessages(ctx context.Context, tenantID, roomID, viewerID string, limit int, cursorSeq *int64) ([]domain.Message, error) {
 	pool, err := r.router.DBForTenant(ctx, tenantID)
 	if err != nil {
 		return nil, err
 	}
 	base := `
-		SELECT message_id AS id, room_id, sender_id, body, meta_json, created_at
-		FROM messages
-		WHERE tenant_id=$1 AND room_id=$2`
+		SELECT m.tenant_id, m.message_id AS id, m.room_id, m.room_seq, m.sender_id, m.body, m.meta_json, m.created_at, m.edited_at, m.deleted_at, m.parent_message_id,
+			tc.reply_count, lr.message_id, lr.sender_id, lr.created_at
+		FROM messages m` + threadSummaryJoin + `
+		WHERE m.tenant_id=$1 AND m.room_id=$2 AND m.parent_message_id IS NULL`
 	args := []any{tenantID, roomID}
 
-	if cursorID != nil {
-		base += ` AND message_id < $3`
-		args = append(args, *cursorID)
-		base += ` ORDER BY message_id DESC LIMIT $4`
+	if cursorSeq != nil {
+		base += ` AND m.room_seq < $3`
+		args = append(args, *cursorSeq)
+		base += ` ORDER BY m.room_seq DESC LIMIT $4`
 		args = append(args, limit)
 	} else {
-		base += ` ORDER BY message_id DESC LIMIT $3`
+		base += ` ORDER BY m.room_seq DESC LIMIT $3`
 		args = append(args, limit)
 	}
 
@@ -120,26 +887,39 @@ func (r *ChatRepository) ListMessages(ctx context.Context, tenantID, roomID stri
 	items := make([]domain.Message, 0)
 	for rows.Next() {
 		var m domain.Message
-		if err := rows.Scan(&m.ID, &m.RoomID, &m.SenderID, &m.Body, &m.MetaJSON, &m.CreatedAt); err != nil {
+		var thread domain.ThreadSummary
+		if err := rows.Scan(
+			&m.TenantID, &m.ID, &m.RoomID, &m.SenderID, &m.Body, &m.MetaJSON, &m.CreatedAt, &m.EditedAt, &m.DeletedAt, &m.ParentMessageID,
+			&thread.ReplyCount, &thread.LastReplyID, &thread.LastReplySenderID, &thread.LastReplyAt,
+		); err != nil {
 			return nil, err
 		}
+		if thread.ReplyCount > 0 {
+			m.Thread = &thread
+		}
 		items = append(items, m)
 	}
-	return items, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return items, attachReactions(ctx, pool, tenantID, viewerID, items)
 }
 
-func (r *ChatRepository) SearchMessages(ctx context.Context, tenantID string, q string, roomID *string, limit int, cursorID *string) ([]domain.Message, error) {
+func (r *ChatRepository) SearchMessages(ctx context.Context, tenantID, userID string, q string, roomID *string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.Message, error) {
 	pool, err := r.router.DBForTenant(ctx, tenantID)
 	if err != nil {
 		return nil, err
 	}
 	base := `
-		SELECT message_id AS id, room_id, sender_id, body, meta_json, created_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE tenant_id=$1
-		  AND (to_tsvector('simple', coalesce(body,'')) @@ plainto_tsquery('simple', $2) OR body ILIKE '%' || $2 || '%')`
-	args := []any{tenantID, q}
-	idx := 3
+		  AND deleted_at IS NULL
+		  AND (to_tsvector('simple', coalesce(body,'')) @@ plainto_tsquery('simple', $2) OR body ILIKE '%' || $2 || '%')
+		  AND room_id IN (SELECT rm.room_id FROM room_members rm WHERE rm.tenant_id=$1 AND rm.user_id=$3)`
+	args := []any{tenantID, q, userID}
+	idx := 4
 
 	if roomID != nil {
 		base += fmt.Sprintf(` AND room_id=$%d`, idx)
@@ -147,13 +927,14 @@ func (r *ChatRepository) SearchMessages(ctx context.Context, tenantID string, q
 		idx++
 	}
 
-	if cursorID != nil {
-		base += fmt.Sprintf(` AND message_id < $%d`, idx)
-		args = append(args, *cursorID)
-		idx++
+	// Results span rooms, so room_seq cannot order them; page by send time instead.
+	if cursorCreatedAt != nil && cursorID != nil {
+		base += fmt.Sprintf(` AND (created_at, message_id) < ($%d, $%d)`, idx, idx+1)
+		args = append(args, *cursorCreatedAt, *cursorID)
+		idx += 2
 	}
 
-	base += fmt.Sprintf(` ORDER BY message_id DESC LIMIT $%d`, idx)
+	base += fmt.Sprintf(` ORDER BY created_at DESC, message_id DESC LIMIT $%d`, idx)
 	args = append(args, limit)
 
 	rows, err := pool.Query(ctx, base, args...)
@@ -164,8 +945,8 @@ func (r *ChatRepository) SearchMessages(ctx context.Context, tenantID string, q
 
 	items := make([]domain.Message, 0)
 	for rows.Next() {
-		var m domain.Message
-		if err := rows.Scan(&m.ID, &m.RoomID, &m.SenderID, &m.Body, &m.MetaJSON, &m.CreatedAt); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, m)
@@ -178,24 +959,66 @@ func (r *ChatRepository) MarkReadUpTo(ctx context.Context, tenantID, roomID, use
 	if err != nil {
 		return err
 	}
-	cmd, err := pool.Exec(ctx, `
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := markReadUpTo(ctx, tx, tenantID, roomID, userID, messageID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+var errRoomMemberNotFound = errors.New("room member not found")
+
+func markReadUpTo(ctx context.Context, tx pgx.Tx, tenantID, roomID, userID, messageID string) error {
+	var targetSeq int64
+	err := tx.QueryRow(ctx, `
+		SELECT room_seq
+		FROM messages
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3
+	`, tenantID, roomID, messageID).Scan(&targetSeq)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMessageNotFound
+		}
+		return err
+	}
+
+	var lastReadSeq int64
+	err = tx.QueryRow(ctx, `
+		SELECT last_read_seq
+		FROM room_members
+		WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3
+		FOR UPDATE
+	`, tenantID, roomID, userID).Scan(&lastReadSeq)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errRoomMemberNotFound
+		}
+		return err
+	}
+	if targetSeq <= lastReadSeq {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `
 		INSERT INTO message_reads(tenant_id, room_id, message_id, user_id, read_at)
 		SELECT m.tenant_id, m.room_id, m.message_id, $3, NOW()
 		FROM messages m
-		WHERE m.tenant_id=$1 AND m.room_id=$2
-		  AND (m.created_at, m.message_id) <= (
-			SELECT m2.created_at, m2.message_id
-			FROM messages m2
-			WHERE m2.tenant_id=$1 AND m2.message_id=$4 AND m2.room_id=$2
-		  )
-		ON CONFLICT (message_id, user_id)
-		DO UPDATE SET read_at=EXCLUDED.read_at
-	`, tenantID, roomID, userID, messageID)
-	if err != nil {
+		WHERE m.tenant_id=$1 AND m.room_id=$2 AND m.room_seq > $4 AND m.room_seq <= $5
+		ON CONFLICT (message_id, user_id) DO NOTHING
+	`, tenantID, roomID, userID, lastReadSeq, targetSeq); err != nil {
 		return err
 	}
-	if cmd.RowsAffected() == 0 {
-		return fmt.Errorf("no messages marked as read")
+	if _, err := tx.Exec(ctx, `
+		UPDATE room_members
+		SET last_read_seq=$4
+		WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3
+	`, tenantID, roomID, userID, targetSeq); err != nil {
+		return err
 	}
 	return nil
 }
@@ -236,11 +1059,10 @@ func (r *ChatRepository) GetLastReadMessageID(ctx context.Context, tenantID, roo
 	err = pool.QueryRow(ctx, `
 		SELECT COALESCE(
 			(
-				SELECT mr.message_id
-				FROM message_reads mr
-				WHERE mr.tenant_id=$1 AND mr.room_id=$2 AND mr.user_id=$3
-				ORDER BY mr.read_at DESC, mr.message_id DESC
-				LIMIT 1
+				SELECT m.message_id
+				FROM room_members rm
+				JOIN messages m ON m.tenant_id = rm.tenant_id AND m.room_id = rm.room_id AND m.room_seq = rm.last_read_seq
+				WHERE rm.tenant_id=$1 AND rm.room_id=$2 AND rm.user_id=$3
 			),
 			''
 		)
@@ -260,9 +1082,9 @@ func (r *ChatRepository) GetUnreadCount(ctx context.Context, tenantID, roomID, u
 		WHERE m.tenant_id=$1
 		  AND m.room_id=$2
 		  AND m.sender_id <> $3
-		  AND m.created_at > COALESCE(
-			(SELECT MAX(mr.read_at) FROM message_reads mr WHERE mr.tenant_id=$1 AND mr.room_id=$2 AND mr.user_id=$3),
-			TIMESTAMPTZ 'epoch'
+		  AND m.room_seq > COALESCE(
+			(SELECT rm.last_read_seq FROM room_members rm WHERE rm.tenant_id=$1 AND rm.room_id=$2 AND rm.user_id=$3),
+			0
 		  )
 	`, tenantID, roomID, userID).Scan(&count)
 	return count, err
@@ -282,11 +1104,7 @@ func (r *ChatRepository) GetUnreadCounts(ctx context.Context, tenantID, userID s
 				WHERE m.tenant_id = $1
 				  AND m.room_id = rm.room_id
 				  AND m.sender_id <> $2
-				  AND m.created_at > COALESCE((
-					SELECT MAX(mr.read_at)
-					FROM message_reads mr
-					WHERE mr.tenant_id = $1 AND mr.room_id = rm.room_id AND mr.user_id = $2
-				  ), TIMESTAMPTZ 'epoch')
+				  AND m.room_seq > rm.last_read_seq
 			), 0) AS unread_count
 		FROM room_members rm
 		WHERE rm.tenant_id = $1 AND rm.user_id = $2
@@ -320,6 +1138,8 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 			cr.room_type,
 			cr.created_by,
 			cr.created_at,
+			cr.archived_at,
+			rm.role,
 			pu.user_id,
 			pu.name,
 			pu.status,
@@ -327,6 +1147,7 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 			lm.id,
 			lm.body,
 			CASE
+				WHEN lm.deleted_at IS NOT NULL THEN 'deleted'
 				WHEN COALESCE(lm.meta_json->>'file_id', '') <> ''
 				  OR (jsonb_typeof(lm.meta_json->'file_ids') = 'array' AND jsonb_array_length(lm.meta_json->'file_ids') > 0)
 				THEN 'file'
@@ -334,6 +1155,7 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 				ELSE 'text'
 			END AS latest_message_kind,
 			CASE
+				WHEN lm.deleted_at IS NOT NULL THEN '[삭제된 메시지]'
 				WHEN COALESCE(lm.meta_json->>'file_id', '') <> ''
 				  OR (jsonb_typeof(lm.meta_json->'file_ids') = 'array' AND jsonb_array_length(lm.meta_json->'file_ids') > 0)
 				THEN '[파일]'
@@ -366,11 +1188,7 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 				WHERE m2.tenant_id = $1
 				  AND m2.room_id = cr.chat_room_id
 				  AND m2.sender_id <> $2
-				  AND m2.created_at > COALESCE((
-					SELECT MAX(mr.read_at)
-					FROM message_reads mr
-					WHERE mr.tenant_id = $1 AND mr.room_id = cr.chat_room_id AND mr.user_id = $2
-				  ), TIMESTAMPTZ 'epoch')
+				  AND m2.room_seq > rm.last_read_seq
 			), 0) AS unread_count
 		FROM room_members rm
 		JOIN chat_rooms cr ON cr.tenant_id = $1 AND cr.chat_room_id = rm.room_id
@@ -389,10 +1207,10 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 			LIMIT 1
 		) pu ON cr.room_type = 'direct'
 		LEFT JOIN LATERAL (
-			SELECT m.message_id AS id, m.body, m.meta_json, m.created_at, m.sender_id
+			SELECT m.message_id AS id, m.body, m.meta_json, m.created_at, m.sender_id, m.deleted_at
 			FROM messages m
 			WHERE m.tenant_id = $1 AND m.room_id = cr.chat_room_id
-			ORDER BY m.message_id DESC
+			ORDER BY m.room_seq DESC
 			LIMIT 1
 		) lm ON true
 		WHERE rm.tenant_id = $1 AND rm.user_id = $2`
@@ -431,6 +1249,8 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 			&item.RoomType,
 			&item.CreatedBy,
 			&item.CreatedAt,
+			&item.ArchivedAt,
+			&item.Role,
 			&item.PeerUserID,
 			&item.PeerName,
 			&item.PeerStatus,
@@ -451,3 +1271,133 @@ func (r *ChatRepository) ListMyRooms(ctx context.Context, tenantID, userID strin
 	}
 	return items, rows.Err()
 }
+
+// ListSyncChanges pages the change feed by (tx_id, change_id) and only returns
+// rows written by transactions older than the current snapshot's xmin. Every
+// transaction that can still commit has an xid at or above that horizon, so a
+// cursor never moves past a change that is not yet visible.
+func (r *ChatRepository) ListSyncChanges(ctx context.Context, tenantID, userID string, since *domain.SyncCursor, limit int) (domain.SyncBatch, error) {
+	batch := domain.SyncBatch{
+		Messages:    make([]domain.Message, 0),
+		ReadStates:  make([]domain.RoomReadState, 0),
+		Memberships: make([]domain.MembershipChange, 0),
+	}
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return batch, err
+	}
+	if since == nil {
+		err = pool.QueryRow(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`).Scan(&batch.Last.TxID)
+		return batch, err
+	}
+	batch.Last = *since
+
+	rows, err := pool.Query(ctx, `
+		SELECT c.tx_id::text::bigint, c.change_id, c.room_id, COALESCE(c.user_id, ''), c.kind, c.entity_id, c.created_at
+		FROM sync_changes c
+		WHERE c.tenant_id=$1
+		  AND (c.tx_id, c.change_id) > ($3::bigint::text::xid8, $4)
+		  AND c.tx_id < pg_snapshot_xmin(pg_current_snapshot())
+		  AND (
+			c.user_id = $2
+			OR (c.kind <> 'read.updated' AND c.room_id IN (SELECT rm.room_id FROM room_members rm WHERE rm.tenant_id=$1 AND rm.user_id=$2))
+		  )
+		ORDER BY c.tx_id ASC, c.change_id ASC
+		LIMIT $5
+	`, tenantID, userID, since.TxID, since.ChangeID, limit+1)
+	if err != nil {
+		return batch, err
+	}
+	defer rows.Close()
+
+	messageIDs := make([]string, 0)
+	readRoomIDs := make([]string, 0)
+	seenMessages := map[string]struct{}{}
+	seenReadRooms := map[string]struct{}{}
+	count := 0
+	for rows.Next() {
+		var position domain.SyncCursor
+		var roomID, changeUserID, kind, entityID string
+		var changedAt time.Time
+		if err := rows.Scan(&position.TxID, &position.ChangeID, &roomID, &changeUserID, &kind, &entityID, &changedAt); err != nil {
+			return batch, err
+		}
+		count++
+		if count > limit {
+			batch.HasMore = true
+			break
+		}
+		batch.Last = position
+		switch kind {
+		case "message.created", "message.updated", "message.deleted", "message.reacted":
+			if _, ok := seenMessages[entityID]; !ok {
+				seenMessages[entityID] = struct{}{}
+				messageIDs = append(messageIDs, entityID)
+			}
+		case "read.updated":
+			if _, ok := seenReadRooms[roomID]; !ok {
+				seenReadRooms[roomID] = struct{}{}
+				readRoomIDs = append(readRoomIDs, roomID)
+			}
+		case "member.joined":
+			batch.Memberships = append(batch.Memberships, domain.MembershipChange{RoomID: roomID, UserID: changeUserID, Action: domain.MembershipJoined, ChangedAt: changedAt})
+		case "member.left":
+			batch.Memberships = append(batch.Memberships, domain.MembershipChange{RoomID: roomID, UserID: changeUserID, Action: domain.MembershipLeft, ChangedAt: changedAt})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return batch, err
+	}
+	rows.Close()
+
+	if len(messageIDs) > 0 {
+		msgRows, err := pool.Query(ctx, `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE tenant_id=$1 AND message_id = ANY($2)
+			ORDER BY room_id, room_seq
+		`, tenantID, messageIDs)
+		if err != nil {
+			return batch, err
+		}
+		defer msgRows.Close()
+		for msgRows.Next() {
+			m, err := scanMessage(msgRows)
+			if err != nil {
+				return batch, err
+			}
+			batch.Messages = append(batch.Messages, m)
+		}
+		if err := msgRows.Err(); err != nil {
+			return batch, err
+		}
+		msgRows.Close()
+		if err := attachReactions(ctx, pool, tenantID, userID, batch.Messages); err != nil {
+			return batch, err
+		}
+	}
+
+	if len(readRoomIDs) > 0 {
+		readRows, err := pool.Query(ctx, `
+			SELECT rm.room_id, rm.last_read_seq, COALESCE(m.message_id, '')
+			FROM room_members rm
+			LEFT JOIN messages m ON m.tenant_id = rm.tenant_id AND m.room_id = rm.room_id AND m.room_seq = rm.last_read_seq
+			WHERE rm.tenant_id=$1 AND rm.user_id=$2 AND rm.room_id = ANY($3)
+		`, tenantID, userID, readRoomIDs)
+		if err != nil {
+			return batch, err
+		}
+		defer readRows.Close()
+		for readRows.Next() {
+			var item domain.RoomReadState
+			if err := readRows.Scan(&item.RoomID, &item.LastReadSeq, &item.LastReadMessageID); err != nil {
+				return batch, err
+			}
+			batch.ReadStates = append(batch.ReadStates, item)
+		}
+		if err := readRows.Err(); err != nil {
+			return batch, err
+		}
+	}
+	return batch, nil
+}
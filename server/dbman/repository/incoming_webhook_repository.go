@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"msg_server/server/chat/domain"
+)
+
+const incomingWebhookColumns = `tenant_id, hook_id, room_id, bot_user_id, name, created_by, created_at, last_used_at, revoked_at, revoked_by`
+
+func scanIncomingWebhook(row pgx.Row) (domain.IncomingWebhook, error) {
+	var h domain.IncomingWebhook
+	err := row.Scan(&h.TenantID, &h.ID, &h.RoomID, &h.BotUserID, &h.Name, &h.CreatedBy, &h.CreatedAt, &h.LastUsedAt, &h.RevokedAt, &h.RevokedBy)
+	return h, err
+}
+
+// CreateIncomingWebhook stores the hook and makes its bot a member of the
+// room. Without BotUserID a bot user is created in the creator's org unit. ok
+// is false when BotUserID is not a bot of the tenant or the creator is unknown.
+func (r *WebhookRepository) CreateIncomingWebhook(ctx context.Context, item domain.IncomingWebhook) (domain.IncomingWebhook, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	botUserID := item.BotUserID
+	if botUserID == "" {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users(tenant_id, org_id, email, name, role, status)
+			SELECT tenant_id, org_id, 'bot+' || gen_random_uuid()::text || '@bots.invalid', $3, 'bot', 'online'
+			FROM users
+			WHERE tenant_id=$1 AND user_id=$2
+			RETURNING user_id
+		`, item.TenantID, item.CreatedBy, item.Name).Scan(&botUserID)
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT user_id
+			FROM users
+			WHERE tenant_id=$1 AND user_id=$2 AND role='bot'
+		`, item.TenantID, botUserID).Scan(&botUserID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.IncomingWebhook{}, false, nil
+	}
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO room_members(tenant_id, room_id, user_id) VALUES($1, $2, $3) ON CONFLICT DO NOTHING`, item.TenantID, item.RoomID, botUserID); err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	created, err := scanIncomingWebhook(tx.QueryRow(ctx, `
+		INSERT INTO incoming_webhooks(tenant_id, room_id, bot_user_id, name, token_hash, created_by)
+		VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING `+incomingWebhookColumns,
+		item.TenantID, item.RoomID, botUserID, item.Name, item.TokenHash, item.CreatedBy))
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	return created, true, nil
+}
+
+func (r *WebhookRepository) ListIncomingWebhooks(ctx context.Context, tenantID, roomID string) ([]domain.IncomingWebhook, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+incomingWebhookColumns+`
+		FROM incoming_webhooks
+		WHERE tenant_id=$1 AND room_id=$2
+		ORDER BY created_at DESC, hook_id DESC
+	`, tenantID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.IncomingWebhook, 0)
+	for rows.Next() {
+		item, err := scanIncomingWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RevokeIncomingWebhook is idempotent; the first revocation is kept.
+func (r *WebhookRepository) RevokeIncomingWebhook(ctx context.Context, tenantID, roomID, hookID, actorID string) (domain.IncomingWebhook, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	item, err := scanIncomingWebhook(pool.QueryRow(ctx, `
+		UPDATE incoming_webhooks
+		SET revoked_by = CASE WHEN revoked_at IS NULL THEN $4 ELSE revoked_by END,
+			revoked_at = COALESCE(revoked_at, NOW())
+		WHERE tenant_id=$1 AND room_id=$2 AND hook_id=$3
+		RETURNING `+incomingWebhookColumns,
+		tenantID, roomID, hookID, actorID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.IncomingWebhook{}, false, nil
+	}
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	return item, true, nil
+}
+
+// AuthenticateIncomingWebhook returns the hook when tokenHash matches and it
+// is not revoked, and records the use.
+func (r *WebhookRepository) AuthenticateIncomingWebhook(ctx context.Context, tenantID, hookID, tokenHash string) (domain.IncomingWebhook, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	item, err := scanIncomingWebhook(pool.QueryRow(ctx, `
+		UPDATE incoming_webhooks
+		SET last_used_at = NOW()
+		WHERE tenant_id=$1 AND hook_id=$2 AND token_hash=$3 AND revoked_at IS NULL
+		RETURNING `+incomingWebhookColumns,
+		tenantID, hookID, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.IncomingWebhook{}, false, nil
+	}
+	if err != nil {
+		return domain.IncomingWebhook{}, false, err
+	}
+	return item, true, nil
+}
@@ -0,0 +1,523 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/db"
+	"msg_server/server/common/moderation"
+)
+
+type ModerationRepository struct {
+	router *db.TenantDBRouter
+}
+
+func NewModerationRepository(router *db.TenantDBRouter) *ModerationRepository {
+	return &ModerationRepository{router: router}
+}
+
+const moderationRuleColumns = `tenant_id, rule_id, name, kind, words, pattern, max_links, action, enabled, updated_by, created_at, updated_at`
+
+func scanModerationRule(row pgx.Row) (moderation.Rule, error) {
+	var r moderation.Rule
+	err := row.Scan(&r.TenantID, &r.ID, &r.Name, &r.Kind, &r.Words, &r.Pattern, &r.MaxLinks, &r.Action, &r.Enabled, &r.UpdatedBy, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+const moderationFlagColumns = `tenant_id, flag_id, source, room_id, subject_id, sender_id, body, rules, reporter_id, reason, status, reviewed_by, reviewed_at, created_at`
+
+func scanModerationFlag(row pgx.Row) (moderation.Flag, error) {
+	var f moderation.Flag
+	err := row.Scan(&f.TenantID, &f.ID, &f.Source, &f.RoomID, &f.SubjectID, &f.SenderID, &f.Body, &f.Rules, &f.ReporterID, &f.Reason, &f.Status, &f.ReviewedBy, &f.ReviewedAt, &f.CreatedAt)
+	return f, err
+}
+
+const moderationAuditColumns = `tenant_id, audit_id, room_id, actor_id, action, target_user_id, message_id, flag_id, reason, created_at`
+
+func scanModerationAudit(row pgx.Row) (moderation.AuditEntry, error) {
+	var a moderation.AuditEntry
+	err := row.Scan(&a.TenantID, &a.ID, &a.RoomID, &a.ActorID, &a.Action, &a.TargetUserID, &a.MessageID, &a.FlagID, &a.Reason, &a.CreatedAt)
+	return a, err
+}
+
+// closePendingFlags resolves every other pending flag and report on content
+// that has just been removed.
+func closePendingFlags(ctx context.Context, tx pgx.Tx, tenantID, subjectID, reviewerID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE moderation_flags
+		SET status='removed', reviewed_by=$3, reviewed_at=NOW()
+		WHERE tenant_id=$1 AND subject_id=$2 AND status='pending'
+	`, tenantID, subjectID, reviewerID)
+	return err
+}
+
+// insertModerationAudit records the action inside the caller's transaction.
+func insertModerationAudit(ctx context.Context, tx pgx.Tx, entry moderation.AuditEntry) (moderation.AuditEntry, error) {
+	return scanModerationAudit(tx.QueryRow(ctx, `
+		INSERT INTO moderation_audit(tenant_id, room_id, actor_id, action, target_user_id, message_id, flag_id, reason)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+moderationAuditColumns,
+		entry.TenantID, entry.RoomID, entry.ActorID, entry.Action, entry.TargetUserID, entry.MessageID, entry.FlagID, entry.Reason))
+}
+
+func (r *ModerationRepository) ListRules(ctx context.Context, tenantID string) ([]moderation.Rule, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+moderationRuleColumns+`
+		FROM moderation_rules
+		WHERE tenant_id=$1
+		ORDER BY created_at, rule_id
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]moderation.Rule, 0)
+	for rows.Next() {
+		item, err := scanModerationRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ModerationRepository) CreateRule(ctx context.Context, item moderation.Rule) (moderation.Rule, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return moderation.Rule{}, err
+	}
+	return scanModerationRule(pool.QueryRow(ctx, `
+		INSERT INTO moderation_rules(tenant_id, name, kind, words, pattern, max_links, action, enabled, updated_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+moderationRuleColumns,
+		item.TenantID, item.Name, item.Kind, nonNilStrings(item.Words), item.Pattern, item.MaxLinks, item.Action, item.Enabled, item.UpdatedBy))
+}
+
+func (r *ModerationRepository) UpdateRule(ctx context.Context, item moderation.Rule) (moderation.Rule, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return moderation.Rule{}, false, err
+	}
+	updated, err := scanModerationRule(pool.QueryRow(ctx, `
+		UPDATE moderation_rules
+		SET name=$3, kind=$4, words=$5, pattern=$6, max_links=$7, action=$8, enabled=$9, updated_by=$10, updated_at=NOW()
+		WHERE tenant_id=$1 AND rule_id=$2
+		RETURNING `+moderationRuleColumns,
+		item.TenantID, item.ID, item.Name, item.Kind, nonNilStrings(item.Words), item.Pattern, item.MaxLinks, item.Action, item.Enabled, item.UpdatedBy))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return moderation.Rule{}, false, nil
+	}
+	if err != nil {
+		return moderation.Rule{}, false, err
+	}
+	return updated, true, nil
+}
+
+func (r *ModerationRepository) DeleteRule(ctx context.Context, tenantID, ruleID string) (bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	var id string
+	err = pool.QueryRow(ctx, `DELETE FROM moderation_rules WHERE tenant_id=$1 AND rule_id=$2 RETURNING rule_id`, tenantID, ruleID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *ModerationRepository) CreateFlag(ctx context.Context, item moderation.Flag) (moderation.Flag, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return moderation.Flag{}, err
+	}
+	return scanModerationFlag(pool.QueryRow(ctx, `
+		INSERT INTO moderation_flags(tenant_id, source, room_id, subject_id, sender_id, body, rules)
+		VALUES($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+moderationFlagColumns,
+		item.TenantID, item.Source, item.RoomID, item.SubjectID, item.SenderID, item.Body, nonNilStrings(item.Rules)))
+}
+
+// insertMessageFlag queues a message for review inside the transaction that
+// stores it, so flagged content is never committed without its flag.
+func insertMessageFlag(ctx context.Context, tx pgx.Tx, message domain.Message, rules []string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO moderation_flags(tenant_id, source, room_id, subject_id, sender_id, body, rules)
+		VALUES($1, $2, $3, $4, $5, $6, $7)
+	`, message.TenantID, moderation.SourceMessage, message.RoomID, message.ID, message.SenderID, message.Body, rules)
+	return err
+}
+
+// ListFlags returns the newest flags first; an empty status lists all.
+func (r *ModerationRepository) ListFlags(ctx context.Context, tenantID string, status moderation.FlagStatus, limit int) ([]moderation.Flag, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+moderationFlagColumns+`
+		FROM moderation_flags
+		WHERE tenant_id=$1 AND ($2='' OR status=$2)
+		ORDER BY created_at DESC, flag_id DESC
+		LIMIT $3
+	`, tenantID, string(status), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]moderation.Flag, 0)
+	for rows.Next() {
+		item, err := scanModerationFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ResolveFlag closes a pending flag as approved or removed and audits it.
+// Removing it soft-deletes the message (queueing message.deleted) or deletes
+// the note in the same transaction; removed is nil unless a message was
+// deleted. ok is false when the flag is unknown or already resolved.
+func (r *ModerationRepository) ResolveFlag(ctx context.Context, tenantID, flagID, reviewerID string, status moderation.FlagStatus) (moderation.Flag, *domain.Message, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return moderation.Flag{}, nil, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return moderation.Flag{}, nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	flag, err := scanModerationFlag(tx.QueryRow(ctx, `
+		UPDATE moderation_flags
+		SET status=$3, reviewed_by=$4, reviewed_at=NOW()
+		WHERE tenant_id=$1 AND flag_id=$2 AND status='pending'
+		RETURNING `+moderationFlagColumns,
+		tenantID, flagID, status, reviewerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return moderation.Flag{}, nil, false, nil
+	}
+	if err != nil {
+		return moderation.Flag{}, nil, false, err
+	}
+
+	var removed *domain.Message
+	if status == moderation.FlagRemoved {
+		switch flag.Source {
+		case moderation.SourceMessage:
+			m, err := softDeleteMessage(ctx, tx, tenantID, flag.RoomID, flag.SubjectID, reviewerID)
+			if err == nil {
+				removed = &m
+			} else if !errors.Is(err, pgx.ErrNoRows) {
+				return moderation.Flag{}, nil, false, err
+			}
+		case moderation.SourceNote:
+			if _, err := tx.Exec(ctx, `DELETE FROM notes WHERE tenant_id=$1 AND note_id=$2`, tenantID, flag.SubjectID); err != nil {
+				return moderation.Flag{}, nil, false, err
+			}
+		}
+	}
+	if status == moderation.FlagRemoved {
+		if err := closePendingFlags(ctx, tx, tenantID, flag.SubjectID, reviewerID); err != nil {
+			return moderation.Flag{}, nil, false, err
+		}
+	}
+	entry := moderation.AuditEntry{TenantID: tenantID, RoomID: flag.RoomID, ActorID: reviewerID, Action: moderation.AuditFlagApprove, TargetUserID: flag.SenderID, FlagID: flag.ID}
+	if status == moderation.FlagRemoved {
+		entry.Action = moderation.AuditFlagRemove
+	}
+	if flag.Source == moderation.SourceMessage {
+		entry.MessageID = flag.SubjectID
+	}
+	if _, err := insertModerationAudit(ctx, tx, entry); err != nil {
+		return moderation.Flag{}, nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return moderation.Flag{}, nil, false, err
+	}
+	return flag, removed, true, nil
+}
+
+// ReportMessage queues a member's report of a live message in the room and
+// audits it. A second pending report by the same member returns the first.
+// ok is false when the message is unknown or deleted.
+func (r *ModerationRepository) ReportMessage(ctx context.Context, tenantID, roomID, messageID, reporterID, reason string) (moderation.Flag, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return moderation.Flag{}, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return moderation.Flag{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	flag, err := scanModerationFlag(tx.QueryRow(ctx, `
+		INSERT INTO moderation_flags(tenant_id, source, room_id, subject_id, sender_id, body, reporter_id, reason)
+		SELECT tenant_id, 'message', room_id, message_id, sender_id, body, $4, $5
+		FROM messages
+		WHERE tenant_id=$1 AND room_id=$2 AND message_id=$3 AND deleted_at IS NULL
+		ON CONFLICT (tenant_id, subject_id, reporter_id) WHERE reporter_id <> '' AND status = 'pending' DO NOTHING
+		RETURNING `+moderationFlagColumns,
+		tenantID, roomID, messageID, reporterID, reason))
+	if errors.Is(err, pgx.ErrNoRows) {
+		flag, err = scanModerationFlag(tx.QueryRow(ctx, `
+			SELECT `+moderationFlagColumns+`
+			FROM moderation_flags
+			WHERE tenant_id=$1 AND room_id=$2 AND subject_id=$3 AND reporter_id=$4 AND status='pending'
+		`, tenantID, roomID, messageID, reporterID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return moderation.Flag{}, false, nil
+		}
+		if err != nil {
+			return moderation.Flag{}, false, err
+		}
+		return flag, true, nil
+	}
+	if err != nil {
+		return moderation.Flag{}, false, err
+	}
+	if _, err := insertModerationAudit(ctx, tx, moderation.AuditEntry{
+		TenantID:     tenantID,
+		RoomID:       roomID,
+		ActorID:      reporterID,
+		Action:       moderation.AuditMessageReport,
+		TargetUserID: flag.SenderID,
+		MessageID:    messageID,
+		FlagID:       flag.ID,
+		Reason:       reason,
+	}); err != nil {
+		return moderation.Flag{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return moderation.Flag{}, false, err
+	}
+	return flag, true, nil
+}
+
+// DeleteMessage soft-deletes any member's message on an admin's behalf and
+// audits it with the reason. ok is false when the message is unknown or
+// already deleted.
+func (r *ModerationRepository) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID, reason string) (domain.Message, moderation.AuditEntry, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	m, err := softDeleteMessage(ctx, tx, tenantID, roomID, messageID, actorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Message{}, moderation.AuditEntry{}, false, nil
+	}
+	if err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	if err := closePendingFlags(ctx, tx, tenantID, m.ID, actorID); err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	entry, err := insertModerationAudit(ctx, tx, moderation.AuditEntry{
+		TenantID:     tenantID,
+		RoomID:       roomID,
+		ActorID:      actorID,
+		Action:       moderation.AuditMessageDelete,
+		TargetUserID: m.SenderID,
+		MessageID:    m.ID,
+		Reason:       reason,
+	})
+	if err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Message{}, moderation.AuditEntry{}, false, err
+	}
+	return m, entry, true, nil
+}
+
+// RemoveMember takes the user out of the room and, with ban, keeps them from
+// being added back. removed reports whether the user was in the room. ok is
+// false when the user is the room's owner, or is not in the room and, for a
+// ban, not a user of the tenant.
+func (r *ModerationRepository) RemoveMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string, ban bool) (moderation.AuditEntry, bool, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var role string
+	err = tx.QueryRow(ctx, `
+		SELECT role FROM room_members WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3 FOR UPDATE
+	`, tenantID, roomID, userID).Scan(&role)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	if role == string(domain.RoomRoleOwner) {
+		return moderation.AuditEntry{}, false, false, nil
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM room_members WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3`, tenantID, roomID, userID)
+	if err != nil {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	removed := tag.RowsAffected() > 0
+	found := removed
+	action := moderation.AuditMemberRemove
+	if ban {
+		action = moderation.AuditMemberBan
+		tag, err = tx.Exec(ctx, `
+			INSERT INTO room_bans(tenant_id, room_id, user_id, reason, banned_by)
+			SELECT $1, $2, user_id, $4, $5
+			FROM users
+			WHERE tenant_id=$1 AND user_id=$3
+			ON CONFLICT (tenant_id, room_id, user_id) DO UPDATE
+			SET reason = EXCLUDED.reason,
+				banned_by = EXCLUDED.banned_by,
+				created_at = NOW()
+		`, tenantID, roomID, userID, reason, actorID)
+		if err != nil {
+			return moderation.AuditEntry{}, false, false, err
+		}
+		found = found || tag.RowsAffected() > 0
+	}
+	if !found {
+		return moderation.AuditEntry{}, false, false, nil
+	}
+	entry, err := insertModerationAudit(ctx, tx, moderation.AuditEntry{
+		TenantID:     tenantID,
+		RoomID:       roomID,
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: userID,
+		Reason:       reason,
+	})
+	if err != nil {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return moderation.AuditEntry{}, false, false, err
+	}
+	return entry, removed, true, nil
+}
+
+// UnbanMember lifts a ban without re-adding the user. ok is false when the
+// user is not banned.
+func (r *ModerationRepository) UnbanMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string) (moderation.AuditEntry, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM room_bans WHERE tenant_id=$1 AND room_id=$2 AND user_id=$3`, tenantID, roomID, userID)
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return moderation.AuditEntry{}, false, nil
+	}
+	entry, err := insertModerationAudit(ctx, tx, moderation.AuditEntry{
+		TenantID:     tenantID,
+		RoomID:       roomID,
+		ActorID:      actorID,
+		Action:       moderation.AuditMemberUnban,
+		TargetUserID: userID,
+		Reason:       reason,
+	})
+	if err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return moderation.AuditEntry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (r *ModerationRepository) ListBans(ctx context.Context, tenantID, roomID string) ([]moderation.RoomBan, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT tenant_id, room_id, user_id, reason, banned_by, created_at
+		FROM room_bans
+		WHERE tenant_id=$1 AND room_id=$2
+		ORDER BY created_at DESC
+	`, tenantID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]moderation.RoomBan, 0)
+	for rows.Next() {
+		var item moderation.RoomBan
+		if err := rows.Scan(&item.TenantID, &item.RoomID, &item.UserID, &item.Reason, &item.BannedBy, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ListAudit returns the newest entries first, optionally for one room.
+func (r *ModerationRepository) ListAudit(ctx context.Context, tenantID, roomID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]moderation.AuditEntry, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + moderationAuditColumns + `
+		FROM moderation_audit
+		WHERE tenant_id=$1 AND ($2='' OR room_id=$2)`
+	args := []any{tenantID, roomID}
+	if cursorCreatedAt != nil && cursorID != nil {
+		query += ` AND (created_at, audit_id) < ($3, $4)
+		ORDER BY created_at DESC, audit_id DESC
+		LIMIT $5`
+		args = append(args, *cursorCreatedAt, *cursorID, limit)
+	} else {
+		query += `
+		ORDER BY created_at DESC, audit_id DESC
+		LIMIT $3`
+		args = append(args, limit)
+	}
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]moderation.AuditEntry, 0)
+	for rows.Next() {
+		item, err := scanModerationAudit(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
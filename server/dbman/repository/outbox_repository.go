@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"msg_server/server/common/events"
+	"msg_server/server/common/infra/db"
+	"msg_server/server/common/trace"
+	"msg_server/server/dbman/domain"
+)
+
+type OutboxRepository struct {
+	router *db.TenantDBRouter
+}
+
+func NewOutboxRepository(router *db.TenantDBRouter) *OutboxRepository {
+	return &OutboxRepository{router: router}
+}
+
+// insertOutboxEvent records an event inside the caller's transaction so it is
+// published if and only if the change commits. The row stores the complete
+// CloudEvents envelope, carrying the request's traceparent.
+func insertOutboxEvent(ctx context.Context, tx pgx.Tx, tenantID string, data events.Data) error {
+	env, err := events.New(tenantID, data, trace.ParentFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO event_outbox(tenant_id, event_type, payload)
+		VALUES($1, $2, $3)
+	`, tenantID, env.Type, body)
+	return err
+}
+
+// ClaimPending leases up to limit due events for lease. A claimed row becomes
+// due again if the relay neither delivers nor reschedules it before the lease
+// runs out, so a crashed relay only delays delivery.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, tenantID string, limit int, lease time.Duration) ([]domain.OutboxEvent, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		UPDATE event_outbox o
+		SET next_attempt_at = NOW() + make_interval(secs => $3), attempts = o.attempts + 1
+		FROM (
+			SELECT id
+			FROM event_outbox
+			WHERE tenant_id=$1 AND delivered_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE o.id = due.id
+		RETURNING o.id, o.tenant_id, o.event_type, o.payload, o.attempts, o.created_at
+	`, tenantID, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.OutboxEvent, 0)
+	for rows.Next() {
+		var item domain.OutboxEvent
+		if err := rows.Scan(&item.ID, &item.TenantID, &item.EventType, &item.Payload, &item.Attempts, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, tenantID string, id int64) error {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Exec(ctx, `
+		UPDATE event_outbox
+		SET delivered_at=NOW(), last_error=''
+		WHERE tenant_id=$1 AND id=$2
+	`, tenantID, id)
+	return err
+}
+
+func (r *OutboxRepository) Reschedule(ctx context.Context, tenantID string, id int64, nextAttemptAt time.Time, lastError string) error {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Exec(ctx, `
+		UPDATE event_outbox
+		SET next_attempt_at=$3, last_error=$4
+		WHERE tenant_id=$1 AND id=$2 AND delivered_at IS NULL
+	`, tenantID, id, nextAttemptAt, lastError)
+	return err
+}
+
+// PurgeDelivered drops delivered rows older than before and returns how many.
+func (r *OutboxRepository) PurgeDelivered(ctx context.Context, tenantID string, before time.Time) (int64, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	cmd, err := pool.Exec(ctx, `
+		DELETE FROM event_outbox
+		WHERE tenant_id=$1 AND delivered_at IS NOT NULL AND delivered_at < $2
+	`, tenantID, before)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
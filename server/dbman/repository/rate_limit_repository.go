@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/db"
+)
+
+type RateLimitRepository struct {
+	router *db.TenantDBRouter
+}
+
+func NewRateLimitRepository(router *db.TenantDBRouter) *RateLimitRepository {
+	return &RateLimitRepository{router: router}
+}
+
+const rateLimitColumns = `tenant_id, policy_id, scope, subject_id, action, per_minute, burst, updated_by, created_at, updated_at`
+
+func scanRateLimitPolicy(row pgx.Row) (domain.RateLimitPolicy, error) {
+	var p domain.RateLimitPolicy
+	err := row.Scan(&p.TenantID, &p.ID, &p.Scope, &p.SubjectID, &p.Action, &p.PerMinute, &p.Burst, &p.UpdatedBy, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func (r *RateLimitRepository) ListPolicies(ctx context.Context, tenantID string) ([]domain.RateLimitPolicy, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+rateLimitColumns+`
+		FROM rate_limit_policies
+		WHERE tenant_id=$1
+		ORDER BY scope, action, subject_id
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.RateLimitPolicy, 0)
+	for rows.Next() {
+		item, err := scanRateLimitPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// UpsertPolicy replaces the policy for (scope, subject_id, action).
+func (r *RateLimitRepository) UpsertPolicy(ctx context.Context, item domain.RateLimitPolicy) (domain.RateLimitPolicy, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return domain.RateLimitPolicy{}, err
+	}
+	return scanRateLimitPolicy(pool.QueryRow(ctx, `
+		INSERT INTO rate_limit_policies(tenant_id, scope, subject_id, action, per_minute, burst, updated_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tenant_id, scope, subject_id, action) DO UPDATE
+		SET per_minute = EXCLUDED.per_minute,
+			burst = EXCLUDED.burst,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING `+rateLimitColumns,
+		item.TenantID, item.Scope, item.SubjectID, item.Action, item.PerMinute, item.Burst, item.UpdatedBy))
+}
+
+func (r *RateLimitRepository) DeletePolicy(ctx context.Context, tenantID, policyID string) (bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	var id string
+	err = pool.QueryRow(ctx, `DELETE FROM rate_limit_policies WHERE tenant_id=$1 AND policy_id=$2 RETURNING policy_id`, tenantID, policyID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -22,6 +22,8 @@ func (r *TenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
 		SELECT tenant_id, name, deployment_mode, dedicated_dsn, dedicated_redis_addr, dedicated_lavinmq_url,
 		       dedicated_minio_endpoint, dedicated_minio_access_key, dedicated_minio_secret_key,
 		       dedicated_minio_bucket, dedicated_minio_use_ssl,
+		       stun_urls, turn_urls, turn_shared_secret, turn_credential_ttl_sec,
+		       sfu_url, sfu_api_key, sfu_api_secret,
 		       user_count_threshold, is_active, created_at, updated_at
 		FROM tenants
 		ORDER BY tenant_id
@@ -46,6 +48,13 @@ func (r *TenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
 			&item.DedicatedMinIOSecretKey,
 			&item.DedicatedMinIOBucket,
 			&item.DedicatedMinIOUseSSL,
+			&item.StunURLs,
+			&item.TurnURLs,
+			&item.TurnSharedSecret,
+			&item.TurnCredentialTTLSec,
+			&item.SFUURL,
+			&item.SFUAPIKey,
+			&item.SFUAPISecret,
 			&item.UserCountThreshold,
 			&item.IsActive,
 			&item.CreatedAt,
@@ -64,6 +73,8 @@ func (r *TenantRepository) GetByID(ctx context.Context, tenantID string) (domain
 		SELECT tenant_id, name, deployment_mode, dedicated_dsn, dedicated_redis_addr, dedicated_lavinmq_url,
 		       dedicated_minio_endpoint, dedicated_minio_access_key, dedicated_minio_secret_key,
 		       dedicated_minio_bucket, dedicated_minio_use_ssl,
+		       stun_urls, turn_urls, turn_shared_secret, turn_credential_ttl_sec,
+		       sfu_url, sfu_api_key, sfu_api_secret,
 		       user_count_threshold, is_active, created_at, updated_at
 		FROM tenants
 		WHERE tenant_id = $1
@@ -79,6 +90,13 @@ func (r *TenantRepository) GetByID(ctx context.Context, tenantID string) (domain
 		&item.DedicatedMinIOSecretKey,
 		&item.DedicatedMinIOBucket,
 		&item.DedicatedMinIOUseSSL,
+		&item.StunURLs,
+		&item.TurnURLs,
+		&item.TurnSharedSecret,
+		&item.TurnCredentialTTLSec,
+		&item.SFUURL,
+		&item.SFUAPIKey,
+		&item.SFUAPISecret,
 		&item.UserCountThreshold,
 		&item.IsActive,
 		&item.CreatedAt,
@@ -95,14 +113,18 @@ func (r *TenantRepository) Create(ctx context.Context, item domain.Tenant) (doma
 			dedicated_redis_addr, dedicated_lavinmq_url,
 			dedicated_minio_endpoint, dedicated_minio_access_key, dedicated_minio_secret_key,
 			dedicated_minio_bucket, dedicated_minio_use_ssl,
+			stun_urls, turn_urls, turn_shared_secret, turn_credential_ttl_sec,
+			sfu_url, sfu_api_key, sfu_api_secret,
 			user_count_threshold, is_active
 		)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 		RETURNING created_at, updated_at
 	`, item.TenantID, item.Name, item.DeploymentMode, item.DedicatedDSN,
 		item.DedicatedRedisAddr, item.DedicatedLavinMQURL,
 		item.DedicatedMinIOEndpoint, item.DedicatedMinIOAccessKey, item.DedicatedMinIOSecretKey,
 		item.DedicatedMinIOBucket, item.DedicatedMinIOUseSSL,
+		nonNilStrings(item.StunURLs), nonNilStrings(item.TurnURLs), item.TurnSharedSecret, item.TurnCredentialTTLSec,
+		item.SFUURL, item.SFUAPIKey, item.SFUAPISecret,
 		item.UserCountThreshold, item.IsActive,
 	).Scan(&item.CreatedAt, &item.UpdatedAt)
 	return item, err
@@ -124,17 +146,28 @@ func (r *TenantRepository) UpdateConfig(ctx context.Context, item domain.Tenant)
 			dedicated_minio_use_ssl = $11,
 			user_count_threshold = $12,
 			is_active = $13,
+			stun_urls = $14,
+			turn_urls = $15,
+			turn_shared_secret = $16,
+			turn_credential_ttl_sec = $17,
+			sfu_url = $18,
+			sfu_api_key = $19,
+			sfu_api_secret = $20,
 			updated_at = NOW()
 		WHERE tenant_id = $1
 		RETURNING tenant_id, name, deployment_mode, dedicated_dsn, dedicated_redis_addr, dedicated_lavinmq_url,
 		          dedicated_minio_endpoint, dedicated_minio_access_key, dedicated_minio_secret_key,
 		          dedicated_minio_bucket, dedicated_minio_use_ssl,
+		          stun_urls, turn_urls, turn_shared_secret, turn_credential_ttl_sec,
+		          sfu_url, sfu_api_key, sfu_api_secret,
 		          user_count_threshold, is_active, created_at, updated_at
 	`, item.TenantID, item.Name, item.DeploymentMode, item.DedicatedDSN,
 		item.DedicatedRedisAddr, item.DedicatedLavinMQURL,
 		item.DedicatedMinIOEndpoint, item.DedicatedMinIOAccessKey, item.DedicatedMinIOSecretKey,
 		item.DedicatedMinIOBucket, item.DedicatedMinIOUseSSL,
 		item.UserCountThreshold, item.IsActive,
+		nonNilStrings(item.StunURLs), nonNilStrings(item.TurnURLs), item.TurnSharedSecret, item.TurnCredentialTTLSec,
+		item.SFUURL, item.SFUAPIKey, item.SFUAPISecret,
 	).Scan(
 		&item.TenantID,
 		&item.Name,
@@ -147,6 +180,13 @@ func (r *TenantRepository) UpdateConfig(ctx context.Context, item domain.Tenant)
 		&item.DedicatedMinIOSecretKey,
 		&item.DedicatedMinIOBucket,
 		&item.DedicatedMinIOUseSSL,
+		&item.StunURLs,
+		&item.TurnURLs,
+		&item.TurnSharedSecret,
+		&item.TurnCredentialTTLSec,
+		&item.SFUURL,
+		&item.SFUAPIKey,
+		&item.SFUAPISecret,
 		&item.UserCountThreshold,
 		&item.IsActive,
 		&item.CreatedAt,
@@ -154,3 +194,11 @@ func (r *TenantRepository) UpdateConfig(ctx context.Context, item domain.Tenant)
 	)
 	return item, err
 }
+
+// nonNilStrings keeps NOT NULL TEXT[] columns from receiving NULL for unset lists.
+func nonNilStrings(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
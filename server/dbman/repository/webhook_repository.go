@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/db"
+)
+
+type WebhookRepository struct {
+	router *db.TenantDBRouter
+}
+
+func NewWebhookRepository(router *db.TenantDBRouter) *WebhookRepository {
+	return &WebhookRepository{router: router}
+}
+
+const webhookColumns = `tenant_id, webhook_id, target_url, event_types, description, is_active, consecutive_failures, disabled_at, disabled_reason, last_success_at, last_failure_at, created_by, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (domain.Webhook, error) {
+	var w domain.Webhook
+	err := row.Scan(&w.TenantID, &w.ID, &w.TargetURL, &w.EventTypes, &w.Description, &w.IsActive, &w.ConsecutiveFailures, &w.DisabledAt, &w.DisabledReason, &w.LastSuccessAt, &w.LastFailureAt, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, item domain.Webhook) (domain.Webhook, error) {
+	pool, err := r.router.DBForTenant(ctx, item.TenantID)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	created, err := scanWebhook(pool.QueryRow(ctx, `
+		INSERT INTO webhooks(tenant_id, target_url, event_types, secret, description, created_by)
+		VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING `+webhookColumns,
+		item.TenantID, item.TargetURL, item.EventTypes, item.Secret, item.Description, item.CreatedBy))
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	created.Secret = item.Secret
+	return created, nil
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, tenantID string) ([]domain.Webhook, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE tenant_id=$1
+		ORDER BY created_at DESC, webhook_id DESC
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.Webhook, 0)
+	for rows.Next() {
+		item, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *WebhookRepository) GetWebhook(ctx context.Context, tenantID, webhookID string) (domain.Webhook, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Webhook{}, false, err
+	}
+	item, err := scanWebhook(pool.QueryRow(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE tenant_id=$1 AND webhook_id=$2
+	`, tenantID, webhookID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Webhook{}, false, nil
+	}
+	if err != nil {
+		return domain.Webhook{}, false, err
+	}
+	return item, true, nil
+}
+
+// UpdateWebhook applies the non-nil fields. Re-activating a webhook clears the
+// failure streak that disabled it.
+func (r *WebhookRepository) UpdateWebhook(ctx context.Context, tenantID, webhookID string, targetURL *string, eventTypes []string, description *string, isActive *bool) (domain.Webhook, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Webhook{}, false, err
+	}
+	item, err := scanWebhook(pool.QueryRow(ctx, `
+		UPDATE webhooks
+		SET target_url = COALESCE($3, target_url),
+			event_types = COALESCE($4, event_types),
+			description = COALESCE($5, description),
+			is_active = COALESCE($6, is_active),
+			consecutive_failures = CASE WHEN $6 IS TRUE THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $6 IS TRUE THEN NULL WHEN $6 IS FALSE THEN COALESCE(disabled_at, NOW()) ELSE disabled_at END,
+			disabled_reason = CASE WHEN $6 IS TRUE THEN '' WHEN $6 IS FALSE AND disabled_at IS NULL THEN 'disabled by admin' ELSE disabled_reason END,
+			updated_at = NOW()
+		WHERE tenant_id=$1 AND webhook_id=$2
+		RETURNING `+webhookColumns,
+		tenantID, webhookID, targetURL, eventTypes, description, isActive))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Webhook{}, false, nil
+	}
+	if err != nil {
+		return domain.Webhook{}, false, err
+	}
+	return item, true, nil
+}
+
+func (r *WebhookRepository) RotateWebhookSecret(ctx context.Context, tenantID, webhookID, secret string) (domain.Webhook, bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return domain.Webhook{}, false, err
+	}
+	item, err := scanWebhook(pool.QueryRow(ctx, `
+		UPDATE webhooks
+		SET secret=$3, updated_at=NOW()
+		WHERE tenant_id=$1 AND webhook_id=$2
+		RETURNING `+webhookColumns,
+		tenantID, webhookID, secret))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Webhook{}, false, nil
+	}
+	if err != nil {
+		return domain.Webhook{}, false, err
+	}
+	item.Secret = secret
+	return item, true, nil
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, tenantID, webhookID string) (bool, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	cmd, err := pool.Exec(ctx, `DELETE FROM webhooks WHERE tenant_id=$1 AND webhook_id=$2`, tenantID, webhookID)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() > 0, nil
+}
+
+// EnqueueDeliveries queues the event for every active webhook subscribed to
+// its type. Redelivered events are ignored per webhook.
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, tenantID, eventID, eventType string, payload []byte) (int64, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	cmd, err := pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries(tenant_id, webhook_id, event_id, event_type, payload)
+		SELECT tenant_id, webhook_id, $2, $3, $4
+		FROM webhooks
+		WHERE tenant_id=$1 AND is_active AND ($3 = ANY(event_types) OR '*' = ANY(event_types))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`, tenantID, eventID, eventType, payload)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// ClaimDeliveries leases up to limit due deliveries of active webhooks and
+// counts the attempt. A lease that runs out makes the delivery due again.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, tenantID string, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $3), attempts = d.attempts + 1
+		FROM (
+			SELECT wd.delivery_id, w.target_url, w.secret
+			FROM webhook_deliveries wd
+			JOIN webhooks w ON w.webhook_id = wd.webhook_id
+			WHERE wd.tenant_id=$1 AND wd.status='pending' AND wd.next_attempt_at <= NOW() AND w.is_active
+			ORDER BY wd.next_attempt_at
+			LIMIT $2
+			FOR UPDATE OF wd SKIP LOCKED
+		) due
+		WHERE d.delivery_id = due.delivery_id
+		RETURNING d.tenant_id, d.delivery_id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, d.created_at, due.target_url, due.secret
+	`, tenantID, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		item := domain.WebhookDelivery{Status: domain.WebhookDeliveryPending}
+		if err := rows.Scan(&item.TenantID, &item.ID, &item.WebhookID, &item.EventID, &item.EventType, &item.Payload, &item.Attempts, &item.CreatedAt, &item.TargetURL, &item.Secret); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RecordAttempt stores the attempt on the delivery and updates the webhook's
+// failure streak. It reports whether the webhook was disabled by this attempt;
+// its remaining pending deliveries are then failed as well.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, attempt domain.WebhookAttempt) (bool, error) {
+	pool, err := r.router.DBForTenant(ctx, attempt.TenantID)
+	if err != nil {
+		return false, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status=$3, response_status=$4, response_body=$5, last_error=$6, duration_ms=$7,
+			next_attempt_at = COALESCE($8, next_attempt_at),
+			completed_at = CASE WHEN $3 = 'pending' THEN NULL ELSE NOW() END
+		WHERE tenant_id=$1 AND delivery_id=$2
+	`, attempt.TenantID, attempt.DeliveryID, attempt.Status, attempt.ResponseStatus, attempt.ResponseBody, attempt.Error, attempt.DurationMS, attempt.NextAttemptAt); err != nil {
+		return false, err
+	}
+
+	if attempt.Succeeded {
+		_, err := tx.Exec(ctx, `
+			UPDATE webhooks
+			SET consecutive_failures=0, last_success_at=NOW()
+			WHERE tenant_id=$1 AND webhook_id=$2
+		`, attempt.TenantID, attempt.WebhookID)
+		if err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	}
+
+	var disabled bool
+	err = tx.QueryRow(ctx, `
+		WITH prev AS (
+			SELECT webhook_id, is_active, consecutive_failures + 1 AS failures
+			FROM webhooks
+			WHERE tenant_id=$1 AND webhook_id=$2
+			FOR UPDATE
+		), next AS (
+			SELECT webhook_id, is_active, failures, is_active AND $3 > 0 AND failures >= $3 AS trips
+			FROM prev
+		)
+		UPDATE webhooks w
+		SET consecutive_failures = next.failures,
+			last_failure_at = NOW(),
+			is_active = w.is_active AND NOT next.trips,
+			disabled_at = CASE WHEN next.trips THEN NOW() ELSE w.disabled_at END,
+			disabled_reason = CASE WHEN next.trips THEN 'too many consecutive failures' ELSE w.disabled_reason END
+		FROM next
+		WHERE w.webhook_id = next.webhook_id
+		RETURNING next.trips
+	`, attempt.TenantID, attempt.WebhookID, attempt.DisableAfter).Scan(&disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, tx.Commit(ctx)
+	}
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		if _, err := tx.Exec(ctx, `
+			UPDATE webhook_deliveries
+			SET status='failed', last_error='webhook disabled', completed_at=NOW()
+			WHERE tenant_id=$1 AND webhook_id=$2 AND status='pending'
+		`, attempt.TenantID, attempt.WebhookID); err != nil {
+			return false, err
+		}
+	}
+	return disabled, tx.Commit(ctx)
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, tenantID, webhookID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.WebhookDelivery, error) {
+	pool, err := r.router.DBForTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT tenant_id, delivery_id, webhook_id, event_id, event_type, payload, status, attempts, response_status, response_body, last_error, duration_ms, next_attempt_at, created_at, completed_at
+		FROM webhook_deliveries
+		WHERE tenant_id=$1 AND webhook_id=$2`
+	args := []any{tenantID, webhookID}
+	if cursorCreatedAt != nil && cursorID != nil {
+		query += ` AND (created_at, delivery_id) < ($3, $4)
+		ORDER BY created_at DESC, delivery_id DESC
+		LIMIT $5`
+		args = append(args, *cursorCreatedAt, *cursorID, limit)
+	} else {
+		query += `
+		ORDER BY created_at DESC, delivery_id DESC
+		LIMIT $3`
+		args = append(args, limit)
+	}
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var item domain.WebhookDelivery
+		if err := rows.Scan(&item.TenantID, &item.ID, &item.WebhookID, &item.EventID, &item.EventType, &item.Payload, &item.Status, &item.Attempts, &item.ResponseStatus, &item.ResponseBody, &item.LastError, &item.DurationMS, &item.NextAttemptAt, &item.CreatedAt, &item.CompletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/dbman/repository"
+)
+
+type CallService struct {
+	repo *repository.CallRepository
+}
+
+func NewCallService(repo *repository.CallRepository) *CallService {
+	return &CallService{repo: repo}
+}
+
+func (s *CallService) CreateCall(ctx context.Context, call domain.Call) (domain.Call, bool, error) {
+	return s.repo.CreateCall(ctx, call)
+}
+
+func (s *CallService) GetCall(ctx context.Context, tenantID, roomID, callID string) (domain.Call, bool, error) {
+	return s.repo.GetCall(ctx, tenantID, roomID, callID)
+}
+
+func (s *CallService) GetLiveCall(ctx context.Context, tenantID, roomID string) (domain.Call, bool, error) {
+	return s.repo.GetLiveCall(ctx, tenantID, roomID)
+}
+
+func (s *CallService) TransitionCall(ctx context.Context, tenantID, roomID, callID, userID string, action domain.CallAction) (domain.Call, bool, error) {
+	return s.repo.TransitionCall(ctx, tenantID, roomID, callID, userID, action)
+}
+
+func (s *CallService) ListCalls(ctx context.Context, tenantID, roomID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.Call, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 30
+	}
+	return s.repo.ListCalls(ctx, tenantID, roomID, limit, cursorCreatedAt, cursorID)
+}
+
+func (s *CallService) ListExpiredRingingCalls(ctx context.Context, tenantID string, createdBefore time.Time, limit int) ([]domain.Call, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 100
+	}
+	return s.repo.ListExpiredRingingCalls(ctx, tenantID, createdBefore, limit)
+}
+
+func (s *CallService) RecordTurnCredential(ctx context.Context, item domain.TurnCredentialAudit) (domain.TurnCredentialAudit, error) {
+	return s.repo.RecordTurnCredential(ctx, item)
+}
@@ -20,34 +20,132 @@ func (s *ChatService) CreateRoom(ctx context.Context, tenantID string, room doma
 	return s.repo.CreateRoom(ctx, tenantID, room, memberIDs)
 }
 
-func (s *ChatService) AddMember(ctx context.Context, tenantID, roomID, userID string) error {
-	return s.repo.AddMember(ctx, tenantID, roomID, userID)
+func (s *ChatService) AddMember(ctx context.Context, tenantID, roomID, userID string, role domain.RoomRole) (bool, bool, error) {
+	if role == "" {
+		role = domain.RoomRoleMember
+	}
+	return s.repo.AddMember(ctx, tenantID, roomID, userID, role)
+}
+
+func (s *ChatService) SetMemberRole(ctx context.Context, tenantID, roomID, userID string, role domain.RoomRole) (domain.RoomMember, bool, error) {
+	return s.repo.SetMemberRole(ctx, tenantID, roomID, userID, role)
+}
+
+func (s *ChatService) TransferOwnership(ctx context.Context, tenantID, roomID, userID string) ([]domain.RoomMember, bool, error) {
+	return s.repo.TransferOwnership(ctx, tenantID, roomID, userID)
 }
 
 func (s *ChatService) IsRoomMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
 	return s.repo.IsRoomMember(ctx, tenantID, roomID, userID)
 }
 
-func (s *ChatService) CreateMessage(ctx context.Context, msg domain.Message) (domain.Message, error) {
-	return s.repo.CreateMessage(ctx, msg)
+func (s *ChatService) GetRoomMember(ctx context.Context, tenantID, roomID, userID string) (domain.RoomMember, bool, error) {
+	return s.repo.GetRoomMember(ctx, tenantID, roomID, userID)
+}
+
+func (s *ChatService) ListMemberRoomIDs(ctx context.Context, tenantID, userID string) ([]string, error) {
+	return s.repo.ListMemberRoomIDs(ctx, tenantID, userID)
+}
+
+func (s *ChatService) ListRoomMemberIDs(ctx context.Context, tenantID, roomID string) ([]string, error) {
+	return s.repo.ListRoomMemberIDs(ctx, tenantID, roomID)
+}
+
+func (s *ChatService) RemoveMember(ctx context.Context, tenantID, roomID, userID string) (bool, error) {
+	return s.repo.RemoveMember(ctx, tenantID, roomID, userID)
+}
+
+func (s *ChatService) GetRoom(ctx context.Context, tenantID, roomID string) (domain.ChatRoom, bool, error) {
+	return s.repo.GetRoom(ctx, tenantID, roomID)
+}
+
+func (s *ChatService) RenameRoom(ctx context.Context, tenantID, roomID, name string) (domain.ChatRoom, bool, error) {
+	return s.repo.RenameRoom(ctx, tenantID, roomID, name)
+}
+
+func (s *ChatService) SetRoomArchived(ctx context.Context, tenantID, roomID string, archived bool) (domain.ChatRoom, bool, error) {
+	return s.repo.SetRoomArchived(ctx, tenantID, roomID, archived)
+}
+
+func (s *ChatService) DeleteRoom(ctx context.Context, tenantID, roomID string) ([]string, bool, error) {
+	return s.repo.DeleteRoom(ctx, tenantID, roomID)
+}
+
+func (s *ChatService) CreateMessage(ctx context.Context, msg domain.Message, flagRules []string) (domain.Message, bool, error) {
+	return s.repo.CreateMessage(ctx, msg, flagRules)
+}
+
+func (s *ChatService) CreateSystemMessage(ctx context.Context, msg domain.Message) (domain.Message, error) {
+	return s.repo.CreateSystemMessage(ctx, msg)
+}
+
+func (s *ChatService) GetMessage(ctx context.Context, tenantID, roomID, messageID string) (domain.Message, bool, error) {
+	return s.repo.GetMessage(ctx, tenantID, roomID, messageID)
+}
+
+func (s *ChatService) UpdateMessage(ctx context.Context, tenantID, roomID, messageID, editorID, body string, flagRules []string) (domain.Message, error) {
+	return s.repo.UpdateMessage(ctx, tenantID, roomID, messageID, editorID, body, flagRules)
+}
+
+func (s *ChatService) PinMessage(ctx context.Context, tenantID, roomID, messageID, userID string) (domain.MessagePin, error) {
+	return s.repo.PinMessage(ctx, tenantID, roomID, messageID, userID)
+}
+
+func (s *ChatService) UnpinMessage(ctx context.Context, tenantID, roomID, messageID string) (bool, error) {
+	return s.repo.UnpinMessage(ctx, tenantID, roomID, messageID)
+}
+
+// ListPinnedMessages returns up to 100 pins; limit defaults to 50.
+func (s *ChatService) ListPinnedMessages(ctx context.Context, tenantID, roomID string, limit int) ([]domain.MessagePin, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.repo.ListPinnedMessages(ctx, tenantID, roomID, limit)
+}
+
+func (s *ChatService) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID string) (domain.Message, error) {
+	return s.repo.DeleteMessage(ctx, tenantID, roomID, messageID, actorID)
+}
+
+func (s *ChatService) ListMessageRevisions(ctx context.Context, tenantID, roomID, messageID string) ([]domain.MessageRevision, error) {
+	return s.repo.ListMessageRevisions(ctx, tenantID, roomID, messageID)
+}
+
+func (s *ChatService) GetThreadSummary(ctx context.Context, tenantID, roomID, parentMessageID string) (domain.ThreadSummary, error) {
+	return s.repo.GetThreadSummary(ctx, tenantID, roomID, parentMessageID)
+}
+
+func (s *ChatService) ListThreadReplies(ctx context.Context, tenantID, roomID, parentMessageID, viewerID string, limit int, cursorSeq *int64) ([]domain.Message, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListThreadReplies(ctx, tenantID, roomID, parentMessageID, viewerID, limit, cursorSeq)
 }
 
 func (s *ChatService) MarkReadUpTo(ctx context.Context, tenantID, roomID, userID, messageID string) error {
 	return s.repo.MarkReadUpTo(ctx, tenantID, roomID, userID, messageID)
 }
 
-func (s *ChatService) ListMessages(ctx context.Context, tenantID, roomID string, limit int, cursorID *string) ([]domain.Message, error) {
+func (s *ChatService) ListMessages(ctx context.Context, tenantID, roomID, viewerID string, limit int, cursorSeq *int64) ([]domain.Message, error) {
 	if limit <= 0 || limit > 200 {
 		limit = 50
 	}
-	return s.repo.ListMessages(ctx, tenantID, roomID, limit, cursorID)
+	return s.repo.ListMessages(ctx, tenantID, roomID, viewerID, limit, cursorSeq)
 }
 
-func (s *ChatService) SearchMessages(ctx context.Context, tenantID, q string, roomID *string, limit int, cursorID *string) ([]domain.Message, error) {
+func (s *ChatService) AddReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	return s.repo.AddReaction(ctx, tenantID, roomID, messageID, userID, emoji)
+}
+
+func (s *ChatService) RemoveReaction(ctx context.Context, tenantID, roomID, messageID, userID, emoji string) (domain.ReactionEvent, bool, error) {
+	return s.repo.RemoveReaction(ctx, tenantID, roomID, messageID, userID, emoji)
+}
+
+func (s *ChatService) SearchMessages(ctx context.Context, tenantID, userID, q string, roomID *string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.Message, error) {
 	if limit <= 0 || limit > 200 {
 		limit = 30
 	}
-	return s.repo.SearchMessages(ctx, tenantID, q, roomID, limit, cursorID)
+	return s.repo.SearchMessages(ctx, tenantID, userID, q, roomID, limit, cursorCreatedAt, cursorID)
 }
 
 func (s *ChatService) GetMessageReaders(ctx context.Context, tenantID, roomID, messageID string) ([]domain.MessageRead, error) {
@@ -72,3 +170,10 @@ func (s *ChatService) ListMyRooms(ctx context.Context, tenantID, userID string,
 	}
 	return s.repo.ListMyRooms(ctx, tenantID, userID, limit, cursorCreatedAt, cursorRoomID)
 }
+
+func (s *ChatService) ListSyncChanges(ctx context.Context, tenantID, userID string, since *domain.SyncCursor, limit int) (domain.SyncBatch, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+	return s.repo.ListSyncChanges(ctx, tenantID, userID, since, limit)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/moderation"
+	"msg_server/server/dbman/repository"
+)
+
+type ModerationService struct {
+	repo *repository.ModerationRepository
+}
+
+func NewModerationService(repo *repository.ModerationRepository) *ModerationService {
+	return &ModerationService{repo: repo}
+}
+
+func (s *ModerationService) ListRules(ctx context.Context, tenantID string) ([]moderation.Rule, error) {
+	return s.repo.ListRules(ctx, tenantID)
+}
+
+func (s *ModerationService) CreateRule(ctx context.Context, item moderation.Rule) (moderation.Rule, error) {
+	return s.repo.CreateRule(ctx, item)
+}
+
+func (s *ModerationService) UpdateRule(ctx context.Context, item moderation.Rule) (moderation.Rule, bool, error) {
+	return s.repo.UpdateRule(ctx, item)
+}
+
+func (s *ModerationService) DeleteRule(ctx context.Context, tenantID, ruleID string) (bool, error) {
+	return s.repo.DeleteRule(ctx, tenantID, ruleID)
+}
+
+func (s *ModerationService) CreateFlag(ctx context.Context, item moderation.Flag) (moderation.Flag, error) {
+	return s.repo.CreateFlag(ctx, item)
+}
+
+func (s *ModerationService) ListFlags(ctx context.Context, tenantID string, status moderation.FlagStatus, limit int) ([]moderation.Flag, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListFlags(ctx, tenantID, status, limit)
+}
+
+func (s *ModerationService) ResolveFlag(ctx context.Context, tenantID, flagID, reviewerID string, status moderation.FlagStatus) (moderation.Flag, *domain.Message, bool, error) {
+	return s.repo.ResolveFlag(ctx, tenantID, flagID, reviewerID, status)
+}
+
+func (s *ModerationService) ReportMessage(ctx context.Context, tenantID, roomID, messageID, reporterID, reason string) (moderation.Flag, bool, error) {
+	return s.repo.ReportMessage(ctx, tenantID, roomID, messageID, reporterID, reason)
+}
+
+func (s *ModerationService) DeleteMessage(ctx context.Context, tenantID, roomID, messageID, actorID, reason string) (domain.Message, moderation.AuditEntry, bool, error) {
+	return s.repo.DeleteMessage(ctx, tenantID, roomID, messageID, actorID, reason)
+}
+
+func (s *ModerationService) RemoveMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string, ban bool) (moderation.AuditEntry, bool, bool, error) {
+	return s.repo.RemoveMember(ctx, tenantID, roomID, userID, actorID, reason, ban)
+}
+
+func (s *ModerationService) UnbanMember(ctx context.Context, tenantID, roomID, userID, actorID, reason string) (moderation.AuditEntry, bool, error) {
+	return s.repo.UnbanMember(ctx, tenantID, roomID, userID, actorID, reason)
+}
+
+func (s *ModerationService) ListBans(ctx context.Context, tenantID, roomID string) ([]moderation.RoomBan, error) {
+	return s.repo.ListBans(ctx, tenantID, roomID)
+}
+
+func (s *ModerationService) ListAudit(ctx context.Context, tenantID, roomID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]moderation.AuditEntry, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListAudit(ctx, tenantID, roomID, limit, cursorCreatedAt, cursorID)
+}
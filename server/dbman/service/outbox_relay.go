@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/common/events"
+	commonlog "msg_server/server/common/log"
+	dbdomain "msg_server/server/dbman/domain"
+	"msg_server/server/dbman/repository"
+)
+
+type outboxPublisher interface {
+	PublishRaw(ctx context.Context, tenantID, key, messageID string, body []byte) error
+}
+
+type OutboxRelayConfig struct {
+	Interval   time.Duration
+	BatchSize  int
+	Lease      time.Duration
+	MaxBackoff time.Duration
+	Retention  time.Duration
+}
+
+// OutboxRelay publishes event_outbox rows to chat.events. Delivery is
+// at-least-once: the envelope id travels as the AMQP message id so consumers
+// can drop duplicates. Failed rows are retried with exponential backoff and are
+// never dropped.
+type OutboxRelay struct {
+	repo    *repository.OutboxRepository
+	tenants *repository.TenantRepository
+	pub     outboxPublisher
+	cfg     OutboxRelayConfig
+}
+
+func NewOutboxRelay(repo *repository.OutboxRepository, tenants *repository.TenantRepository, pub outboxPublisher, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+	return &OutboxRelay{repo: repo, tenants: tenants, pub: pub, cfg: cfg}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	lastPurge := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tenants, err := r.tenants.List(ctx)
+		if err != nil {
+			commonlog.Errorf("event=dbman_outbox action=list_tenants status=failed error=%v", err)
+			continue
+		}
+		purge := time.Since(lastPurge) > time.Hour
+		for _, tenant := range tenants {
+			if ctx.Err() != nil {
+				return
+			}
+			if !tenant.IsActive {
+				continue
+			}
+			r.relayTenant(ctx, tenant)
+			if purge {
+				r.purge(ctx, tenant.TenantID)
+			}
+		}
+		if purge {
+			lastPurge = time.Now()
+		}
+	}
+}
+
+func (r *OutboxRelay) relayTenant(ctx context.Context, tenant domain.Tenant) {
+	for {
+		events, err := r.repo.ClaimPending(ctx, tenant.TenantID, r.cfg.BatchSize, r.cfg.Lease)
+		if err != nil {
+			commonlog.Errorf("event=dbman_outbox action=claim status=failed tenant_id=%s error=%v", tenant.TenantID, err)
+			return
+		}
+		for _, evt := range events {
+			r.deliver(ctx, evt)
+		}
+		if len(events) < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) deliver(ctx context.Context, evt dbdomain.OutboxEvent) {
+	pubCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	err := r.pub.PublishRaw(pubCtx, evt.TenantID, evt.EventType, outboxMessageID(evt), evt.Payload)
+	cancel()
+	if err != nil {
+		next := time.Now().Add(r.backoff(evt.Attempts))
+		commonlog.Warnf("event=dbman_outbox action=publish status=failed tenant_id=%s outbox_id=%d event_type=%s attempts=%d next_attempt_at=%s error=%v", evt.TenantID, evt.ID, evt.EventType, evt.Attempts, next.UTC().Format(time.RFC3339), err)
+		if err := r.repo.Reschedule(ctx, evt.TenantID, evt.ID, next, err.Error()); err != nil {
+			commonlog.Errorf("event=dbman_outbox action=reschedule status=failed tenant_id=%s outbox_id=%d error=%v", evt.TenantID, evt.ID, err)
+		}
+		return
+	}
+	if err := r.repo.MarkDelivered(ctx, evt.TenantID, evt.ID); err != nil {
+		// The lease expires and the row is published again; consumers dedupe by id.
+		commonlog.Errorf("event=dbman_outbox action=mark_delivered status=failed tenant_id=%s outbox_id=%d error=%v", evt.TenantID, evt.ID, err)
+	}
+}
+
+// outboxMessageID is the CloudEvents id of the stored envelope. Outbox row ids
+// are only unique within one database, so they are used, tenant-qualified,
+// only for rows without an envelope id.
+func outboxMessageID(evt dbdomain.OutboxEvent) string {
+	if env, err := events.Decode(evt.Payload); err == nil && env.ID != "" {
+		return env.ID
+	}
+	return "outbox:" + evt.TenantID + ":" + strconv.FormatInt(evt.ID, 10)
+}
+
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.cfg.MaxBackoff {
+		d = r.cfg.MaxBackoff
+	}
+	return d
+}
+
+func (r *OutboxRelay) purge(ctx context.Context, tenantID string) {
+	n, err := r.repo.PurgeDelivered(ctx, tenantID, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		commonlog.Errorf("event=dbman_outbox action=purge status=failed tenant_id=%s error=%v", tenantID, err)
+		return
+	}
+	if n > 0 {
+		commonlog.Infof("event=dbman_outbox action=purge status=ok tenant_id=%s deleted=%d", tenantID, n)
+	}
+}
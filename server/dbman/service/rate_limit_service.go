@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/dbman/repository"
+)
+
+type RateLimitService struct {
+	repo *repository.RateLimitRepository
+}
+
+func NewRateLimitService(repo *repository.RateLimitRepository) *RateLimitService {
+	return &RateLimitService{repo: repo}
+}
+
+func (s *RateLimitService) ListPolicies(ctx context.Context, tenantID string) ([]domain.RateLimitPolicy, error) {
+	return s.repo.ListPolicies(ctx, tenantID)
+}
+
+func (s *RateLimitService) UpsertPolicy(ctx context.Context, item domain.RateLimitPolicy) (domain.RateLimitPolicy, error) {
+	return s.repo.UpsertPolicy(ctx, item)
+}
+
+func (s *RateLimitService) DeletePolicy(ctx context.Context, tenantID, policyID string) (bool, error) {
+	return s.repo.DeletePolicy(ctx, tenantID, policyID)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"msg_server/server/chat/domain"
+	"msg_server/server/common/infra/mq"
 	"msg_server/server/dbman/repository"
 )
 
@@ -87,3 +88,15 @@ func validateTenant(item domain.Tenant) error {
 	}
 	return nil
 }
+
+func (s *TenantService) GetTenantMQMeta(ctx context.Context, tenantID string) (mq.TenantMQMeta, error) {
+	tenant, err := s.repo.GetByID(ctx, tenantID)
+	if err != nil {
+		return mq.TenantMQMeta{}, err
+	}
+	return mq.TenantMQMeta{
+		DeploymentMode:      strings.ToLower(strings.TrimSpace(tenant.DeploymentMode)),
+		DedicatedLavinMQURL: strings.TrimSpace(tenant.DedicatedLavinMQURL),
+		IsActive:            tenant.IsActive,
+	}, nil
+}
@@ -65,6 +65,9 @@ func (s *UserService) Authenticate(ctx context.Context, tenantID, email, passwor
 	if err != nil {
 		return domain.User{}, err
 	}
+	if user.Role == domain.UserRoleBot {
+		return domain.User{}, errors.New("invalid credentials")
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		return domain.User{}, errors.New("invalid credentials")
 	}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"msg_server/server/chat/domain"
+	"msg_server/server/dbman/repository"
+)
+
+type WebhookService struct {
+	repo *repository.WebhookRepository
+}
+
+func NewWebhookService(repo *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, item domain.Webhook) (domain.Webhook, error) {
+	return s.repo.CreateWebhook(ctx, item)
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, tenantID string) ([]domain.Webhook, error) {
+	return s.repo.ListWebhooks(ctx, tenantID)
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, tenantID, webhookID string) (domain.Webhook, bool, error) {
+	return s.repo.GetWebhook(ctx, tenantID, webhookID)
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, tenantID, webhookID string, targetURL *string, eventTypes []string, description *string, isActive *bool) (domain.Webhook, bool, error) {
+	return s.repo.UpdateWebhook(ctx, tenantID, webhookID, targetURL, eventTypes, description, isActive)
+}
+
+func (s *WebhookService) RotateWebhookSecret(ctx context.Context, tenantID, webhookID, secret string) (domain.Webhook, bool, error) {
+	return s.repo.RotateWebhookSecret(ctx, tenantID, webhookID, secret)
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, tenantID, webhookID string) (bool, error) {
+	return s.repo.DeleteWebhook(ctx, tenantID, webhookID)
+}
+
+func (s *WebhookService) EnqueueDeliveries(ctx context.Context, tenantID, eventID, eventType string, payload []byte) (int64, error) {
+	return s.repo.EnqueueDeliveries(ctx, tenantID, eventID, eventType, payload)
+}
+
+func (s *WebhookService) ClaimDeliveries(ctx context.Context, tenantID string, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	return s.repo.ClaimDeliveries(ctx, tenantID, limit, lease)
+}
+
+func (s *WebhookService) RecordAttempt(ctx context.Context, attempt domain.WebhookAttempt) (bool, error) {
+	return s.repo.RecordAttempt(ctx, attempt)
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, tenantID, webhookID string, limit int, cursorCreatedAt *time.Time, cursorID *string) ([]domain.WebhookDelivery, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 30
+	}
+	return s.repo.ListDeliveries(ctx, tenantID, webhookID, limit, cursorCreatedAt, cursorID)
+}
+
+func (s *WebhookService) CreateIncomingWebhook(ctx context.Context, item domain.IncomingWebhook) (domain.IncomingWebhook, bool, error) {
+	return s.repo.CreateIncomingWebhook(ctx, item)
+}
+
+func (s *WebhookService) ListIncomingWebhooks(ctx context.Context, tenantID, roomID string) ([]domain.IncomingWebhook, error) {
+	return s.repo.ListIncomingWebhooks(ctx, tenantID, roomID)
+}
+
+func (s *WebhookService) RevokeIncomingWebhook(ctx context.Context, tenantID, roomID, hookID, actorID string) (domain.IncomingWebhook, bool, error) {
+	return s.repo.RevokeIncomingWebhook(ctx, tenantID, roomID, hookID, actorID)
+}
+
+func (s *WebhookService) AuthenticateIncomingWebhook(ctx context.Context, tenantID, hookID, tokenHash string) (domain.IncomingWebhook, bool, error) {
+	return s.repo.AuthenticateIncomingWebhook(ctx, tenantID, hookID, tokenHash)
+}
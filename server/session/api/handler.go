@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	commonauth "msg_server/server/common/auth"
 	"msg_server/server/common/middleware"
+	"msg_server/server/common/moderation"
 	"msg_server/server/common/transport/httpresp"
 	sessiondomain "msg_server/server/session/domain"
 	sessionservice "msg_server/server/session/service"
@@ -20,19 +22,27 @@ type Handler struct {
 	sessionSvc *sessionservice.SessionService
 	noteSvc    *sessionservice.NoteService
 	chatSvc    *sessionservice.ChatService
+	callSvc    *sessionservice.CallService
 	auth       *commonauth.Service
 	hub        *sessionservice.Hub
 }
 
-func NewHandler(sessionSvc *sessionservice.SessionService, noteSvc *sessionservice.NoteService, chatSvc *sessionservice.ChatService, auth *commonauth.Service, hub *sessionservice.Hub) *Handler {
-	return &Handler{sessionSvc: sessionSvc, noteSvc: noteSvc, chatSvc: chatSvc, auth: auth, hub: hub}
+func NewHandler(sessionSvc *sessionservice.SessionService, noteSvc *sessionservice.NoteService, chatSvc *sessionservice.ChatService, callSvc *sessionservice.CallService, auth *commonauth.Service, hub *sessionservice.Hub) *Handler {
+	return &Handler{sessionSvc: sessionSvc, noteSvc: noteSvc, chatSvc: chatSvc, callSvc: callSvc, auth: auth, hub: hub}
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	r.GET("/health", func(c *gin.Context) {
+		if h.hub.Draining() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 	r.GET("/ws/session", h.handleSessionWS)
+	r.GET("/metrics/ws", func(c *gin.Context) {
+		c.JSON(http.StatusOK, h.hub.Metrics())
+	})
 
 	api := r.Group("/api/v1")
 	api.Use(middleware.AuthRequired(h.auth))
@@ -43,9 +53,15 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 		api.GET("/notes/inbox", h.listInbox)
 		api.POST("/notes/:id/read", h.markNoteRead)
 		api.POST("/chat/notify", h.notifyChat)
+		// Only chat rings devices; a user token could otherwise push
+		// incoming-call prompts with any caller to anyone.
+		api.POST("/calls/notify", middleware.RequireRoles(systemRole), h.notifyCall)
 	}
 }
 
+// systemRole marks tokens minted by servers acting on a user's behalf.
+const systemRole = "system"
+
 var sessionUpgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
 func (h *Handler) handleSessionWS(c *gin.Context) {
@@ -53,6 +69,10 @@ func (h *Handler) handleSessionWS(c *gin.Context) {
 	userID := strings.TrimSpace(c.Query("user_id"))
 	sessionID := strings.TrimSpace(c.Query("session_id"))
 	sessionToken := strings.TrimSpace(c.Query("session_token"))
+	if h.hub.Draining() {
+		c.JSON(http.StatusServiceUnavailable, httpresp.NewErrorResponse("server is draining"))
+		return
+	}
 
 	ok, err := h.sessionSvc.ValidateSession(c.Request.Context(), tenantID, userID, sessionID, sessionToken)
 	if err != nil {
@@ -70,9 +90,10 @@ func (h *Handler) handleSessionWS(c *gin.Context) {
 		return
 	}
 
-	client := &sessionservice.WSClient{TenantID: tenantID, UserID: userID, SessionID: sessionID, Conn: conn}
+	client := h.hub.NewClient(tenantID, userID, sessionID, conn)
 	h.hub.Register(client)
 	defer h.hub.Unregister(client)
+	client.StartKeepalive()
 
 	client.WriteJSON(map[string]any{
 		"type":         "session.connected",
@@ -83,13 +104,11 @@ func (h *Handler) handleSessionWS(c *gin.Context) {
 	})
 
 	for {
-		if err := conn.SetReadDeadline(time.Now().Add(90 * time.Second)); err != nil {
-			return
-		}
 		_, _, err := conn.ReadMessage()
 		if err != nil {
 			return
 		}
+		client.Touch()
 		_, _ = h.sessionSvc.ValidateSession(c.Request.Context(), tenantID, userID, sessionID, sessionToken)
 	}
 }
@@ -157,7 +176,11 @@ func (h *Handler) sendNote(c *gin.Context) {
 	}
 	note, err := h.noteSvc.SendNote(c.Request.Context(), tenantID, userID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
+		status := http.StatusBadRequest
+		if errors.As(err, new(*moderation.BlockedError)) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, httpresp.NewErrorResponse(err.Error()))
 		return
 	}
 	c.JSON(http.StatusCreated, note)
@@ -208,6 +231,10 @@ func (h *Handler) notifyChat(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
 		return
 	}
+	// Server-minted tokens must not be handed to recipients.
+	if c.GetString("auth_role") == systemRole {
+		authToken = ""
+	}
 	if err := h.chatSvc.NotifyChat(c.Request.Context(), tenantID, userID, authToken, req); err != nil {
 		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
 		return
@@ -215,6 +242,24 @@ func (h *Handler) notifyChat(c *gin.Context) {
 	c.JSON(http.StatusOK, httpresp.NewOKResponse())
 }
 
+func (h *Handler) notifyCall(c *gin.Context) {
+	tenantID, userID, err := actorFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, httpresp.NewErrorResponse(httpresp.ErrUnauthorized))
+		return
+	}
+	var req sessiondomain.CallNotifyInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
+		return
+	}
+	if err := h.callSvc.NotifyCall(c.Request.Context(), tenantID, userID, req); err != nil {
+		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, httpresp.NewOKResponse())
+}
+
 func actorFromContext(c *gin.Context) (string, string, error) {
 	rawTenantID, ok := c.Get("auth_tenant_id")
 	if !ok {
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	commonauth "msg_server/server/common/auth"
+	sessionservice "msg_server/server/session/service"
+)
+
+func TestNotifyCallRequiresSystemRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := commonauth.NewService("test-secret", 5)
+	hub := sessionservice.NewHub()
+	h := NewHandler(nil, nil, nil, sessionservice.NewCallService(hub), auth, hub)
+	r := gin.New()
+	h.RegisterRoutes(r)
+
+	body := []byte(`{"room_id":"room-1","call_id":"call-1","event":"call.incoming","media":"audio","caller_id":"ceo","recipient_ids":["user-2"]}`)
+	cases := []struct {
+		role   string
+		status int
+	}{
+		{"user", http.StatusForbidden},
+		{"admin", http.StatusForbidden},
+		{systemRole, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.role, func(t *testing.T) {
+			tok, err := auth.GenerateToken("user-1", "tenant-1", tc.role)
+			if err != nil {
+				t.Fatalf("generate token: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calls/notify", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+tok)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tc.status, w.Body.String())
+			}
+		})
+	}
+}
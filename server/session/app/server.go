@@ -10,6 +10,8 @@ import (
 
 	commonauth "msg_server/server/common/auth"
 	"msg_server/server/common/infra/cache"
+	"msg_server/server/common/moderation"
+	"msg_server/server/common/transport/wsconn"
 	sessionapi "msg_server/server/session/api"
 	sessionservice "msg_server/server/session/service"
 )
@@ -21,6 +23,13 @@ type Config struct {
 	JWTTTLMinutes  int
 	DBManEndpoint  string
 	DBManEndpoints []string
+
+	WSQueueSize      int
+	WSOverflowPolicy string
+	WSPingInterval   time.Duration
+	WSPongWait       time.Duration
+	WSDrainWindow    time.Duration
+	WSReconnectAfter time.Duration
 }
 
 type Server struct {
@@ -40,12 +49,22 @@ func NewServer(cfg Config) (*Server, error) {
 	dbClient := sessionservice.NewDBManClient(cfg.DBManEndpoints...)
 	hub := sessionservice.NewHub()
 	hub.UseRedis(redisClient)
+	queueCfg := wsconn.DefaultConfig()
+	queueCfg.QueueSize = cfg.WSQueueSize
+	queueCfg.Policy = wsconn.ParsePolicy(cfg.WSOverflowPolicy)
+	queueCfg.PingInterval = cfg.WSPingInterval
+	queueCfg.PongWait = cfg.WSPongWait
+	queueCfg.DrainWindow = cfg.WSDrainWindow
+	queueCfg.DrainReconnectAfter = cfg.WSReconnectAfter
+	hub.UseSendQueue(queueCfg)
 	_ = hub.StartRedisSubscriber(context.Background())
 	sessionSvc := sessionservice.NewSessionService(dbClient, hub)
 	noteSvc := sessionservice.NewNoteService(dbClient, hub)
+	noteSvc.UseModeration(moderation.NewScanner(dbClient), dbClient)
 	chatSvc := sessionservice.NewChatService(dbClient, hub)
+	callSvc := sessionservice.NewCallService(hub)
 	auth := commonauth.NewService(cfg.JWTSecret, cfg.JWTTTLMinutes)
-	h := sessionapi.NewHandler(sessionSvc, noteSvc, chatSvc, auth, hub)
+	h := sessionapi.NewHandler(sessionSvc, noteSvc, chatSvc, callSvc, auth, hub)
 
 	r := gin.Default()
 	h.RegisterRoutes(r)
@@ -63,6 +82,7 @@ func NewServer(cfg Config) (*Server, error) {
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.hub != nil {
+		s.hub.Drain(ctx)
 		s.hub.StopRedisSubscriber()
 	}
 	if s.Redis != nil {
@@ -73,3 +73,13 @@ type ChatNotifyInput struct {
 	Body         string   `json:"body"`
 	RecipientIDs []string `json:"recipient_ids"`
 }
+
+type CallNotifyInput struct {
+	RoomID       string   `json:"room_id"`
+	CallID       string   `json:"call_id"`
+	Event        string   `json:"event"`
+	Media        string   `json:"media"`
+	CallerID     string   `json:"caller_id"`
+	Reason       string   `json:"reason"`
+	RecipientIDs []string `json:"recipient_ids"`
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"msg_server/server/session/domain"
+)
+
+// CallService rings every logged-in device of the recipients. Chat decides who
+// to ring and when to stop; this only fans call.* events out through the hub.
+type CallService struct {
+	hub *Hub
+}
+
+func NewCallService(hub *Hub) *CallService {
+	return &CallService{hub: hub}
+}
+
+var callNotifyEvents = map[string]struct{}{
+	"call.incoming":  {},
+	"call.cancelled": {},
+	"call.missed":    {},
+}
+
+func (s *CallService) NotifyCall(ctx context.Context, tenantID, senderUserID string, input domain.CallNotifyInput) error {
+	if strings.TrimSpace(input.RoomID) == "" || strings.TrimSpace(input.CallID) == "" {
+		return errors.New("room_id and call_id are required")
+	}
+	if _, ok := callNotifyEvents[input.Event]; !ok {
+		return errors.New("event must be call.incoming, call.cancelled or call.missed")
+	}
+	recipients := dedupeAndTrim(input.RecipientIDs)
+	if len(recipients) == 0 {
+		return errors.New("recipient_ids is required")
+	}
+
+	now := time.Now().UTC()
+	s.hub.NotifyUsers(tenantID, recipients, func(userID string) any {
+		return map[string]any{
+			"type":              input.Event,
+			"tenant_id":         tenantID,
+			"room_id":           input.RoomID,
+			"call_id":           input.CallID,
+			"media":             input.Media,
+			"caller_id":         input.CallerID,
+			"reason":            input.Reason,
+			"sender_user_id":    senderUserID,
+			"recipient_user_id": userID,
+			"created_at":        now,
+		}
+	})
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	commondbman "msg_server/server/common/infra/dbman"
+	"msg_server/server/common/moderation"
 	sessiondomain "msg_server/server/session/domain"
 )
 
@@ -75,6 +76,22 @@ func (c *DBManClient) SaveSessionChatNotifications(ctx context.Context, tenantID
 	return c.post(ctx, dbmanBasePath+"/session/chat/notify", payload, &out)
 }
 
+func (c *DBManClient) ListModerationRules(ctx context.Context, tenantID string) ([]moderation.Rule, error) {
+	var items []moderation.Rule
+	if err := c.post(ctx, dbmanBasePath+"/moderation/rules/list", map[string]any{"tenant_id": tenantID}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *DBManClient) CreateModerationFlag(ctx context.Context, item moderation.Flag) (moderation.Flag, error) {
+	var out moderation.Flag
+	if err := c.post(ctx, dbmanBasePath+"/moderation/flags/create", item, &out); err != nil {
+		return moderation.Flag{}, err
+	}
+	return out, nil
+}
+
 func (c *DBManClient) post(ctx context.Context, path string, payload any, out any) error {
 	return c.client.Post(ctx, path, payload, out)
 }
@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"sync"
-	"time"
 
 	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/transport/wsconn"
 
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
@@ -18,7 +18,7 @@ type WSClient struct {
 	UserID    string
 	SessionID string
 	Conn      *websocket.Conn
-	mu        sync.Mutex
+	out       *wsconn.Conn
 }
 
 type Hub struct {
@@ -27,6 +27,9 @@ type Hub struct {
 	redis     *redis.Client
 	redisSub  *redis.PubSub
 	subCancel context.CancelFunc
+	queueCfg  wsconn.Config
+	metrics   *wsconn.Metrics
+	registry  *wsconn.Registry
 }
 
 const sessionEventsChannel = "session:events"
@@ -41,7 +44,46 @@ type hubEvent struct {
 }
 
 func NewHub() *Hub {
-	return &Hub{clients: map[string]map[string]*WSClient{}}
+	return &Hub{clients: map[string]map[string]*WSClient{}, queueCfg: wsconn.DefaultConfig(), metrics: wsconn.NewMetrics("session"), registry: wsconn.NewRegistry("session")}
+}
+
+func (h *Hub) UseSendQueue(cfg wsconn.Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queueCfg = cfg
+}
+
+func (h *Hub) Metrics() wsconn.MetricsSnapshot {
+	return h.metrics.Snapshot()
+}
+
+func (h *Hub) Draining() bool {
+	return h.registry.Draining()
+}
+
+// Drain refuses new connections and closes open sessions gradually.
+func (h *Hub) Drain(ctx context.Context) {
+	h.mu.RLock()
+	cfg := h.queueCfg
+	h.mu.RUnlock()
+	h.registry.Drain(ctx, cfg)
+}
+
+// NewClient wraps an upgraded connection with the hub's outbound send queue.
+func (h *Hub) NewClient(tenantID, userID, sessionID string, conn *websocket.Conn) *WSClient {
+	h.mu.RLock()
+	cfg := h.queueCfg
+	h.mu.RUnlock()
+	logTag := "tenant_id=" + tenantID + " user_id=" + userID + " session_id=" + sessionID
+	out := wsconn.New(conn, cfg, h.metrics, logTag)
+	h.registry.Add(out)
+	return &WSClient{
+		TenantID:  tenantID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Conn:      conn,
+		out:       out,
+	}
 }
 
 func (h *Hub) UseRedis(client *redis.Client) {
@@ -107,7 +149,7 @@ func (h *Hub) Unregister(client *WSClient) {
 			delete(h.clients, key)
 		}
 	}
-	_ = client.Conn.Close()
+	client.Close()
 }
 
 func (h *Hub) NotifyUser(tenantID, userID string, payload any) {
@@ -121,15 +163,16 @@ func (h *Hub) NotifyUser(tenantID, userID string, payload any) {
 func (h *Hub) notifyUserLocal(tenantID, userID string, payload any) int {
 	key := userKey(tenantID, userID)
 	h.mu.RLock()
-	sessions := h.clients[key]
+	sessions := make([]*WSClient, 0, len(h.clients[key]))
+	for _, client := range h.clients[key] {
+		sessions = append(sessions, client)
+	}
 	h.mu.RUnlock()
 
-	count := 0
 	for _, client := range sessions {
 		client.WriteJSON(payload)
-		count++
 	}
-	return count
+	return len(sessions)
 }
 
 func (h *Hub) NotifyUsers(tenantID string, userIDs []string, payloadBuilder func(string) any) {
@@ -317,9 +360,25 @@ func (h *Hub) consumeEvents(ctx context.Context, sub *redis.PubSub) {
 	}
 }
 
+// WriteJSON queues payload for the connection's writer goroutine; it never
+// blocks on a slow client.
 func (c *WSClient) WriteJSON(payload any) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	_ = c.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	_ = c.Conn.WriteJSON(payload)
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	c.out.Send(b)
+}
+
+// StartKeepalive arms ping/pong liveness; Touch extends it after each read.
+func (c *WSClient) StartKeepalive() {
+	c.out.StartKeepalive()
+}
+
+func (c *WSClient) Touch() {
+	c.out.Touch()
+}
+
+func (c *WSClient) Close() {
+	c.out.Close()
 }
@@ -3,8 +3,11 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
 
+	commonlog "msg_server/server/common/log"
+	"msg_server/server/common/moderation"
 	"msg_server/server/session/domain"
 )
 
@@ -14,15 +17,28 @@ type noteServiceStore interface {
 	MarkSessionNoteRead(ctx context.Context, tenantID, userID, noteID string) error
 }
 
+type moderationFlagStore interface {
+	CreateModerationFlag(ctx context.Context, flag moderation.Flag) (moderation.Flag, error)
+}
+
 type NoteService struct {
-	store noteServiceStore
-	hub   *Hub
+	store   noteServiceStore
+	hub     *Hub
+	scanner *moderation.Scanner
+	flags   moderationFlagStore
 }
 
 func NewNoteService(store noteServiceStore, hub *Hub) *NoteService {
 	return &NoteService{store: store, hub: hub}
 }
 
+// UseModeration runs note bodies through the tenant's moderation rules, the
+// same pipeline chat applies to messages.
+func (s *NoteService) UseModeration(scanner *moderation.Scanner, flags moderationFlagStore) {
+	s.scanner = scanner
+	s.flags = flags
+}
+
 func (s *NoteService) SendNote(ctx context.Context, tenantID, senderUserID string, input domain.NoteCreateInput) (domain.Note, error) {
 	if strings.TrimSpace(input.Title) == "" {
 		return domain.Note{}, errors.New("title is required")
@@ -42,17 +58,54 @@ func (s *NoteService) SendNote(ctx context.Context, tenantID, senderUserID strin
 		recipients = append(recipients, domain.NoteRecipient{UserID: userID, Type: "bcc"})
 	}
 
+	title := strings.TrimSpace(input.Title)
+	body := input.Body
+	var flagged []string
+	titleFlagged := false
+	if s.scanner != nil {
+		// The title reaches every recipient's inbox just like the body, so it
+		// goes through the same rules.
+		titleRes, err := s.scanNote(ctx, tenantID, senderUserID, title)
+		if err != nil {
+			return domain.Note{}, err
+		}
+		bodyRes, err := s.scanNote(ctx, tenantID, senderUserID, body)
+		if err != nil {
+			return domain.Note{}, err
+		}
+		title, body = titleRes.Text, bodyRes.Text
+		titleFlagged = len(titleRes.Flagged) > 0
+		flagged = mergeRules(titleRes.Flagged, bodyRes.Flagged)
+	}
+
 	note, err := s.store.CreateSessionNote(ctx, domain.Note{
 		TenantID:     tenantID,
 		SenderUserID: senderUserID,
-		Title:        strings.TrimSpace(input.Title),
-		Body:         input.Body,
+		Title:        title,
+		Body:         body,
 		Recipients:   recipients,
 		Files:        input.Files,
 	})
 	if err != nil {
 		return domain.Note{}, err
 	}
+	if len(flagged) > 0 && s.flags != nil {
+		flaggedBody := note.Body
+		if titleFlagged {
+			flaggedBody = note.Title + "\n\n" + note.Body
+		}
+		_, err := s.flags.CreateModerationFlag(ctx, moderation.Flag{
+			TenantID:  tenantID,
+			Source:    moderation.SourceNote,
+			SubjectID: note.NoteID,
+			SenderID:  senderUserID,
+			Body:      flaggedBody,
+			Rules:     flagged,
+		})
+		if err != nil {
+			commonlog.Errorf("event=moderation action=flag status=failed source=note tenant_id=%s note_id=%s error=%v", tenantID, note.NoteID, err)
+		}
+	}
 
 	recipientTypeByUser := map[string]string{}
 	for _, recipient := range note.Recipients {
@@ -96,3 +149,27 @@ func (s *NoteService) MarkNoteRead(ctx context.Context, tenantID, userID, noteID
 	}
 	return s.store.MarkSessionNoteRead(ctx, tenantID, userID, noteID)
 }
+
+// scanNote runs one note field through the tenant's moderation rules and
+// rejects it with *moderation.BlockedError when a block rule matches.
+func (s *NoteService) scanNote(ctx context.Context, tenantID, senderUserID, text string) (moderation.Result, error) {
+	res, err := s.scanner.Scan(ctx, tenantID, text)
+	if err != nil {
+		return moderation.Result{}, err
+	}
+	if res.Blocked != "" {
+		commonlog.Infof("event=moderation action=block status=ok source=note tenant_id=%s user_id=%s rule=%q", tenantID, senderUserID, res.Blocked)
+		return moderation.Result{}, &moderation.BlockedError{Rule: res.Blocked}
+	}
+	return res, nil
+}
+
+func mergeRules(a, b []string) []string {
+	merged := append([]string(nil), a...)
+	for _, rule := range b {
+		if !slices.Contains(merged, rule) {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
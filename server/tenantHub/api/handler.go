@@ -48,19 +48,26 @@ func (h *Handler) listTenants(c *gin.Context) {
 
 func (h *Handler) createTenant(c *gin.Context) {
 	var req struct {
-		TenantID                string `json:"tenant_id" binding:"required"`
-		Name                    string `json:"name" binding:"required"`
-		DeploymentMode          string `json:"deployment_mode" binding:"required"`
-		DedicatedDSN            string `json:"dedicated_dsn"`
-		DedicatedRedisAddr      string `json:"dedicated_redis_addr"`
-		DedicatedLavinMQURL     string `json:"dedicated_lavinmq_url"`
-		DedicatedMinIOEndpoint  string `json:"dedicated_minio_endpoint"`
-		DedicatedMinIOAccessKey string `json:"dedicated_minio_access_key"`
-		DedicatedMinIOSecretKey string `json:"dedicated_minio_secret_key"`
-		DedicatedMinIOBucket    string `json:"dedicated_minio_bucket"`
-		DedicatedMinIOUseSSL    bool   `json:"dedicated_minio_use_ssl"`
-		UserCountThreshold      int    `json:"user_count_threshold"`
-		IsActive                *bool  `json:"is_active"`
+		TenantID                string   `json:"tenant_id" binding:"required"`
+		Name                    string   `json:"name" binding:"required"`
+		DeploymentMode          string   `json:"deployment_mode" binding:"required"`
+		DedicatedDSN            string   `json:"dedicated_dsn"`
+		DedicatedRedisAddr      string   `json:"dedicated_redis_addr"`
+		DedicatedLavinMQURL     string   `json:"dedicated_lavinmq_url"`
+		DedicatedMinIOEndpoint  string   `json:"dedicated_minio_endpoint"`
+		DedicatedMinIOAccessKey string   `json:"dedicated_minio_access_key"`
+		DedicatedMinIOSecretKey string   `json:"dedicated_minio_secret_key"`
+		DedicatedMinIOBucket    string   `json:"dedicated_minio_bucket"`
+		DedicatedMinIOUseSSL    bool     `json:"dedicated_minio_use_ssl"`
+		StunURLs                []string `json:"stun_urls"`
+		TurnURLs                []string `json:"turn_urls"`
+		TurnSharedSecret        string   `json:"turn_shared_secret"`
+		TurnCredentialTTLSec    int      `json:"turn_credential_ttl_sec"`
+		SFUURL                  string   `json:"sfu_url"`
+		SFUAPIKey               string   `json:"sfu_api_key"`
+		SFUAPISecret            string   `json:"sfu_api_secret"`
+		UserCountThreshold      int      `json:"user_count_threshold"`
+		IsActive                *bool    `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
@@ -85,6 +92,13 @@ func (h *Handler) createTenant(c *gin.Context) {
 		DedicatedMinIOSecretKey: req.DedicatedMinIOSecretKey,
 		DedicatedMinIOBucket:    req.DedicatedMinIOBucket,
 		DedicatedMinIOUseSSL:    req.DedicatedMinIOUseSSL,
+		StunURLs:                req.StunURLs,
+		TurnURLs:                req.TurnURLs,
+		TurnSharedSecret:        req.TurnSharedSecret,
+		TurnCredentialTTLSec:    req.TurnCredentialTTLSec,
+		SFUURL:                  req.SFUURL,
+		SFUAPIKey:               req.SFUAPIKey,
+		SFUAPISecret:            req.SFUAPISecret,
 		UserCountThreshold:      req.UserCountThreshold,
 		IsActive:                isActive,
 	})
@@ -98,18 +112,25 @@ func (h *Handler) createTenant(c *gin.Context) {
 func (h *Handler) updateTenant(c *gin.Context) {
 	tenantID := c.Param("id")
 	var req struct {
-		Name                    string `json:"name" binding:"required"`
-		DeploymentMode          string `json:"deployment_mode" binding:"required"`
-		DedicatedDSN            string `json:"dedicated_dsn"`
-		DedicatedRedisAddr      string `json:"dedicated_redis_addr"`
-		DedicatedLavinMQURL     string `json:"dedicated_lavinmq_url"`
-		DedicatedMinIOEndpoint  string `json:"dedicated_minio_endpoint"`
-		DedicatedMinIOAccessKey string `json:"dedicated_minio_access_key"`
-		DedicatedMinIOSecretKey string `json:"dedicated_minio_secret_key"`
-		DedicatedMinIOBucket    string `json:"dedicated_minio_bucket"`
-		DedicatedMinIOUseSSL    bool   `json:"dedicated_minio_use_ssl"`
-		UserCountThreshold      int    `json:"user_count_threshold" binding:"required"`
-		IsActive                bool   `json:"is_active"`
+		Name                    string   `json:"name" binding:"required"`
+		DeploymentMode          string   `json:"deployment_mode" binding:"required"`
+		DedicatedDSN            string   `json:"dedicated_dsn"`
+		DedicatedRedisAddr      string   `json:"dedicated_redis_addr"`
+		DedicatedLavinMQURL     string   `json:"dedicated_lavinmq_url"`
+		DedicatedMinIOEndpoint  string   `json:"dedicated_minio_endpoint"`
+		DedicatedMinIOAccessKey string   `json:"dedicated_minio_access_key"`
+		DedicatedMinIOSecretKey string   `json:"dedicated_minio_secret_key"`
+		DedicatedMinIOBucket    string   `json:"dedicated_minio_bucket"`
+		DedicatedMinIOUseSSL    bool     `json:"dedicated_minio_use_ssl"`
+		StunURLs                []string `json:"stun_urls"`
+		TurnURLs                []string `json:"turn_urls"`
+		TurnSharedSecret        string   `json:"turn_shared_secret"`
+		TurnCredentialTTLSec    int      `json:"turn_credential_ttl_sec"`
+		SFUURL                  string   `json:"sfu_url"`
+		SFUAPIKey               string   `json:"sfu_api_key"`
+		SFUAPISecret            string   `json:"sfu_api_secret"`
+		UserCountThreshold      int      `json:"user_count_threshold" binding:"required"`
+		IsActive                bool     `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, httpresp.NewErrorResponse(err.Error()))
@@ -127,6 +148,13 @@ func (h *Handler) updateTenant(c *gin.Context) {
 		DedicatedMinIOSecretKey: req.DedicatedMinIOSecretKey,
 		DedicatedMinIOBucket:    req.DedicatedMinIOBucket,
 		DedicatedMinIOUseSSL:    req.DedicatedMinIOUseSSL,
+		StunURLs:                req.StunURLs,
+		TurnURLs:                req.TurnURLs,
+		TurnSharedSecret:        req.TurnSharedSecret,
+		TurnCredentialTTLSec:    req.TurnCredentialTTLSec,
+		SFUURL:                  req.SFUURL,
+		SFUAPIKey:               req.SFUAPIKey,
+		SFUAPISecret:            req.SFUAPISecret,
 		UserCountThreshold:      req.UserCountThreshold,
 		IsActive:                req.IsActive,
 	})
@@ -24,6 +24,7 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	api := r.Group("/api/v1/vectors/messages")
 	{
 		api.POST("/index", h.index)
+		api.POST("/delete", h.delete)
 		api.POST("/search", h.search)
 	}
 }
@@ -45,6 +46,21 @@ func (h *Handler) index(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
+func (h *Handler) delete(c *gin.Context) {
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.DeleteMessage(c.Request.Context(), req.MessageID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
 func (h *Handler) search(c *gin.Context) {
 	var req struct {
 		Query  string  `json:"query" binding:"required"`
@@ -92,6 +92,21 @@ func (e *ElasticsearchService) IndexMessage(ctx context.Context, messageID, room
 	return nil
 }
 
+func (e *ElasticsearchService) DeleteMessage(ctx context.Context, messageID string) error {
+	if !e.enabled {
+		return nil
+	}
+	path := fmt.Sprintf("/%s/_doc/%s", e.index, messageID)
+	_, statusCode, err := e.requestBytes(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode >= 300 && statusCode != http.StatusNotFound {
+		return fmt.Errorf("elasticsearch status %d", statusCode)
+	}
+	return nil
+}
+
 func (e *ElasticsearchService) SemanticSearch(ctx context.Context, query string, roomID *string, limit int) ([]string, error) {
 	if !e.enabled {
 		return nil, nil
@@ -77,7 +77,19 @@ func (m *MilvusService) IndexMessage(ctx context.Context, messageID, roomID, tex
 			"text":    text,
 		}},
 	}
-	_, err := m.post(ctx, "/v2/vectordb/entities/insert", payload)
+	_, err := m.post(ctx, "/v2/vectordb/entities/upsert", payload)
+	return err
+}
+
+func (m *MilvusService) DeleteMessage(ctx context.Context, messageID string) error {
+	if !m.enabled {
+		return nil
+	}
+	payload := map[string]any{
+		"collectionName": defaultCollectionName,
+		"filter":         fmt.Sprintf("id in [\"%s\"]", messageID),
+	}
+	_, err := m.post(ctx, "/v2/vectordb/entities/delete", payload)
 	return err
 }
 
@@ -82,6 +82,14 @@ func (q *QdrantService) IndexMessage(ctx context.Context, messageID, roomID, tex
 	return q.requestNoDecode(ctx, http.MethodPut, fmt.Sprintf("/collections/%s/points", q.collection), payload)
 }
 
+func (q *QdrantService) DeleteMessage(ctx context.Context, messageID string) error {
+	if !q.enabled {
+		return nil
+	}
+	payload := map[string]any{"points": []string{messageID}}
+	return q.requestNoDecode(ctx, http.MethodPost, fmt.Sprintf("/collections/%s/points/delete", q.collection), payload)
+}
+
 func (q *QdrantService) SemanticSearch(ctx context.Context, query string, roomID *string, limit int) ([]string, error) {
 	if !q.enabled {
 		return nil, nil